@@ -6,7 +6,6 @@ import (
 
 	"github.com/spf13/cobra"
 	"github.com/rojolang/vocals-sdk-go/pkg/vocals"
-	"github.com/joho/godotenv"
 )
 
 var (
@@ -18,6 +17,9 @@ var (
 )
 
 func main() {
+	// Pick up settings from a local .env file, if there is one
+	_ = vocals.LoadDotEnv()
+
 	rootCmd := &cobra.Command{
 		Use:   "vocals",
 		Short: "Vocals SDK Go CLI",
@@ -72,6 +74,7 @@ func demoRecordCmd() *cobra.Command {
 			
 			if apiKey != "" {
 				// Set API key if provided
+				config.APIKey = apiKey
 				vocals.GetGlobalLogger().WithField("api_key_prefix", apiKey[:min(len(apiKey), 8)]).Info("Using API key")
 			}
 			
@@ -117,9 +120,6 @@ func demoStatsCmd() *cobra.Command {
 				duration = 5.0 // Default 5 seconds
 			}
 
-			// Load environment variables
-			_ = godotenv.Load()
-
 			config := vocals.NewVocalsConfig()
 			audioConfig := vocals.NewAudioConfig()
 			
@@ -132,8 +132,7 @@ func demoStatsCmd() *cobra.Command {
 			}
 			
 			if apiKey != "" {
-				// Set API key in the vocals module
-				os.Setenv("VOCALS_DEV_API_KEY", apiKey)
+				config.APIKey = apiKey
 				vocals.GetGlobalLogger().WithField("api_key_prefix", apiKey[:min(len(apiKey), 8)]).Info("Using API key")
 			}
 			
@@ -397,9 +396,6 @@ func setupConfigCmd() *cobra.Command {
 		Short: "Show current configuration",
 		Long:  "Display current configuration settings",
 		Run: func(cmd *cobra.Command, args []string) {
-			// Load environment variables
-			_ = godotenv.Load()
-			
 			// Get actual values from environment or flags (flags take precedence)
 			actualApiKey := apiKey
 			if actualApiKey == "" {
@@ -0,0 +1,127 @@
+package vocals
+
+import (
+	"sync"
+)
+
+const (
+	// ErrCodeAudioUnavailable reports that this build has no audio device
+	// support
+	ErrCodeAudioUnavailable = "AUDIO_UNAVAILABLE"
+	// ErrCodeInvalidAudioDevice reports a selected device that does not
+	// exist or cannot open the requested stream
+	ErrCodeInvalidAudioDevice = "INVALID_AUDIO_DEVICE"
+)
+
+// audioStream is an open device stream of the audio backend
+type audioStream interface {
+	Start() error
+	Stop() error
+	Close() error
+}
+
+// AudioBackendAvailable reports whether this build can use audio devices.
+// Builds without cgo or with the vocals_headless tag use a null backend:
+// everything except device capture, playback and enumeration keeps
+// working, and those fail with AUDIO_UNAVAILABLE.
+func AudioBackendAvailable() bool {
+	return audioBackendAvailable
+}
+
+var (
+	backendUsers int
+	backendMu    sync.Mutex
+)
+
+// acquireAudioBackend initializes the backend for its first user. Every
+// successful call must be paired with releaseAudioBackend.
+func acquireAudioBackend() error {
+	backendMu.Lock()
+	defer backendMu.Unlock()
+
+	if backendUsers == 0 {
+		if err := backendInitialize(); err != nil {
+			return err
+		}
+	}
+	backendUsers++
+	return nil
+}
+
+// releaseAudioBackend terminates the backend once its last user is done
+func releaseAudioBackend() {
+	backendMu.Lock()
+	defer backendMu.Unlock()
+
+	if backendUsers == 0 {
+		return
+	}
+	backendUsers--
+	if backendUsers == 0 {
+		backendTerminate()
+	}
+}
+
+// openOutputStream opens a stream on the output device deviceID, or on
+// the default output device when deviceID is nil; callback fills each
+// buffer. The backend stays initialized until the stream is closed.
+func openOutputStream(deviceID *int, channels, sampleRate, bufferSize int, callback func(out []float32)) (audioStream, error) {
+	return openStream(deviceID, false, channels, sampleRate, bufferSize, callback)
+}
+
+// openInputStream opens the input device deviceID, or the default input
+// device when deviceID is nil; callback receives each captured buffer
+func openInputStream(deviceID *int, channels, sampleRate, bufferSize int, callback func(in []float32)) (audioStream, error) {
+	return openStream(deviceID, true, channels, sampleRate, bufferSize, callback)
+}
+
+func openStream(deviceID *int, isInput bool, channels, sampleRate, bufferSize int, callback func([]float32)) (audioStream, error) {
+	if err := acquireAudioBackend(); err != nil {
+		return nil, err
+	}
+
+	if deviceID != nil {
+		if err := validateStreamDevice(*deviceID, isInput, channels, sampleRate); err != nil {
+			releaseAudioBackend()
+			return nil, err
+		}
+	}
+
+	inputChannels, outputChannels := 0, channels
+	if isInput {
+		inputChannels, outputChannels = channels, 0
+	}
+	stream, err := backendOpenStream(deviceID, inputChannels, outputChannels, sampleRate, bufferSize, callback)
+	if err != nil {
+		releaseAudioBackend()
+		return nil, err
+	}
+	return &releasingStream{audioStream: stream}, nil
+}
+
+// validateStreamDevice checks with ValidateDevice that deviceID can open
+// the requested stream. The caller has acquired the backend.
+func validateStreamDevice(deviceID int, isInput bool, channels, sampleRate int) error {
+	adm := NewAudioDeviceManager()
+	if err := adm.RefreshDevices(); err != nil {
+		return NewVocalsError(err.Error(), ErrCodeAudioDevice)
+	}
+	if err := adm.ValidateDevice(deviceID, isInput, channels, float64(sampleRate)); err != nil {
+		return NewVocalsError(err.Error(), ErrCodeInvalidAudioDevice).
+			AddDetail("device_id", deviceID).
+			AddDetail("input", isInput)
+	}
+	return nil
+}
+
+// releasingStream releases the backend when the stream is closed
+type releasingStream struct {
+	audioStream
+	once sync.Once
+}
+
+func (s *releasingStream) Close() error {
+	err := s.audioStream.Close()
+	s.once.Do(releaseAudioBackend)
+	return err
+}
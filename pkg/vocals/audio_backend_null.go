@@ -0,0 +1,23 @@
+//go:build !cgo || vocals_headless
+
+package vocals
+
+const audioBackendAvailable = false
+
+func errAudioUnavailable() *VocalsError {
+	return NewVocalsError("Audio devices are not available in this build (no cgo or vocals_headless)", ErrCodeAudioUnavailable)
+}
+
+func backendInitialize() error {
+	return errAudioUnavailable()
+}
+
+func backendTerminate() {}
+
+func backendOpenStream(deviceID *int, inputChannels, outputChannels, sampleRate, bufferSize int, callback func([]float32)) (audioStream, error) {
+	return nil, errAudioUnavailable()
+}
+
+func backendDevices() ([]AudioDevice, error) {
+	return nil, errAudioUnavailable()
+}
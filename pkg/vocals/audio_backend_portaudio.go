@@ -0,0 +1,118 @@
+//go:build cgo && !vocals_headless
+
+package vocals
+
+import (
+	"fmt"
+
+	"github.com/gordonklaus/portaudio"
+)
+
+const audioBackendAvailable = true
+
+func backendInitialize() error {
+	if err := portaudio.Initialize(); err != nil {
+		return NewVocalsError(err.Error(), ErrCodeAudioDevice)
+	}
+	return nil
+}
+
+func backendTerminate() {
+	portaudio.Terminate()
+}
+
+// backendOpenStream opens a stream on the device deviceID, or on the
+// default device when deviceID is nil. callback gets the input buffer for
+// capture streams and the output buffer otherwise.
+func backendOpenStream(deviceID *int, inputChannels, outputChannels, sampleRate, bufferSize int, callback func([]float32)) (audioStream, error) {
+	var stream *portaudio.Stream
+	var err error
+	if deviceID != nil {
+		var params portaudio.StreamParameters
+		params, err = deviceStreamParameters(*deviceID, inputChannels, outputChannels)
+		if err != nil {
+			return nil, err
+		}
+		params.SampleRate = float64(sampleRate)
+		params.FramesPerBuffer = bufferSize
+		stream, err = portaudio.OpenStream(params, func(buf []float32) {
+			callback(buf)
+		})
+	} else if inputChannels > 0 {
+		stream, err = portaudio.OpenDefaultStream(inputChannels, 0, float64(sampleRate), bufferSize, func(in []float32) {
+			callback(in)
+		})
+	} else {
+		stream, err = portaudio.OpenDefaultStream(0, outputChannels, float64(sampleRate), bufferSize, func(out []float32) {
+			callback(out)
+		})
+	}
+	if err != nil {
+		return nil, NewVocalsError(err.Error(), ErrCodeAudioDevice)
+	}
+	return stream, nil
+}
+
+// deviceStreamParameters describes a capture stream on deviceID when
+// inputChannels is set, and a playback stream otherwise
+func deviceStreamParameters(deviceID, inputChannels, outputChannels int) (portaudio.StreamParameters, error) {
+	infos, err := portaudio.Devices()
+	if err != nil {
+		return portaudio.StreamParameters{}, NewVocalsError(err.Error(), ErrCodeAudioDevice)
+	}
+	if deviceID < 0 || deviceID >= len(infos) {
+		return portaudio.StreamParameters{}, NewVocalsError(fmt.Sprintf("device with ID %d not found", deviceID), ErrCodeInvalidAudioDevice)
+	}
+
+	device := infos[deviceID]
+	if inputChannels > 0 {
+		params := portaudio.HighLatencyParameters(device, nil)
+		params.Input.Channels = inputChannels
+		return params, nil
+	}
+	params := portaudio.HighLatencyParameters(nil, device)
+	params.Output.Channels = outputChannels
+	return params, nil
+}
+
+// backendDevices lists the devices of every host API
+func backendDevices() ([]AudioDevice, error) {
+	defaultInput, _ := portaudio.DefaultInputDevice()
+	defaultOutput, _ := portaudio.DefaultOutputDevice()
+
+	infos, err := portaudio.Devices()
+	if err != nil {
+		return nil, err
+	}
+
+	devices := make([]AudioDevice, 0, len(infos))
+	for i, dev := range infos {
+		hostAPIName := "Unknown"
+		if dev.HostApi != nil {
+			hostAPIName = dev.HostApi.Name
+		}
+
+		device := AudioDevice{
+			ID:                i,
+			Name:              dev.Name,
+			MaxInputChannels:  dev.MaxInputChannels,
+			MaxOutputChannels: dev.MaxOutputChannels,
+			DefaultSampleRate: dev.DefaultSampleRate,
+			IsDefault:         false,
+			IsInput:           dev.MaxInputChannels > 0,
+			IsOutput:          dev.MaxOutputChannels > 0,
+			HostAPI:           hostAPIName,
+		}
+
+		// Check if it's a default device
+		if defaultInput != nil && dev == defaultInput {
+			device.IsDefault = true
+		}
+		if defaultOutput != nil && dev == defaultOutput {
+			device.IsDefault = true
+		}
+
+		devices = append(devices, device)
+	}
+	return devices, nil
+}
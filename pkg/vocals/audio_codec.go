@@ -0,0 +1,210 @@
+package vocals
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// Audio formats for AudioConfig.Format. Every format carries interleaved
+// little-endian samples; the client converts to and from float32 samples
+// when sending audio and playing TTS audio.
+const (
+	FormatPCMF32LE = "pcm_f32le" // 32-bit float
+	FormatPCMS16LE = "pcm_s16le" // 16-bit signed, half the bandwidth of pcm_f32le
+	FormatPCMS24LE = "pcm_s24le" // 24-bit signed, packed in 3 bytes
+	FormatPCMS32LE = "pcm_s32le" // 32-bit signed
+	FormatPCMU8    = "pcm_u8"    // 8-bit unsigned
+	FormatMuLaw    = "pcm_mulaw" // G.711 mu-law, 8 bits per sample
+	FormatALaw     = "pcm_alaw"  // G.711 a-law, 8 bits per sample
+)
+
+// pcmSampleSize returns the bytes per sample of a PCM format
+func pcmSampleSize(format string) (int, error) {
+	switch format {
+	case FormatPCMU8, FormatMuLaw, FormatALaw:
+		return 1, nil
+	case FormatPCMS16LE:
+		return 2, nil
+	case FormatPCMS24LE:
+		return 3, nil
+	case FormatPCMF32LE, FormatPCMS32LE:
+		return 4, nil
+	}
+	return 0, NewVocalsError(fmt.Sprintf("Unsupported PCM format: %s", format), "UNSUPPORTED_AUDIO_FORMAT")
+}
+
+// IsSupportedAudioFormat reports whether EncodePCM and DecodePCM handle
+// format
+func IsSupportedAudioFormat(format string) bool {
+	_, err := pcmSampleSize(format)
+	return err == nil
+}
+
+// EncodePCM converts float32 samples in [-1, 1] to format. Samples outside
+// that range are clipped by the integer formats.
+func EncodePCM(samples []float32, format string) ([]byte, error) {
+	size, err := pcmSampleSize(format)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, len(samples)*size)
+	for i, sample := range samples {
+		b := data[i*size : (i+1)*size]
+		switch format {
+		case FormatPCMF32LE:
+			binary.LittleEndian.PutUint32(b, math.Float32bits(sample))
+		case FormatPCMS16LE:
+			binary.LittleEndian.PutUint16(b, uint16(quantize(sample, 32767)))
+		case FormatPCMS24LE:
+			v := uint32(quantize(sample, 8388607))
+			b[0], b[1], b[2] = byte(v), byte(v>>8), byte(v>>16)
+		case FormatPCMS32LE:
+			binary.LittleEndian.PutUint32(b, uint32(quantize(sample, 2147483647)))
+		case FormatPCMU8:
+			b[0] = byte(quantize(sample, 127) + 128)
+		case FormatMuLaw:
+			b[0] = linearToMuLaw(int16(quantize(sample, 32767)))
+		case FormatALaw:
+			b[0] = linearToALaw(int16(quantize(sample, 32767)))
+		}
+	}
+	return data, nil
+}
+
+// DecodePCM converts audio in format to float32 samples in [-1, 1]. A
+// trailing partial sample is ignored.
+func DecodePCM(data []byte, format string) ([]float32, error) {
+	size, err := pcmSampleSize(format)
+	if err != nil {
+		return nil, err
+	}
+
+	samples := make([]float32, len(data)/size)
+	for i := range samples {
+		b := data[i*size : (i+1)*size]
+		switch format {
+		case FormatPCMU8:
+			samples[i] = (float32(b[0]) - 128) / 128
+		case FormatPCMS16LE:
+			samples[i] = float32(int16(binary.LittleEndian.Uint16(b))) / 32768
+		case FormatPCMS24LE:
+			v := int32(uint32(b[0])<<8|uint32(b[1])<<16|uint32(b[2])<<24) >> 8
+			samples[i] = float32(v) / 8388608
+		case FormatPCMS32LE:
+			samples[i] = float32(float64(int32(binary.LittleEndian.Uint32(b))) / 2147483648)
+		case FormatPCMF32LE:
+			samples[i] = math.Float32frombits(binary.LittleEndian.Uint32(b))
+		case FormatMuLaw:
+			samples[i] = muLawTable[b[0]]
+		case FormatALaw:
+			samples[i] = aLawTable[b[0]]
+		}
+	}
+	return samples, nil
+}
+
+// quantize scales sample to a signed integer of magnitude at most full
+func quantize(sample float32, full float64) int64 {
+	v := math.Round(float64(sample) * full)
+	return int64(math.Max(-full-1, math.Min(full, v)))
+}
+
+// G.711 companding after the ITU reference, on 16-bit linear samples
+
+const muLawBias = 0x84
+
+var (
+	muLawTable = g711Table(muLawToLinear)
+	aLawTable  = g711Table(aLawToLinear)
+)
+
+func g711Table(decode func(byte) int16) [256]float32 {
+	var table [256]float32
+	for i := range table {
+		table[i] = float32(decode(byte(i))) / 32768
+	}
+	return table
+}
+
+// g711Segment returns the index of the first end value not below v, or
+// len(ends) when v exceeds them all
+func g711Segment(v int, ends []int) int {
+	for i, end := range ends {
+		if v <= end {
+			return i
+		}
+	}
+	return len(ends)
+}
+
+var (
+	muLawSegmentEnds = []int{0x3F, 0x7F, 0xFF, 0x1FF, 0x3FF, 0x7FF, 0xFFF, 0x1FFF}
+	aLawSegmentEnds  = []int{0x1F, 0x3F, 0x7F, 0xFF, 0x1FF, 0x3FF, 0x7FF, 0xFFF}
+)
+
+func linearToMuLaw(pcm int16) byte {
+	v := int(pcm) >> 2
+	mask := 0xFF
+	if v < 0 {
+		v = -v
+		mask = 0x7F
+	}
+	v = min(v, 8159) + muLawBias>>2
+
+	segment := g711Segment(v, muLawSegmentEnds)
+	if segment >= 8 {
+		return byte(0x7F ^ mask)
+	}
+	return byte((segment<<4 | (v>>(segment+1))&0x0F) ^ mask)
+}
+
+func muLawToLinear(u byte) int16 {
+	u = ^u
+	t := (int(u&0x0F) << 3) + muLawBias
+	t <<= (u & 0x70) >> 4
+	if u&0x80 != 0 {
+		return int16(muLawBias - t)
+	}
+	return int16(t - muLawBias)
+}
+
+func linearToALaw(pcm int16) byte {
+	v := int(pcm) >> 3
+	mask := 0xD5
+	if v < 0 {
+		v = -v - 1
+		mask = 0x55
+	}
+
+	segment := g711Segment(v, aLawSegmentEnds)
+	if segment >= 8 {
+		return byte(0x7F ^ mask)
+	}
+	a := segment << 4
+	if segment < 2 {
+		a |= (v >> 1) & 0x0F
+	} else {
+		a |= (v >> segment) & 0x0F
+	}
+	return byte(a ^ mask)
+}
+
+func aLawToLinear(a byte) int16 {
+	a ^= 0x55
+	t := int(a&0x0F) << 4
+	switch segment := (a & 0x70) >> 4; segment {
+	case 0:
+		t += 8
+	case 1:
+		t += 0x108
+	default:
+		t += 0x108
+		t <<= segment - 1
+	}
+	if a&0x80 != 0 {
+		return int16(t)
+	}
+	return int16(-t)
+}
@@ -0,0 +1,182 @@
+package vocals
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestMuLawReferenceValues(t *testing.T) {
+	// Values from the ITU G.711 reference implementation
+	encode := []struct {
+		pcm  int16
+		code byte
+	}{
+		{0, 0xFF},
+		{-1, 0x7E},
+		{32767, 0x80},
+		{-32768, 0x00},
+		{1000, 0xCE},
+		{-1000, 0x4E},
+	}
+	for _, tc := range encode {
+		if got := linearToMuLaw(tc.pcm); got != tc.code {
+			t.Errorf("linearToMuLaw(%d) = %#02x, want %#02x", tc.pcm, got, tc.code)
+		}
+	}
+
+	decode := []struct {
+		code byte
+		pcm  int16
+	}{
+		{0xFF, 0},
+		{0x80, 32124},
+		{0x00, -32124},
+		{0xCE, 988},
+		{0x4E, -988},
+	}
+	for _, tc := range decode {
+		if got := muLawToLinear(tc.code); got != tc.pcm {
+			t.Errorf("muLawToLinear(%#02x) = %d, want %d", tc.code, got, tc.pcm)
+		}
+	}
+}
+
+func TestALawReferenceValues(t *testing.T) {
+	encode := []struct {
+		pcm  int16
+		code byte
+	}{
+		{0, 0xD5},
+		{-1, 0x55},
+		{32767, 0xAA},
+		{-32768, 0x2A},
+		{1000, 0xFA},
+		{-1000, 0x7A},
+	}
+	for _, tc := range encode {
+		if got := linearToALaw(tc.pcm); got != tc.code {
+			t.Errorf("linearToALaw(%d) = %#02x, want %#02x", tc.pcm, got, tc.code)
+		}
+	}
+
+	decode := []struct {
+		code byte
+		pcm  int16
+	}{
+		{0xD5, 8},
+		{0x55, -8},
+		{0xAA, 32256},
+		{0x2A, -32256},
+		{0xFA, 1008},
+		{0x7A, -1008},
+	}
+	for _, tc := range decode {
+		if got := aLawToLinear(tc.code); got != tc.pcm {
+			t.Errorf("aLawToLinear(%#02x) = %d, want %d", tc.code, got, tc.pcm)
+		}
+	}
+}
+
+func TestG711CodesRoundTrip(t *testing.T) {
+	for i := 0; i < 256; i++ {
+		code := byte(i)
+		// 0x7F is mu-law's negative zero, which encodes back to 0xFF
+		if code != 0x7F {
+			if got := linearToMuLaw(muLawToLinear(code)); got != code {
+				t.Errorf("mu-law %#02x decodes to %d and encodes back to %#02x", code, muLawToLinear(code), got)
+			}
+		}
+		if got := linearToALaw(aLawToLinear(code)); got != code {
+			t.Errorf("a-law %#02x decodes to %d and encodes back to %#02x", code, aLawToLinear(code), got)
+		}
+	}
+}
+
+func TestPCMRoundTrip(t *testing.T) {
+	samples := make([]float32, 1000)
+	for i := range samples {
+		samples[i] = float32(0.9 * math.Sin(2*math.Pi*float64(i)/97))
+	}
+
+	tests := []struct {
+		format    string
+		size      int
+		tolerance float64
+	}{
+		{FormatPCMF32LE, 4, 0},
+		// Integer formats scale by 2^(n-1)-1 and back by 2^(n-1)
+		{FormatPCMS32LE, 4, 1e-7},
+		{FormatPCMS24LE, 3, 2.0 / 8388607},
+		{FormatPCMS16LE, 2, 2.0 / 32767},
+		{FormatPCMU8, 1, 2.0 / 127},
+		// Companding keeps about 12 bits near full scale
+		{FormatMuLaw, 1, 0.02},
+		{FormatALaw, 1, 0.02},
+	}
+	for _, tc := range tests {
+		t.Run(tc.format, func(t *testing.T) {
+			data, err := EncodePCM(samples, tc.format)
+			if err != nil {
+				t.Fatalf("EncodePCM: %v", err)
+			}
+			if len(data) != len(samples)*tc.size {
+				t.Fatalf("encoded %d samples into %d bytes, want %d", len(samples), len(data), len(samples)*tc.size)
+			}
+
+			decoded, err := DecodePCM(data, tc.format)
+			if err != nil {
+				t.Fatalf("DecodePCM: %v", err)
+			}
+			if len(decoded) != len(samples) {
+				t.Fatalf("decoded %d samples, want %d", len(decoded), len(samples))
+			}
+			for i := range samples {
+				if diff := math.Abs(float64(decoded[i] - samples[i])); diff > tc.tolerance {
+					t.Fatalf("sample %d: got %v, want %v (diff %g > %g)", i, decoded[i], samples[i], diff, tc.tolerance)
+				}
+			}
+		})
+	}
+}
+
+func TestEncodePCMClips(t *testing.T) {
+	for _, format := range []string{FormatPCMS16LE, FormatPCMS24LE, FormatPCMS32LE, FormatPCMU8, FormatMuLaw, FormatALaw} {
+		data, err := EncodePCM([]float32{2, -2}, format)
+		if err != nil {
+			t.Fatalf("%s: EncodePCM: %v", format, err)
+		}
+		decoded, err := DecodePCM(data, format)
+		if err != nil {
+			t.Fatalf("%s: DecodePCM: %v", format, err)
+		}
+		if decoded[0] < 0.9 || decoded[0] > 1 || decoded[1] > -0.9 || decoded[1] < -1 {
+			t.Errorf("%s: clipped samples decode to %v, want close to [1 -1]", format, decoded)
+		}
+	}
+}
+
+func TestDecodePCMIgnoresPartialSample(t *testing.T) {
+	samples, err := DecodePCM([]byte{0, 0x40, 0, 0xC0, 0x12}, FormatPCMS16LE)
+	if err != nil {
+		t.Fatalf("DecodePCM: %v", err)
+	}
+	if len(samples) != 2 || samples[0] != 0.5 || samples[1] != -0.5 {
+		t.Errorf("got %v, want [0.5 -0.5]", samples)
+	}
+}
+
+func TestUnsupportedPCMFormat(t *testing.T) {
+	if IsSupportedAudioFormat("opus") {
+		t.Error("opus reported as supported")
+	}
+
+	_, err := EncodePCM([]float32{0}, "opus")
+	var vErr *VocalsError
+	if !errors.As(err, &vErr) || vErr.Code != "UNSUPPORTED_AUDIO_FORMAT" {
+		t.Errorf("EncodePCM error = %v, want UNSUPPORTED_AUDIO_FORMAT", err)
+	}
+	if _, err := DecodePCM([]byte{0}, "opus"); err == nil {
+		t.Error("DecodePCM accepted an unsupported format")
+	}
+}
@@ -3,8 +3,6 @@ package vocals
 import (
 	"fmt"
 	"sync"
-
-	"github.com/gordonklaus/portaudio"
 )
 
 // AudioDevice represents an audio device
@@ -23,8 +21,9 @@ type AudioDevice struct {
 // AudioDeviceManager manages audio devices
 type AudioDeviceManager struct {
 	mu      sync.RWMutex
-	devices []AudioDevice
-	logger  *VocalsLogger
+	devices     []AudioDevice
+	initialized bool
+	logger      *VocalsLogger
 }
 
 // NewAudioDeviceManager creates a new audio device manager
@@ -40,9 +39,12 @@ func (adm *AudioDeviceManager) Initialize() error {
 	adm.mu.Lock()
 	defer adm.mu.Unlock()
 
-	if err := portaudio.Initialize(); err != nil {
-		adm.logger.WithError(err).Error("Failed to initialize PortAudio")
-		return err
+	if !adm.initialized {
+		if err := acquireAudioBackend(); err != nil {
+			adm.logger.WithError(err).Error("Failed to initialize audio backend")
+			return err
+		}
+		adm.initialized = true
 	}
 
 	if err := adm.refreshDevices(); err != nil {
@@ -59,8 +61,9 @@ func (adm *AudioDeviceManager) Cleanup() {
 	adm.mu.Lock()
 	defer adm.mu.Unlock()
 
-	if err := portaudio.Terminate(); err != nil {
-		adm.logger.WithError(err).Error("Failed to terminate PortAudio")
+	if adm.initialized {
+		releaseAudioBackend()
+		adm.initialized = false
 	}
 
 	adm.logger.Info("Audio device manager cleaned up")
@@ -68,54 +71,11 @@ func (adm *AudioDeviceManager) Cleanup() {
 
 // refreshDevices refreshes the device list
 func (adm *AudioDeviceManager) refreshDevices() error {
-	adm.devices = make([]AudioDevice, 0)
-
-	// Get default devices
-	defaultInput, err := portaudio.DefaultInputDevice()
-	if err != nil {
-		adm.logger.WithError(err).Warn("No default input device")
-	}
-
-	defaultOutput, err := portaudio.DefaultOutputDevice()
-	if err != nil {
-		adm.logger.WithError(err).Warn("No default output device")
-	}
-
-	// Get all devices
-	devices, err := portaudio.Devices()
+	devices, err := backendDevices()
 	if err != nil {
 		return err
 	}
-
-	for i, dev := range devices {
-		hostAPIName := "Unknown"
-		if dev.HostApi != nil {
-			hostAPIName = dev.HostApi.Name
-		}
-
-		device := AudioDevice{
-			ID:                i,
-			Name:              dev.Name,
-			MaxInputChannels:  dev.MaxInputChannels,
-			MaxOutputChannels: dev.MaxOutputChannels,
-			DefaultSampleRate: dev.DefaultSampleRate,
-			IsDefault:         false,
-			IsInput:           dev.MaxInputChannels > 0,
-			IsOutput:          dev.MaxOutputChannels > 0,
-			HostAPI:           hostAPIName,
-		}
-
-		// Check if it's a default device
-		if defaultInput != nil && dev == defaultInput {
-			device.IsDefault = true
-		}
-		if defaultOutput != nil && dev == defaultOutput {
-			device.IsDefault = true
-		}
-
-		adm.devices = append(adm.devices, device)
-	}
-
+	adm.devices = devices
 	return nil
 }
 
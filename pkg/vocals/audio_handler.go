@@ -11,8 +11,6 @@ import (
 	"path/filepath"
 	"sync"
 	"time"
-	
-	"github.com/gordonklaus/portaudio"
 )
 
 // AudioHandler manages local audio storage and processing
@@ -65,29 +63,30 @@ func (ah *AudioHandler) SetProcessFunc(fn AudioProcessFunc) {
 
 // HandleTTSAudio processes incoming TTS audio messages
 func (ah *AudioHandler) HandleTTSAudio(msg *WebSocketResponse) error {
-	if msg.Type == nil || *msg.Type != "tts_audio" {
+	if msg.Type == nil || *msg.Type != EventTypeTTSAudio {
 		return fmt.Errorf("not a TTS audio message")
 	}
 
-	data, ok := msg.Data.(map[string]interface{})
+	event, err := msg.Decode()
+	if err != nil {
+		return fmt.Errorf("invalid TTS message format: %v", err)
+	}
+	tts, ok := event.(TTSAudioEvent)
 	if !ok {
 		return fmt.Errorf("invalid TTS message format")
 	}
+	return ah.HandleTTSAudioEvent(tts)
+}
 
-	// Extract fields
-	segmentID := getString(data, "segment_id")
-	text := getString(data, "text")
-	audioDataB64 := getString(data, "audio_data")
-	sampleRate := getInt(data, "sample_rate")
-	format := getString(data, "format")
-	duration := getFloat64(data, "duration_seconds")
-
-	if segmentID == "" || audioDataB64 == "" {
-		return fmt.Errorf("missing required fields")
+// HandleTTSAudioEvent buffers, saves and processes a decoded TTS segment
+func (ah *AudioHandler) HandleTTSAudioEvent(event TTSAudioEvent) error {
+	if err := event.Validate(); err != nil {
+		return err
 	}
+	segmentID := event.SegmentID
 
 	// Decode base64 audio data
-	audioBytes, err := base64.StdEncoding.DecodeString(audioDataB64)
+	audioBytes, err := base64.StdEncoding.DecodeString(event.AudioData)
 	if err != nil {
 		return fmt.Errorf("failed to decode audio data: %v", err)
 	}
@@ -95,12 +94,12 @@ func (ah *AudioHandler) HandleTTSAudio(msg *WebSocketResponse) error {
 	// Create buffer entry
 	entry := AudioBufferEntry{
 		SegmentID:       segmentID,
-		Text:            text,
+		Text:            event.Text,
 		AudioData:       audioBytes,
-		SampleRate:      sampleRate,
-		Format:          format,
+		SampleRate:      event.SampleRate,
+		Format:          event.Format,
 		Timestamp:       time.Now(),
-		DurationSeconds: duration,
+		DurationSeconds: event.DurationSeconds,
 	}
 
 	// Add to buffer
@@ -233,34 +232,16 @@ type AudioHandlerStats struct {
 	OutputDirectory  string
 }
 
-// ConvertToFloat32Samples converts raw audio bytes to float32 samples
+// ConvertToFloat32Samples converts raw audio bytes in format to float32
+// samples. An empty format means pcm_f32le.
 func ConvertToFloat32Samples(audioData []byte, format string) ([]float32, error) {
 	if len(audioData) == 0 {
 		return nil, fmt.Errorf("empty audio data")
 	}
-
-	// For now, assume PCM float32 format (can extend for other formats)
-	if format != "pcm_f32le" && format != "" {
-		log.Printf("Warning: Unsupported format %s, assuming pcm_f32le", format)
-	}
-
-	// Convert bytes to float32 samples
-	numSamples := len(audioData) / 4
-	samples := make([]float32, numSamples)
-	
-	for i := 0; i < numSamples; i++ {
-		offset := i * 4
-		if offset+4 > len(audioData) {
-			break
-		}
-		bits := uint32(audioData[offset]) |
-			uint32(audioData[offset+1])<<8 |
-			uint32(audioData[offset+2])<<16 |
-			uint32(audioData[offset+3])<<24
-		samples[i] = float32(bits)
+	if format == "" {
+		format = FormatPCMF32LE
 	}
-
-	return samples, nil
+	return DecodePCM(audioData, format)
 }
 
 // MergeAudioBuffers merges multiple audio buffers into one
@@ -280,25 +261,20 @@ func MergeAudioBuffers(entries []AudioBufferEntry) ([]byte, error) {
 // PlayAudioEntry plays an audio entry through the speakers
 func (ah *AudioHandler) PlayAudioEntry(entry AudioBufferEntry) error {
 	// Convert bytes to float32 samples
-	samples, err := ConvertBytesToFloat32(entry.AudioData)
+	samples, err := ConvertToFloat32Samples(entry.AudioData, entry.Format)
 	if err != nil {
 		return fmt.Errorf("failed to convert audio data: %v", err)
 	}
 
-	// Initialize PortAudio if needed
-	if err := portaudio.Initialize(); err != nil {
-		return fmt.Errorf("failed to initialize audio: %v", err)
-	}
-	defer portaudio.Terminate()
-
-	// Create output stream
-	stream, err := portaudio.OpenDefaultStream(
-		0,                    // no input channels
-		1,                    // mono output
-		float64(entry.SampleRate), // sample rate
-		len(samples)/4,       // frames per buffer
-		&samples,             // audio data
-	)
+	// Create output stream fed from the decoded samples
+	position := 0
+	stream, err := openOutputStream(nil, 1, entry.SampleRate, 1024, func(out []float32) {
+		n := copy(out, samples[position:])
+		position += n
+		for i := n; i < len(out); i++ {
+			out[i] = 0
+		}
+	})
 	if err != nil {
 		return fmt.Errorf("failed to open audio stream: %v", err)
 	}
@@ -1,15 +1,14 @@
 package vocals
 
 import (
+	"context"
 	"encoding/base64"
-	"encoding/binary"
 	"fmt"
+	"io"
 	"log"
 	"math"
 	"sync"
 	"time"
-
-	"github.com/gordonklaus/portaudio"
 )
 
 type AudioConfig struct {
@@ -17,15 +16,26 @@ type AudioConfig struct {
 	Channels   int
 	Format     string
 	BufferSize int
-	DeviceID   *int
+	// DeviceID selects the input device and OutputDeviceID the output
+	// device; nil uses the system default
+	DeviceID       *int
+	OutputDeviceID *int
+	// DeviceSampleRate is the rate the input device is opened at when it
+	// differs from the wire SampleRate; 0 opens it at SampleRate
+	DeviceSampleRate int
+	// PlaybackSampleRate is the rate TTS audio is played at; 0 plays each
+	// segment at its own rate
+	PlaybackSampleRate int
+	ResampleQuality    string // ResampleQualityLow, ResampleQualityMedium or ResampleQualityHigh
 }
 
 func NewAudioConfig() *AudioConfig {
 	return &AudioConfig{
-		SampleRate: 24000,
-		Channels:   1,
-		Format:     "pcm_f32le",
-		BufferSize: 1024,
+		SampleRate:      24000,
+		Channels:        1,
+		Format:          FormatPCMF32LE,
+		BufferSize:      1024,
+		ResampleQuality: ResampleQualityMedium,
 	}
 }
 
@@ -38,27 +48,82 @@ type AudioProcessor struct {
 	isPlaying         bool
 	audioQueue        []TTSAudioSegment
 	currentSegment    *TTSAudioSegment
-	audioDataHandlers []AudioDataHandler
-	errorHandlers     []ErrorHandler
+	audioDataHandlers handlerList[AudioDataHandler]
+	errorHandlers     handlerList[ErrorHandler]
 	autoPlayback      bool
-	stream            *portaudio.Stream
+	source            AudioSource        // Source being recorded, nil when idle
+	stopCapture       context.CancelFunc // Stops the capture loop of source
+	captureDone       chan struct{}      // Closed when the capture loop exits
 	mu                sync.Mutex
 }
 
+// NewAudioProcessor creates a processor. Audio devices are only opened
+// when recording from the microphone or playing audio.
 func NewAudioProcessor(config *AudioConfig) *AudioProcessor {
-	portaudio.Initialize()
 	return &AudioProcessor{
-		config:            config,
-		recordingState:    IdleRecording,
-		playbackState:     IdlePlayback,
-		autoPlayback:      true,
-		audioQueue:        make([]TTSAudioSegment, 0),
-		audioDataHandlers: []AudioDataHandler{},
-		errorHandlers:     []ErrorHandler{},
+		config:         config,
+		recordingState: IdleRecording,
+		playbackState:  IdlePlayback,
+		autoPlayback:   audioBackendAvailable, // Headless builds only queue TTS audio
+		audioQueue:     make([]TTSAudioSegment, 0),
 	}
 }
 
 func (ap *AudioProcessor) StartRecording(handler func([]float32)) error {
+	var frameHandler func(AudioFrame)
+	if handler != nil {
+		frameHandler = func(frame AudioFrame) { handler(frame.Samples) }
+	}
+	return ap.startMicrophone(frameHandler)
+}
+
+// startMicrophone records the default input device at the configured
+// rate, passing whole frames to handler
+func (ap *AudioProcessor) startMicrophone(handler func(AudioFrame)) error {
+	ap.mu.Lock()
+	if ap.isRecording {
+		ap.mu.Unlock()
+		return fmt.Errorf("already recording")
+	}
+	ap.mu.Unlock()
+
+	var source AudioSource
+	device, err := NewPortAudioSource(ap.config)
+	if err == nil {
+		source = device
+		if device.SampleRate() != ap.config.SampleRate {
+			// Deliver the wire rate whatever rate the device runs at
+			source, err = NewResampledSource(device, ap.config.SampleRate, ap.config.Channels, ap.config.ResampleQuality)
+			if err != nil {
+				device.Close()
+			}
+		}
+	}
+	if err != nil {
+		ap.mu.Lock()
+		ap.recordingState = ErrorRecording
+		ap.mu.Unlock()
+		if vErr, ok := err.(*VocalsError); ok {
+			ap.handleError(vErr)
+		} else {
+			ap.handleError(NewVocalsError(err.Error(), "RECORDING_START_ERROR"))
+		}
+		return err
+	}
+
+	if err := ap.StartRecordingFrom(source, handler); err != nil {
+		source.Close()
+		return err
+	}
+	return nil
+}
+
+// StartRecordingFrom captures from source until it ends or StopRecording
+// is called, passing each frame to handler and the audio data handlers.
+// Frames are delivered no faster than real time, so files and generators
+// stream like a live microphone. The processor closes source when
+// recording stops.
+func (ap *AudioProcessor) StartRecordingFrom(source AudioSource, handler func(AudioFrame)) error {
 	ap.mu.Lock()
 	defer ap.mu.Unlock()
 
@@ -68,58 +133,118 @@ func (ap *AudioProcessor) StartRecording(handler func([]float32)) error {
 	ap.recordingState = Recording
 	ap.isRecording = true
 
-	var err error
-	ap.stream, err = portaudio.OpenDefaultStream(ap.config.Channels, 0, float64(ap.config.SampleRate), ap.config.BufferSize, func(in []float32) {
-		ap.currentAmplitude = 0
-		for _, v := range in {
-			ap.currentAmplitude += float32(math.Abs(float64(v)))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	ap.source = source
+	ap.stopCapture = cancel
+	ap.captureDone = done
+	go ap.captureLoop(ctx, source, handler, done)
+
+	log.Println("Recording started")
+	return nil
+}
+
+// captureLoop reads source until it ends or ctx is cancelled
+func (ap *AudioProcessor) captureLoop(ctx context.Context, source AudioSource, handler func(AudioFrame), done chan struct{}) {
+	defer close(done)
+
+	start := time.Now()
+	for {
+		frame, err := source.ReadFrame(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			if err != io.EOF {
+				ap.handleError(NewVocalsError(fmt.Sprintf("Failed to read audio: %v", err), "RECORDING_READ_ERROR"))
+			}
+			ap.finishCapture(source, err == io.EOF)
+			return
+		}
+		if len(frame.Samples) == 0 {
+			continue
 		}
-		ap.currentAmplitude /= float32(len(in))
+
+		// Pace sources that produce audio faster than real time. A paced
+		// frame is captured when it is released, like live audio.
+		if due := start.Add(frame.Timestamp); time.Until(due) > 0 {
+			if err := sleepContext(ctx, time.Until(due)); err != nil {
+				return
+			}
+			frame.CapturedAt = due
+		} else if frame.CapturedAt.IsZero() {
+			frame.CapturedAt = time.Now()
+		}
+
+		amplitude := float32(0)
+		for _, v := range frame.Samples {
+			amplitude += float32(math.Abs(float64(v)))
+		}
+		ap.mu.Lock()
+		ap.currentAmplitude = amplitude / float32(len(frame.Samples))
+		ap.mu.Unlock()
 
 		if handler != nil {
-			handler(in)
+			handler(frame)
 		}
-		for _, h := range ap.audioDataHandlers {
-			go h(in) // Non-blocking
+		for _, h := range ap.audioDataHandlers.snapshot() {
+			go h(frame.Samples) // Non-blocking
 		}
-	})
-	if err != nil {
-		ap.recordingState = ErrorRecording
-		ap.isRecording = false
-		ap.handleError(NewVocalsError(err.Error(), "RECORDING_OPEN_ERROR"))
-		return err
 	}
+}
 
-	if err := ap.stream.Start(); err != nil {
-		ap.recordingState = ErrorRecording
+// finishCapture ends a recording whose source stopped on its own
+func (ap *AudioProcessor) finishCapture(source AudioSource, completed bool) {
+	ap.mu.Lock()
+	if ap.source == source {
 		ap.isRecording = false
-		ap.handleError(NewVocalsError(err.Error(), "RECORDING_START_ERROR"))
-		return err
+		ap.source = nil
+		ap.stopCapture()
+		if completed {
+			ap.recordingState = CompletedRecording
+		} else {
+			ap.recordingState = ErrorRecording
+		}
 	}
+	ap.mu.Unlock()
 
-	log.Println("Recording started")
-	return nil
+	if err := source.Close(); err != nil {
+		log.Printf("Error closing audio source: %v", err)
+	}
+	log.Println("Recording finished")
 }
 
-func (ap *AudioProcessor) StopRecording() error {
+// recordingDone returns a channel closed when the current recording ends,
+// or nil when not recording
+func (ap *AudioProcessor) recordingDone() <-chan struct{} {
 	ap.mu.Lock()
 	defer ap.mu.Unlock()
+	if !ap.isRecording {
+		return nil
+	}
+	return ap.captureDone
+}
 
+func (ap *AudioProcessor) StopRecording() error {
+	ap.mu.Lock()
 	if !ap.isRecording {
+		ap.mu.Unlock()
 		return nil
 	}
 
 	ap.isRecording = false
 	ap.recordingState = IdleRecording
+	source := ap.source
+	ap.source = nil
+	ap.stopCapture()
+	ap.mu.Unlock()
 
-	if ap.stream != nil {
-		if err := ap.stream.Stop(); err != nil {
-			ap.handleError(NewVocalsError(err.Error(), "RECORDING_STOP_ERROR"))
-		}
-		if err := ap.stream.Close(); err != nil {
+	if err := source.Close(); err != nil {
+		if vErr, ok := err.(*VocalsError); ok {
+			ap.handleError(vErr)
+		} else {
 			ap.handleError(NewVocalsError(err.Error(), "RECORDING_CLOSE_ERROR"))
 		}
-		ap.stream = nil
 	}
 
 	log.Println("Recording stopped")
@@ -171,20 +296,41 @@ func (ap *AudioProcessor) playNextSegment() {
 		return
 	}
 
-	// Convert to float32 if needed (assuming input is pcm_f32le little-endian)
-	samples := make([]float32, len(audioData)/4)
-	for i := 0; i < len(samples); i++ {
-		bits := binary.LittleEndian.Uint32(audioData[i*4 : (i+1)*4])
-		samples[i] = math.Float32frombits(bits)
+	// Convert from the segment's wire format, pcm_f32le unless stated
+	samples, err := ConvertToFloat32Samples(audioData, segment.Format)
+	if err != nil {
+		ap.handleError(NewVocalsError(fmt.Sprintf("Failed to decode audio data: %v", err), "AUDIO_DECODE_ERROR"))
+		ap.mu.Lock()
+		ap.playbackState = ErrorPlayback
+		ap.currentSegment = nil
+		ap.mu.Unlock()
+		go ap.playNextSegment() // Try next
+		return
 	}
 
 	// Create a channel to signal when playback is complete
 	done := make(chan bool, 1)
 	sampleIndex := 0
 	var mu sync.Mutex
-	
+
+	ap.mu.Lock()
+	outputDeviceID := ap.config.OutputDeviceID
+	playbackRate := ap.config.PlaybackSampleRate
+	quality := ap.config.ResampleQuality
+	ap.mu.Unlock()
+
+	sampleRate := segment.SampleRate
+	if playbackRate > 0 && playbackRate != sampleRate {
+		resampled, err := Resample(samples, sampleRate, playbackRate, ap.config.Channels, quality)
+		if err != nil {
+			log.Printf("Failed to resample audio segment, playing at %d Hz: %v", sampleRate, err)
+		} else {
+			samples, sampleRate = resampled, playbackRate
+		}
+	}
+
 	// Open playback stream with callback that feeds audio data
-	stream, err := portaudio.OpenDefaultStream(0, ap.config.Channels, float64(segment.SampleRate), ap.config.BufferSize, func(out []float32) {
+	stream, err := openOutputStream(outputDeviceID, ap.config.Channels, sampleRate, ap.config.BufferSize, func(out []float32) {
 		mu.Lock()
 		defer mu.Unlock()
 		
@@ -233,7 +379,7 @@ func (ap *AudioProcessor) playNextSegment() {
 	select {
 	case <-done:
 		log.Printf("Audio playback completed")
-	case <-time.After(time.Duration(float64(len(samples))/float64(segment.SampleRate)*1.5) * time.Second):
+	case <-time.After(time.Duration(float64(len(samples))/float64(sampleRate)*1.5) * time.Second):
 		log.Printf("Audio playback timeout")
 	}
 
@@ -317,50 +463,23 @@ func (ap *AudioProcessor) FadeOutAudio(duration time.Duration) {
 
 func (ap *AudioProcessor) handleError(err *VocalsError) {
 	log.Printf("Audio error: %s (%s)", err.Message, err.Code)
-	for _, handler := range ap.errorHandlers {
+	for _, handler := range ap.errorHandlers.snapshot() {
 		go handler(err)
 	}
 }
 
 func (ap *AudioProcessor) AddAudioDataHandler(handler AudioDataHandler) func() {
-	ap.mu.Lock()
-	ap.audioDataHandlers = append(ap.audioDataHandlers, handler)
-	ap.mu.Unlock()
-
-	return func() {
-		ap.mu.Lock()
-		for i, h := range ap.audioDataHandlers {
-			if &h == &handler {
-				ap.audioDataHandlers = append(ap.audioDataHandlers[:i], ap.audioDataHandlers[i+1:]...)
-				break
-			}
-		}
-		ap.mu.Unlock()
-	}
+	return ap.audioDataHandlers.add(handler)
 }
 
 func (ap *AudioProcessor) AddErrorHandler(handler ErrorHandler) func() {
-	ap.mu.Lock()
-	ap.errorHandlers = append(ap.errorHandlers, handler)
-	ap.mu.Unlock()
-
-	return func() {
-		ap.mu.Lock()
-		for i, h := range ap.errorHandlers {
-			if &h == &handler {
-				ap.errorHandlers = append(ap.errorHandlers[:i], ap.errorHandlers[i+1:]...)
-				break
-			}
-		}
-		ap.mu.Unlock()
-	}
+	return ap.errorHandlers.add(handler)
 }
 
 func (ap *AudioProcessor) Cleanup() {
 	ap.StopRecording()
 	ap.StopPlayback()
 	ap.ClearQueue()
-	portaudio.Terminate()
 	log.Println("Audio processor cleaned up")
 }
 
@@ -410,11 +529,11 @@ func (ap *AudioProcessor) ProcessQueue(callback func(TTSAudioSegment), consumeAl
 }
 
 func ListAudioDevices() []map[string]interface{} {
-	if err := portaudio.Initialize(); err != nil {
-		log.Printf("Failed to initialize portaudio: %v", err)
+	if err := acquireAudioBackend(); err != nil {
+		log.Printf("Failed to initialize audio: %v", err)
 		return []map[string]interface{}{}
 	}
-	defer portaudio.Terminate()
+	defer releaseAudioBackend()
 
 	var devices []map[string]interface{}
 	
@@ -429,7 +548,21 @@ func ListAudioDevices() []map[string]interface{} {
 	return devices
 }
 
+// SetDeviceID selects the input device. While recording from a device,
+// capture moves to the new one without interrupting the recording; if the
+// device cannot be opened the error is returned and the old one is kept.
 func (ap *AudioProcessor) SetDeviceID(deviceID int) error {
+	ap.mu.Lock()
+	source, live := deviceSource(ap.source)
+	ap.mu.Unlock()
+
+	if live {
+		if err := source.SwitchDevice(&deviceID); err != nil {
+			return err
+		}
+		log.Printf("Recording switched to input device %d", deviceID)
+	}
+
 	ap.mu.Lock()
 	defer ap.mu.Unlock()
 	ap.config.DeviceID = &deviceID
@@ -441,3 +574,27 @@ func (ap *AudioProcessor) GetDeviceID() *int {
 	defer ap.mu.Unlock()
 	return ap.config.DeviceID
 }
+
+// deviceSource returns the input device source behind source, if any
+func deviceSource(source AudioSource) (*PortAudioSource, bool) {
+	if resampled, ok := source.(*ResampledSource); ok {
+		source = resampled.source
+	}
+	device, ok := source.(*PortAudioSource)
+	return device, ok
+}
+
+// SetOutputDeviceID selects the output device, starting with the next
+// segment played
+func (ap *AudioProcessor) SetOutputDeviceID(deviceID int) error {
+	ap.mu.Lock()
+	defer ap.mu.Unlock()
+	ap.config.OutputDeviceID = &deviceID
+	return nil
+}
+
+func (ap *AudioProcessor) GetOutputDeviceID() *int {
+	ap.mu.Lock()
+	defer ap.mu.Unlock()
+	return ap.config.OutputDeviceID
+}
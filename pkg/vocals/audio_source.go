@@ -0,0 +1,502 @@
+package vocals
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+)
+
+// AudioFrame is a block of interleaved float32 samples from an AudioSource
+type AudioFrame struct {
+	Samples    []float32
+	SampleRate int
+	Channels   int
+	Timestamp  time.Duration // Position of the first sample since the source started
+	CapturedAt time.Time     // Wall-clock time the frame was captured or produced
+}
+
+// Duration returns the length of the frame
+func (f AudioFrame) Duration() time.Duration {
+	if f.SampleRate <= 0 || f.Channels <= 0 {
+		return 0
+	}
+	return time.Duration(len(f.Samples)) * time.Second / time.Duration(f.SampleRate*f.Channels)
+}
+
+// AudioSource produces the audio a client streams. ReadFrame blocks until
+// the next frame is available and returns io.EOF once the source is
+// exhausted. Close releases the source and may be called while ReadFrame
+// is blocked.
+type AudioSource interface {
+	ReadFrame(ctx context.Context) (AudioFrame, error)
+	Close() error
+}
+
+var errAudioSourceClosed = errors.New("audio source closed")
+
+// frameClock stamps frames by the number of samples produced so far
+type frameClock struct {
+	sampleRate int
+	channels   int
+	samples    int64
+}
+
+func (c *frameClock) frame(samples []float32) AudioFrame {
+	frame := AudioFrame{
+		Samples:    samples,
+		SampleRate: c.sampleRate,
+		Channels:   c.channels,
+		Timestamp:  time.Duration(c.samples) * time.Second / time.Duration(c.sampleRate*c.channels),
+		CapturedAt: time.Now(),
+	}
+	c.samples += int64(len(samples))
+	return frame
+}
+
+// PCMReaderSource reads raw interleaved PCM from an io.Reader, such as an
+// HTTP request body
+type PCMReaderSource struct {
+	reader     io.Reader
+	format     string
+	sampleSize int
+	frameBytes int
+	clock      frameClock
+	closed     chan struct{}
+	closeOnce  sync.Once
+}
+
+// NewPCMReaderSource reads PCM in config's Format, SampleRate and Channels,
+// BufferSize samples per channel at a time. Every format DecodePCM handles
+// is supported, including G.711 telephony audio.
+func NewPCMReaderSource(r io.Reader, config *AudioConfig) (*PCMReaderSource, error) {
+	sampleSize, err := pcmSampleSize(config.Format)
+	if err != nil {
+		return nil, err
+	}
+	if err := ValidateAudioConfig(config); err != nil {
+		return nil, err
+	}
+	return &PCMReaderSource{
+		reader:     r,
+		format:     config.Format,
+		sampleSize: sampleSize,
+		frameBytes: config.BufferSize * config.Channels * sampleSize,
+		clock:      frameClock{sampleRate: config.SampleRate, channels: config.Channels},
+		closed:     make(chan struct{}),
+	}, nil
+}
+
+func (s *PCMReaderSource) ReadFrame(ctx context.Context) (AudioFrame, error) {
+	select {
+	case <-s.closed:
+		return AudioFrame{}, errAudioSourceClosed
+	case <-ctx.Done():
+		return AudioFrame{}, ctx.Err()
+	default:
+	}
+
+	buf := make([]byte, s.frameBytes)
+	n, err := io.ReadFull(s.reader, buf)
+	// Keep whole sample frames of a short final read
+	n -= n % (s.sampleSize * s.clock.channels)
+	if n == 0 {
+		if err == nil || err == io.ErrUnexpectedEOF {
+			err = io.EOF
+		}
+		return AudioFrame{}, err
+	}
+
+	samples, decodeErr := DecodePCM(buf[:n], s.format)
+	if decodeErr != nil {
+		return AudioFrame{}, decodeErr
+	}
+	return s.clock.frame(samples), nil
+}
+
+// Close closes the reader if it is an io.Closer
+func (s *PCMReaderSource) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		close(s.closed)
+		if closer, ok := s.reader.(io.Closer); ok {
+			err = closer.Close()
+		}
+	})
+	return err
+}
+
+// WAVSource reads a WAV file. The sample rate and channel count come from
+// its header.
+type WAVSource struct {
+	*PCMReaderSource
+	SampleRate int
+	Channels   int
+	Format     string // PCM format of the data chunk, e.g. pcm_s16le
+}
+
+// NewWAVSource parses the WAV header in r and reads frameSize samples per
+// channel at a time, 1024 when frameSize is 0. Closing the source closes r
+// if it is an io.Closer.
+func NewWAVSource(r io.Reader, frameSize int) (*WAVSource, error) {
+	reader := bufio.NewReader(r)
+	header := make([]byte, 12)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return nil, NewVocalsError(fmt.Sprintf("Failed to read WAV header: %v", err), "INVALID_WAV")
+	}
+	if string(header[0:4]) != "RIFF" || string(header[8:12]) != "WAVE" {
+		return nil, NewVocalsError("Not a WAV file", "INVALID_WAV")
+	}
+
+	var format string
+	var sampleRate, channels int
+	for {
+		chunk := make([]byte, 8)
+		if _, err := io.ReadFull(reader, chunk); err != nil {
+			return nil, NewVocalsError("WAV file has no data chunk", "INVALID_WAV")
+		}
+		id := string(chunk[0:4])
+		size := int64(binary.LittleEndian.Uint32(chunk[4:8]))
+
+		switch id {
+		case "fmt ":
+			if size < 16 {
+				return nil, NewVocalsError("WAV fmt chunk too short", "INVALID_WAV")
+			}
+			fmtChunk := make([]byte, size)
+			if _, err := io.ReadFull(reader, fmtChunk); err != nil {
+				return nil, NewVocalsError(fmt.Sprintf("Failed to read WAV fmt chunk: %v", err), "INVALID_WAV")
+			}
+			audioFormat := binary.LittleEndian.Uint16(fmtChunk[0:2])
+			channels = int(binary.LittleEndian.Uint16(fmtChunk[2:4]))
+			sampleRate = int(binary.LittleEndian.Uint32(fmtChunk[4:8]))
+			bits := int(binary.LittleEndian.Uint16(fmtChunk[14:16]))
+			if audioFormat == 0xFFFE && size >= 26 {
+				// WAVE_FORMAT_EXTENSIBLE keeps the real format in the sub-format GUID
+				audioFormat = binary.LittleEndian.Uint16(fmtChunk[24:26])
+			}
+			var err error
+			if format, err = wavPCMFormat(audioFormat, bits); err != nil {
+				return nil, err
+			}
+			if size%2 == 1 {
+				reader.Discard(1)
+			}
+		case "data":
+			if format == "" {
+				return nil, NewVocalsError("WAV data chunk precedes fmt chunk", "INVALID_WAV")
+			}
+			var data io.Reader = io.LimitReader(reader, size)
+			if closer, ok := r.(io.Closer); ok {
+				data = struct {
+					io.Reader
+					io.Closer
+				}{data, closer}
+			}
+			if frameSize <= 0 {
+				frameSize = 1024
+			}
+			config := &AudioConfig{SampleRate: sampleRate, Channels: channels, Format: format, BufferSize: frameSize}
+			source, err := NewPCMReaderSource(data, config)
+			if err != nil {
+				return nil, err
+			}
+			return &WAVSource{PCMReaderSource: source, SampleRate: sampleRate, Channels: channels, Format: format}, nil
+		default:
+			if _, err := reader.Discard(int(size + size%2)); err != nil {
+				return nil, NewVocalsError(fmt.Sprintf("Failed to skip WAV %q chunk: %v", id, err), "INVALID_WAV")
+			}
+		}
+	}
+}
+
+// OpenWAVSource opens the WAV file at path
+func OpenWAVSource(path string, frameSize int) (*WAVSource, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, NewVocalsError(fmt.Sprintf("Failed to open WAV file: %v", err), "INVALID_WAV")
+	}
+	source, err := NewWAVSource(file, frameSize)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return source, nil
+}
+
+// wavPCMFormat maps a WAV format tag and bit depth to a PCM format name
+func wavPCMFormat(audioFormat uint16, bits int) (string, error) {
+	switch {
+	case audioFormat == 1 && bits == 8:
+		return "pcm_u8", nil
+	case audioFormat == 1 && bits == 16:
+		return "pcm_s16le", nil
+	case audioFormat == 1 && bits == 24:
+		return "pcm_s24le", nil
+	case audioFormat == 1 && bits == 32:
+		return "pcm_s32le", nil
+	case audioFormat == 3 && bits == 32:
+		return "pcm_f32le", nil
+	}
+	return "", NewVocalsError(fmt.Sprintf("Unsupported WAV encoding: format %d, %d bits", audioFormat, bits), "UNSUPPORTED_AUDIO_FORMAT")
+}
+
+// Waveforms produced by a GeneratorSource
+const (
+	WaveformSine    = "sine"
+	WaveformNoise   = "noise"
+	WaveformSilence = "silence"
+)
+
+// GeneratorConfig describes synthetic audio
+type GeneratorConfig struct {
+	Waveform   string        // WaveformSine, WaveformNoise or WaveformSilence
+	Frequency  float64       // Sine frequency in Hz, 440 when 0
+	Amplitude  float32       // Peak amplitude, 0.5 when 0
+	SampleRate int           // 24000 when 0
+	Channels   int           // 1 when 0
+	FrameSize  int           // Samples per channel per frame, 1024 when 0
+	Duration   time.Duration // Total length, unlimited when 0
+}
+
+// GeneratorSource produces synthetic audio for tests and load generation
+type GeneratorSource struct {
+	config    GeneratorConfig
+	clock     frameClock
+	phase     float64
+	produced  int64 // Samples per channel produced so far
+	rng       *rand.Rand
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// NewGeneratorSource creates a source producing config's waveform
+func NewGeneratorSource(config GeneratorConfig) (*GeneratorSource, error) {
+	switch config.Waveform {
+	case WaveformSine, WaveformNoise, WaveformSilence:
+	default:
+		return nil, NewVocalsError(fmt.Sprintf("Unknown waveform: %s", config.Waveform), "INVALID_WAVEFORM")
+	}
+	if config.Frequency == 0 {
+		config.Frequency = 440
+	}
+	if config.Amplitude == 0 {
+		config.Amplitude = 0.5
+	}
+	if config.SampleRate <= 0 {
+		config.SampleRate = 24000
+	}
+	if config.Channels <= 0 {
+		config.Channels = 1
+	}
+	if config.FrameSize <= 0 {
+		config.FrameSize = 1024
+	}
+	return &GeneratorSource{
+		config: config,
+		clock:  frameClock{sampleRate: config.SampleRate, channels: config.Channels},
+		rng:    rand.New(rand.NewSource(time.Now().UnixNano())),
+		closed: make(chan struct{}),
+	}, nil
+}
+
+func (s *GeneratorSource) ReadFrame(ctx context.Context) (AudioFrame, error) {
+	select {
+	case <-s.closed:
+		return AudioFrame{}, errAudioSourceClosed
+	case <-ctx.Done():
+		return AudioFrame{}, ctx.Err()
+	default:
+	}
+
+	frameSize := int64(s.config.FrameSize)
+	if s.config.Duration > 0 {
+		total := int64(s.config.Duration) * int64(s.config.SampleRate) / int64(time.Second)
+		if remaining := total - s.produced; remaining < frameSize {
+			frameSize = remaining
+		}
+		if frameSize <= 0 {
+			return AudioFrame{}, io.EOF
+		}
+	}
+
+	samples := make([]float32, int(frameSize)*s.config.Channels)
+	step := 2 * math.Pi * s.config.Frequency / float64(s.config.SampleRate)
+	for i := 0; i < int(frameSize); i++ {
+		var v float32
+		switch s.config.Waveform {
+		case WaveformSine:
+			v = s.config.Amplitude * float32(math.Sin(s.phase))
+			s.phase = math.Mod(s.phase+step, 2*math.Pi)
+		case WaveformNoise:
+			v = s.config.Amplitude * (2*s.rng.Float32() - 1)
+		}
+		for ch := 0; ch < s.config.Channels; ch++ {
+			samples[i*s.config.Channels+ch] = v
+		}
+	}
+	s.produced += frameSize
+	return s.clock.frame(samples), nil
+}
+
+func (s *GeneratorSource) Close() error {
+	s.closeOnce.Do(func() { close(s.closed) })
+	return nil
+}
+
+// ChannelSource streams sample blocks sent on a Go channel. The source
+// ends when the channel is closed.
+type ChannelSource struct {
+	ch        <-chan []float32
+	clock     frameClock
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// NewChannelSource reads interleaved samples at sampleRate from ch
+func NewChannelSource(ch <-chan []float32, sampleRate, channels int) (*ChannelSource, error) {
+	if sampleRate <= 0 {
+		return nil, NewVocalsError("Invalid sample rate", "INVALID_SAMPLE_RATE")
+	}
+	if channels <= 0 {
+		return nil, NewVocalsError("Invalid channel count", "INVALID_CHANNELS")
+	}
+	return &ChannelSource{
+		ch:     ch,
+		clock:  frameClock{sampleRate: sampleRate, channels: channels},
+		closed: make(chan struct{}),
+	}, nil
+}
+
+func (s *ChannelSource) ReadFrame(ctx context.Context) (AudioFrame, error) {
+	select {
+	case samples, ok := <-s.ch:
+		if !ok {
+			return AudioFrame{}, io.EOF
+		}
+		return s.clock.frame(samples), nil
+	case <-s.closed:
+		return AudioFrame{}, errAudioSourceClosed
+	case <-ctx.Done():
+		return AudioFrame{}, ctx.Err()
+	}
+}
+
+// Close stops reading; the channel itself belongs to the sender
+func (s *ChannelSource) Close() error {
+	s.closeOnce.Do(func() { close(s.closed) })
+	return nil
+}
+
+// ResampledSource converts the frames of another source to a fixed sample
+// rate and channel count, keeping the resampler state across frames. Frames
+// already in that format pass through unchanged.
+type ResampledSource struct {
+	source    AudioSource
+	rate      int
+	channels  int
+	quality   string
+	resampler *Resampler
+	clock     frameClock
+	flushed   bool
+}
+
+// NewResampledSource converts source's frames to sampleRate and channels
+// with the given ResampleQuality level. Channels are mixed down by
+// averaging, so stereo becomes mono, and mixed up by repeating them.
+func NewResampledSource(source AudioSource, sampleRate, channels int, quality string) (*ResampledSource, error) {
+	if sampleRate <= 0 {
+		return nil, NewVocalsError("Invalid sample rate", "INVALID_SAMPLE_RATE")
+	}
+	if channels <= 0 {
+		return nil, NewVocalsError("Invalid channel count", "INVALID_CHANNELS")
+	}
+	if _, ok := resampleProfiles[quality]; quality != "" && !ok {
+		return nil, NewVocalsError(fmt.Sprintf("Unknown resample quality %q", quality), "INVALID_RESAMPLE_QUALITY")
+	}
+	return &ResampledSource{
+		source:   source,
+		rate:     sampleRate,
+		channels: channels,
+		quality:  quality,
+		clock:    frameClock{sampleRate: sampleRate, channels: channels},
+	}, nil
+}
+
+func (s *ResampledSource) ReadFrame(ctx context.Context) (AudioFrame, error) {
+	for {
+		frame, err := s.source.ReadFrame(ctx)
+		if err == io.EOF && s.resampler != nil && !s.flushed {
+			s.flushed = true
+			if tail := s.resampler.Flush(); len(tail) > 0 {
+				return s.clock.frame(tail), nil
+			}
+		}
+		if err != nil {
+			return AudioFrame{}, err
+		}
+		if channels := max(frame.Channels, 1); channels != s.channels {
+			frame.Samples = remixChannels(frame.Samples, channels, s.channels)
+			frame.Channels = s.channels
+		}
+		if s.resampler == nil && frame.SampleRate == s.rate {
+			return frame, nil
+		}
+
+		if s.resampler == nil || s.resampler.InputRate() != frame.SampleRate {
+			resampler, err := NewResampler(frame.SampleRate, s.rate, s.channels, s.quality)
+			if err != nil {
+				return AudioFrame{}, err
+			}
+			s.resampler = resampler
+			s.clock.samples = int64(frame.Timestamp) * int64(s.rate*s.channels) / int64(time.Second)
+		}
+
+		// The filter holds back its look-ahead, so the first frames may
+		// produce nothing yet
+		samples := s.resampler.Process(frame.Samples)
+		if len(samples) == 0 {
+			continue
+		}
+		out := s.clock.frame(samples)
+		out.CapturedAt = frame.CapturedAt
+		return out, nil
+	}
+}
+
+// Close closes the underlying source
+func (s *ResampledSource) Close() error {
+	return s.source.Close()
+}
+
+// remixChannels converts interleaved samples from one channel count to
+// another. Each output channel averages the input channels that fold onto
+// it, or repeats an input channel when there are more outputs than inputs.
+func remixChannels(samples []float32, from, to int) []float32 {
+	frames := len(samples) / from
+	out := make([]float32, frames*to)
+	for f := 0; f < frames; f++ {
+		in := samples[f*from : (f+1)*from]
+		for c := 0; c < to; c++ {
+			if from < to {
+				out[f*to+c] = in[c%from]
+				continue
+			}
+			var sum float32
+			n := 0
+			for i := c; i < from; i += to {
+				sum += in[i]
+				n++
+			}
+			out[f*to+c] = sum / float32(n)
+		}
+	}
+	return out
+}
@@ -0,0 +1,156 @@
+package vocals
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// PortAudioSource captures from an input device. It is unavailable in
+// headless builds.
+type PortAudioSource struct {
+	config    AudioConfig
+	deviceID  *int
+	stream    audioStream
+	active    atomic.Int64 // Generation of the stream whose frames are kept
+	frames    chan AudioFrame
+	clock     frameClock
+	clockMu   sync.Mutex
+	dropped   atomic.Int64
+	mu        sync.Mutex
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// NewPortAudioSource opens and starts an input stream on config's device,
+// or on the default input device when DeviceID is nil, with config's
+// channel count and buffer size. The stream runs at DeviceSampleRate when
+// set and at SampleRate otherwise. Frames the reader does not keep up with
+// are dropped.
+func NewPortAudioSource(config *AudioConfig) (*PortAudioSource, error) {
+	s := &PortAudioSource{
+		config: *config,
+		frames: make(chan AudioFrame, 32),
+		closed: make(chan struct{}),
+	}
+	if s.config.DeviceSampleRate > 0 {
+		s.config.SampleRate = s.config.DeviceSampleRate
+	}
+	s.clock = frameClock{sampleRate: s.config.SampleRate, channels: s.config.Channels}
+
+	stream, err := s.openStream(config.DeviceID, 1)
+	if err != nil {
+		return nil, err
+	}
+	s.active.Store(1)
+	s.deviceID = config.DeviceID
+	s.stream = stream
+	return s, nil
+}
+
+// openStream opens and starts a stream on deviceID whose frames are kept
+// while generation is active
+func (s *PortAudioSource) openStream(deviceID *int, generation int64) (audioStream, error) {
+	stream, err := openInputStream(deviceID, s.config.Channels, s.config.SampleRate, s.config.BufferSize, func(in []float32) {
+		if s.active.Load() != generation {
+			return
+		}
+
+		// PortAudio reuses the buffer between callbacks
+		s.clockMu.Lock()
+		frame := s.clock.frame(append([]float32(nil), in...))
+		s.clockMu.Unlock()
+		select {
+		case s.frames <- frame:
+		default:
+			s.dropped.Add(1)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := stream.Start(); err != nil {
+		stream.Close()
+		return nil, NewVocalsError(err.Error(), "RECORDING_START_ERROR")
+	}
+	return stream, nil
+}
+
+// SwitchDevice moves capture to deviceID, or to the default input device
+// when deviceID is nil. The new stream is started before the old one is
+// closed, and frame timestamps continue where the old stream stopped, so
+// readers see one uninterrupted recording. On failure the old device keeps
+// capturing.
+func (s *PortAudioSource) SwitchDevice(deviceID *int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	select {
+	case <-s.closed:
+		return errAudioSourceClosed
+	default:
+	}
+
+	generation := s.active.Load() + 1
+	stream, err := s.openStream(deviceID, generation)
+	if err != nil {
+		return err
+	}
+
+	old := s.stream
+	s.active.Store(generation)
+	s.stream = stream
+	s.deviceID = deviceID
+
+	old.Stop()
+	old.Close()
+	return nil
+}
+
+// SampleRate returns the rate the device captures at
+func (s *PortAudioSource) SampleRate() int {
+	return s.config.SampleRate
+}
+
+// DeviceID returns the device being captured, or nil for the default
+// input device
+func (s *PortAudioSource) DeviceID() *int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.deviceID
+}
+
+func (s *PortAudioSource) ReadFrame(ctx context.Context) (AudioFrame, error) {
+	select {
+	case frame := <-s.frames:
+		return frame, nil
+	case <-s.closed:
+		return AudioFrame{}, errAudioSourceClosed
+	case <-ctx.Done():
+		return AudioFrame{}, ctx.Err()
+	}
+}
+
+// Dropped returns the number of frames discarded because the reader fell
+// behind
+func (s *PortAudioSource) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+// Close stops and closes the input stream
+func (s *PortAudioSource) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		close(s.closed)
+		if stopErr := s.stream.Stop(); stopErr != nil {
+			err = NewVocalsError(stopErr.Error(), "RECORDING_STOP_ERROR")
+		}
+		if closeErr := s.stream.Close(); closeErr != nil && err == nil {
+			err = NewVocalsError(closeErr.Error(), "RECORDING_CLOSE_ERROR")
+		}
+	})
+	return err
+}
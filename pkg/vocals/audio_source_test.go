@@ -0,0 +1,268 @@
+package vocals
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"testing"
+	"time"
+)
+
+// buildWAV returns a 16-bit PCM WAV file holding samples, with an extra
+// chunk before the data that readers must skip
+func buildWAV(sampleRate, channels int, samples []int16) []byte {
+	var fmtChunk bytes.Buffer
+	binary.Write(&fmtChunk, binary.LittleEndian, uint16(1))
+	binary.Write(&fmtChunk, binary.LittleEndian, uint16(channels))
+	binary.Write(&fmtChunk, binary.LittleEndian, uint32(sampleRate))
+	binary.Write(&fmtChunk, binary.LittleEndian, uint32(sampleRate*channels*2))
+	binary.Write(&fmtChunk, binary.LittleEndian, uint16(channels*2))
+	binary.Write(&fmtChunk, binary.LittleEndian, uint16(16))
+
+	var data bytes.Buffer
+	binary.Write(&data, binary.LittleEndian, samples)
+
+	var body bytes.Buffer
+	body.WriteString("WAVE")
+	for _, chunk := range []struct {
+		id   string
+		data []byte
+	}{{"fmt ", fmtChunk.Bytes()}, {"LIST", []byte("odd")}, {"data", data.Bytes()}} {
+		body.WriteString(chunk.id)
+		binary.Write(&body, binary.LittleEndian, uint32(len(chunk.data)))
+		body.Write(chunk.data)
+		if len(chunk.data)%2 == 1 {
+			body.WriteByte(0)
+		}
+	}
+
+	var file bytes.Buffer
+	file.WriteString("RIFF")
+	binary.Write(&file, binary.LittleEndian, uint32(body.Len()))
+	file.Write(body.Bytes())
+	return file.Bytes()
+}
+
+// readAll reads frames from source until it ends
+func readAll(t *testing.T, source AudioSource) []AudioFrame {
+	t.Helper()
+	var frames []AudioFrame
+	for {
+		frame, err := source.ReadFrame(context.Background())
+		if err == io.EOF {
+			return frames
+		}
+		if err != nil {
+			t.Fatalf("ReadFrame: %v", err)
+		}
+		frames = append(frames, frame)
+	}
+}
+
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestWAVSourceReadsHeaderAndFrames(t *testing.T) {
+	file := &closeRecorder{Reader: bytes.NewReader(buildWAV(8000, 2, []int16{0, 16384, -16384, 32767, 8192, -8192}))}
+	source, err := NewWAVSource(file, 2)
+	if err != nil {
+		t.Fatalf("NewWAVSource: %v", err)
+	}
+	if source.SampleRate != 8000 || source.Channels != 2 || source.Format != "pcm_s16le" {
+		t.Fatalf("header = %d Hz, %d channels, %s", source.SampleRate, source.Channels, source.Format)
+	}
+
+	frames := readAll(t, source)
+	if len(frames) != 2 {
+		t.Fatalf("read %d frames, want 2", len(frames))
+	}
+	if got := frames[0].Samples; len(got) != 4 || got[1] != 0.5 || got[2] != -0.5 {
+		t.Errorf("first frame = %v", got)
+	}
+	if len(frames[1].Samples) != 2 {
+		t.Errorf("last frame has %d samples, want the remaining 2", len(frames[1].Samples))
+	}
+	if want := 250 * time.Microsecond; frames[1].Timestamp != want {
+		t.Errorf("second frame at %s, want %s", frames[1].Timestamp, want)
+	}
+
+	source.Close()
+	if !file.closed {
+		t.Error("Close did not close the file")
+	}
+}
+
+func TestWAVSourceRejectsBadFiles(t *testing.T) {
+	valid := buildWAV(8000, 1, []int16{1, 2})
+	unsupported := append([]byte(nil), valid...)
+	binary.LittleEndian.PutUint16(unsupported[34:36], 12) // Bits per sample
+
+	for _, tc := range []struct {
+		name string
+		data []byte
+		code string
+	}{
+		{"not RIFF", []byte("RIFX\x00\x00\x00\x00WAVE"), "INVALID_WAV"},
+		{"truncated", valid[:20], "INVALID_WAV"},
+		{"no data chunk", valid[:36], "INVALID_WAV"},
+		{"12-bit samples", unsupported, "UNSUPPORTED_AUDIO_FORMAT"},
+	} {
+		_, err := NewWAVSource(bytes.NewReader(tc.data), 0)
+		var vErr *VocalsError
+		if !errors.As(err, &vErr) || vErr.Code != tc.code {
+			t.Errorf("%s: err = %v, want %s", tc.name, err, tc.code)
+		}
+	}
+}
+
+func TestPCMReaderSourceDropsPartialFrames(t *testing.T) {
+	config := NewAudioConfig()
+	config.Format = "pcm_s16le"
+	config.SampleRate = 16000
+	config.Channels = 2
+	config.BufferSize = 2
+
+	// Three stereo sample frames and one stray byte
+	data := make([]byte, 3*2*2+1)
+	source, err := NewPCMReaderSource(bytes.NewReader(data), config)
+	if err != nil {
+		t.Fatalf("NewPCMReaderSource: %v", err)
+	}
+	frames := readAll(t, source)
+	if len(frames) != 2 || len(frames[0].Samples) != 4 || len(frames[1].Samples) != 2 {
+		t.Fatalf("frames = %v, want 4 samples then 2", frames)
+	}
+	if frames[1].Timestamp != 125*time.Microsecond || frames[1].Duration() != 62500*time.Nanosecond {
+		t.Errorf("second frame at %s for %s", frames[1].Timestamp, frames[1].Duration())
+	}
+
+	config.Format = "pcm_s12le"
+	if _, err := NewPCMReaderSource(bytes.NewReader(data), config); err == nil {
+		t.Error("NewPCMReaderSource accepted an unknown format")
+	}
+}
+
+func TestGeneratorSourceProducesDuration(t *testing.T) {
+	source, err := NewGeneratorSource(GeneratorConfig{
+		Waveform:   WaveformSine,
+		Frequency:  1000,
+		SampleRate: 8000,
+		Channels:   2,
+		FrameSize:  120,
+		Duration:   25 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("NewGeneratorSource: %v", err)
+	}
+
+	var total time.Duration
+	var peak float32
+	for _, frame := range readAll(t, source) {
+		if frame.Timestamp != total {
+			t.Errorf("frame at %s, want %s", frame.Timestamp, total)
+		}
+		total += frame.Duration()
+		for i := 0; i < len(frame.Samples); i += 2 {
+			if frame.Samples[i] != frame.Samples[i+1] {
+				t.Fatalf("channels differ at sample %d", i)
+			}
+			peak = float32(math.Max(float64(peak), math.Abs(float64(frame.Samples[i]))))
+		}
+	}
+	if total != 25*time.Millisecond {
+		t.Errorf("produced %s, want 25ms", total)
+	}
+	if peak < 0.49 || peak > 0.5 {
+		t.Errorf("peak amplitude %f, want the default 0.5", peak)
+	}
+
+	if _, err := NewGeneratorSource(GeneratorConfig{Waveform: "square"}); err == nil {
+		t.Error("NewGeneratorSource accepted an unknown waveform")
+	}
+}
+
+func TestChannelSourceEndsAndCloses(t *testing.T) {
+	ch := make(chan []float32, 2)
+	source, err := NewChannelSource(ch, 16000, 1)
+	if err != nil {
+		t.Fatalf("NewChannelSource: %v", err)
+	}
+	ch <- make([]float32, 160)
+	ch <- make([]float32, 160)
+	close(ch)
+	frames := readAll(t, source)
+	if len(frames) != 2 || frames[1].Timestamp != 10*time.Millisecond {
+		t.Errorf("frames = %d, second at %s", len(frames), frames[len(frames)-1].Timestamp)
+	}
+
+	// Close unblocks a pending read
+	source, _ = NewChannelSource(make(chan []float32), 16000, 1)
+	read := make(chan error, 1)
+	go func() {
+		_, err := source.ReadFrame(context.Background())
+		read <- err
+	}()
+	source.Close()
+	select {
+	case err := <-read:
+		if err != errAudioSourceClosed {
+			t.Errorf("ReadFrame after Close = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("ReadFrame still blocked after Close")
+	}
+}
+
+func TestChannelSourceRejectsInvalidFormat(t *testing.T) {
+	for _, tc := range []struct {
+		sampleRate, channels int
+		code                 string
+	}{
+		{0, 1, "INVALID_SAMPLE_RATE"},
+		{16000, 0, "INVALID_CHANNELS"},
+		{16000, -2, "INVALID_CHANNELS"},
+	} {
+		_, err := NewChannelSource(make(chan []float32), tc.sampleRate, tc.channels)
+		var vErr *VocalsError
+		if !errors.As(err, &vErr) || vErr.Code != tc.code {
+			t.Errorf("NewChannelSource(%d Hz, %d channels) = %v, want %s", tc.sampleRate, tc.channels, err, tc.code)
+		}
+	}
+}
+
+func TestRecordingFromSourceCompletes(t *testing.T) {
+	source, err := NewGeneratorSource(GeneratorConfig{Waveform: WaveformSilence, SampleRate: 16000, FrameSize: 160, Duration: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("NewGeneratorSource: %v", err)
+	}
+	ap := NewAudioProcessor(NewAudioConfig())
+	frames := make(chan AudioFrame, 10)
+	start := time.Now()
+	if err := ap.StartRecordingFrom(source, func(frame AudioFrame) { frames <- frame }); err != nil {
+		t.Fatalf("StartRecordingFrom: %v", err)
+	}
+	if err := ap.StartRecordingFrom(source, nil); err == nil {
+		t.Error("second recording started while the first runs")
+	}
+
+	<-ap.recordingDone()
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("50ms of audio delivered in %s, want real-time pacing", elapsed)
+	}
+	if len(frames) != 5 {
+		t.Errorf("delivered %d frames, want 5", len(frames))
+	}
+	if state := ap.GetRecordingState(); state != CompletedRecording || ap.IsRecording() {
+		t.Errorf("state %s after the source ended, want completed", state)
+	}
+}
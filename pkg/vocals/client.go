@@ -1,9 +1,7 @@
 package vocals
 
 import (
-	"bytes"
 	"context"
-	"encoding/binary"
 	"fmt"
 	"log"
 	"math"
@@ -12,19 +10,17 @@ import (
 )
 
 type VocalsClient struct {
-	config             *VocalsConfig
-	audioConfig        *AudioConfig
-	modes              []string
-	websocketClient    *WebSocketClient
-	audioProcessor     *AudioProcessor
-	messageHandlers    []MessageHandler
-	connectionHandlers []ConnectionHandler
-	errorHandlers      []ErrorHandler
-	audioDataHandlers  []AudioDataHandler
-	ctx                context.Context
-	cancel             context.CancelFunc
-	mu                 sync.Mutex
-	logger             *VocalsLogger
+	config          *VocalsConfig
+	audioConfig     *AudioConfig
+	modes           []string
+	websocketClient *WebSocketClient
+	audioProcessor  *AudioProcessor
+	replayBuffer    *audioRingBuffer // Recent microphone audio, nil when replay is disabled
+	lastReplayed    time.Duration    // Audio replayed on the last resume
+	ctx             context.Context
+	cancel          context.CancelFunc
+	mu              sync.Mutex
+	logger          *VocalsLogger
 }
 
 func NewVocalsClient(config *VocalsConfig, audioConfig *AudioConfig, userID *string, modes []string) *VocalsClient {
@@ -34,6 +30,12 @@ func NewVocalsClient(config *VocalsConfig, audioConfig *AudioConfig, userID *str
 	if audioConfig == nil {
 		audioConfig = NewAudioConfig()
 	}
+	if audioConfig.DeviceID == nil && config.AudioDeviceID != nil {
+		audioConfig.DeviceID = config.AudioDeviceID
+	}
+	if audioConfig.OutputDeviceID == nil && config.AudioOutputDeviceID != nil {
+		audioConfig.OutputDeviceID = config.AudioOutputDeviceID
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 
@@ -41,18 +43,20 @@ func NewVocalsClient(config *VocalsConfig, audioConfig *AudioConfig, userID *str
 	audioProc := NewAudioProcessor(audioConfig)
 
 	client := &VocalsClient{
-		config:             config,
-		audioConfig:        audioConfig,
-		modes:              modes,
-		websocketClient:    wsClient,
-		audioProcessor:     audioProc,
-		messageHandlers:    []MessageHandler{},
-		connectionHandlers: []ConnectionHandler{},
-		errorHandlers:      []ErrorHandler{},
-		audioDataHandlers:  []AudioDataHandler{},
-		ctx:                ctx,
-		cancel:             cancel,
+		config:          config,
+		audioConfig:     audioConfig,
+		modes:           modes,
+		websocketClient: wsClient,
+		audioProcessor:  audioProc,
+		ctx:             ctx,
+		cancel:          cancel,
+	}
+
+	if config.ResumeAudioReplayMs > 0 {
+		capacity := config.ResumeAudioReplayMs * audioConfig.SampleRate * audioConfig.Channels / 1000
+		client.replayBuffer = newAudioRingBuffer(capacity, audioConfig.SampleRate*audioConfig.Channels)
 	}
+	wsClient.SetSessionSetup(client.sessionSetupMessages)
 
 	client.setupInternalHandlers()
 
@@ -72,40 +76,20 @@ func NewVocalsClient(config *VocalsConfig, audioConfig *AudioConfig, userID *str
 }
 
 func (c *VocalsClient) setupInternalHandlers() {
-	c.websocketClient.AddMessageHandler(func(msg *WebSocketResponse) {
+	c.websocketClient.AddEventHandler(func(event Event) {
 		// Internal processing, e.g., add TTS to queue
-		if msg.Type != nil && *msg.Type == "tts_audio" {
-			if data, ok := msg.Data.(map[string]interface{}); ok {
-				// Validate required fields before processing
-				segmentID := getString(data, "segment_id")
-				audioData := getString(data, "audio_data")
-
-				if segmentID == "" || audioData == "" {
-					log.Printf("Invalid TTS message: missing required fields (segment_id: %s, audio_data: %s)", segmentID, audioData)
-					return
-				}
-
-				segment := TTSAudioSegment{
-					SegmentID:      segmentID,
-					SentenceNumber: getInt(data, "sentence_number"),
-					AudioData:      audioData,
-					SampleRate:     getInt(data, "sample_rate"),
-					Text:           getString(data, "text"),
-					Format:         getString(data, "format"),
-				}
-				c.audioProcessor.AddToQueue(segment)
-			} else {
-				log.Printf("Invalid TTS message format: expected map[string]interface{}, got %T", msg.Data)
+		if tts, ok := event.(TTSAudioEvent); ok {
+			if err := tts.Validate(); err != nil {
+				log.Printf("Invalid TTS message: %v", err)
+				return
 			}
+			c.audioProcessor.AddToQueue(tts.Segment())
 		}
 		// Handle other types internally
 	})
 
-	c.audioProcessor.AddErrorHandler(func(err *VocalsError) {
-		for _, h := range c.errorHandlers {
-			go h(err)
-		}
-	})
+	// Audio errors reach the same error handlers as WebSocket errors
+	c.audioProcessor.AddErrorHandler(c.websocketClient.emitError)
 }
 
 func (c *VocalsClient) setupDefaultHandlers() {
@@ -114,7 +98,13 @@ func (c *VocalsClient) setupDefaultHandlers() {
 }
 
 func (c *VocalsClient) Connect() error {
-	return c.websocketClient.Connect()
+	return c.ConnectContext(context.Background())
+}
+
+// ConnectContext connects to the server, giving up with ctx.Err() when ctx
+// is cancelled or its deadline passes
+func (c *VocalsClient) ConnectContext(ctx context.Context) error {
+	return c.websocketClient.ConnectContext(ctx)
 }
 
 func (c *VocalsClient) Disconnect() {
@@ -123,6 +113,11 @@ func (c *VocalsClient) Disconnect() {
 
 // EnsureConnected ensures the WebSocket connection is established before proceeding
 func (c *VocalsClient) EnsureConnected() error {
+	return c.EnsureConnectedContext(context.Background())
+}
+
+// EnsureConnectedContext is EnsureConnected bounded by ctx
+func (c *VocalsClient) EnsureConnectedContext(ctx context.Context) error {
 	if c.websocketClient.IsConnected() {
 		log.Printf("Already connected (state: %v)", c.websocketClient.GetState())
 		return nil
@@ -131,7 +126,10 @@ func (c *VocalsClient) EnsureConnected() error {
 	log.Printf("Connection state: %v - attempting to connect...", c.websocketClient.GetState())
 	
 	// Try to connect
-	if err := c.Connect(); err != nil {
+	if err := c.ConnectContext(ctx); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
 		return fmt.Errorf("connection failed: %v", err)
 	}
 	
@@ -144,17 +142,13 @@ func (c *VocalsClient) EnsureConnected() error {
 	
 	for {
 		select {
+		case <-ctx.Done():
+			return ctx.Err()
 		case <-timeout.C:
 			return fmt.Errorf("connection timeout after 10 seconds (state: %v)", c.websocketClient.GetState())
 		case <-ticker.C:
 			if c.websocketClient.IsConnected() {
 				log.Printf("Successfully connected (state: %v)", c.websocketClient.GetState())
-				
-				// Send settings event after connection is established
-				if err := c.sendSettingsEvent(); err != nil {
-					log.Printf("Failed to send settings event: %v", err)
-				}
-				
 				return nil
 			}
 			state := c.websocketClient.GetState()
@@ -166,55 +160,150 @@ func (c *VocalsClient) EnsureConnected() error {
 	}
 }
 
-// sendSettingsEvent sends the audio settings to the server
-func (c *VocalsClient) sendSettingsEvent() error {
-	settingsMsg := &WebSocketMessage{
+// createSettingsMessage builds the settings event with the audio format and modes
+func (c *VocalsClient) createSettingsMessage() *WebSocketMessage {
+	data := map[string]interface{}{
+		"format":     c.audioConfig.Format,
+		"sampleRate": c.audioConfig.SampleRate,
+		"channels":   c.audioConfig.Channels,
+	}
+	if len(c.modes) > 0 {
+		data["modes"] = c.modes
+	}
+
+	log.Printf("Sending settings event: format=%s, sampleRate=%d, channels=%d, modes=%v", 
+		c.audioConfig.Format, c.audioConfig.SampleRate, c.audioConfig.Channels, c.modes)
+
+	return &WebSocketMessage{
 		Event: "settings",
-		Data: map[string]interface{}{
-			"format":     c.audioConfig.Format,
-			"sampleRate": c.audioConfig.SampleRate,
-			"channels":   c.audioConfig.Channels,
-		},
+		Data:  data,
 	}
-	
-	log.Printf("Sending settings event: format=%s, sampleRate=%d, channels=%d", 
-		c.audioConfig.Format, c.audioConfig.SampleRate, c.audioConfig.Channels)
-	
-	return c.websocketClient.SendMessage(settingsMsg)
 }
 
-func (c *VocalsClient) StartRecording() error {
-	return c.audioProcessor.StartRecording(func(data []float32) {
-		// Check if we're connected before trying to send data
-		if !c.websocketClient.IsConnected() {
-			if c.config.DebugWebsocket {
-				log.Printf("Skipping audio data - not connected (state: %v)", c.websocketClient.GetState())
+// sessionSetupMessages is sent after the start event on every connection.
+// After a dropped connection it also replays buffered microphone audio so
+// speech captured during the outage reaches the server.
+func (c *VocalsClient) sessionSetupMessages(resumed bool) []*WebSocketMessage {
+	messages := []*WebSocketMessage{c.createSettingsMessage()}
+
+	c.mu.Lock()
+	c.lastReplayed = 0
+	c.mu.Unlock()
+
+	if !resumed || c.replayBuffer == nil || !c.audioProcessor.IsRecording() {
+		return messages
+	}
+
+	// Replayed audio keeps the time it was captured, not the time it is resent
+	rate := c.audioConfig.SampleRate * c.audioConfig.Channels
+	chunkSize := c.audioConfig.BufferSize * c.audioConfig.Channels
+	total := 0
+	for _, block := range c.replayBuffer.Snapshot() {
+		for i := 0; i < len(block.samples); i += chunkSize {
+			end := i + chunkSize
+			if end > len(block.samples) {
+				end = len(block.samples)
 			}
-			return
+			msg := CreateAudioMessage(block.samples[i:end], c.audioConfig.SampleRate, c.audioConfig.Format)
+			msg.CapturedAt = block.after(i, rate)
+			messages = append(messages, msg)
 		}
+		total += len(block.samples)
+	}
 
-		// Convert PCM float32 to raw bytes
-		buf := new(bytes.Buffer)
-		for _, sample := range data {
-			bits := math.Float32bits(sample)
-			binary.Write(buf, binary.LittleEndian, bits)
-		}
-		
-		// Send as JSON with raw bytes - Go's JSON marshaler will auto-base64 it
-		format := c.audioConfig.Format
-		sampleRate := c.audioConfig.SampleRate
-		msg := &WebSocketMessage{
-			Event:      "media",
-			Data:       buf.Bytes(),  // Raw []byte - JSON marshaler will auto-base64 it
-			Format:     &format,
-			SampleRate: &sampleRate,
-		}
-		err := c.websocketClient.SendMessage(msg)
-		if err != nil {
-			log.Printf("Error sending audio data: %v", err)
-			return
+	replayed := time.Duration(float64(total) / float64(rate) * float64(time.Second))
+	c.mu.Lock()
+	c.lastReplayed = replayed
+	c.mu.Unlock()
+	log.Printf("Replaying %s of buffered audio after reconnect", replayed)
+
+	return messages
+}
+func (c *VocalsClient) StartRecording() error {
+	if c.replayBuffer != nil {
+		c.replayBuffer.Reset()
+	}
+
+	return c.audioProcessor.startMicrophone(c.captureAudio)
+}
+
+// StartRecordingFrom streams source instead of the microphone until it
+// ends or StopRecording is called
+func (c *VocalsClient) StartRecordingFrom(source AudioSource) error {
+	if c.replayBuffer != nil {
+		c.replayBuffer.Reset()
+	}
+
+	// Sources at another rate or channel count are converted to the wire format
+	resampled, err := NewResampledSource(source, c.audioConfig.SampleRate, c.audioConfig.Channels, c.audioConfig.ResampleQuality)
+	if err != nil {
+		return err
+	}
+	return c.audioProcessor.StartRecordingFrom(resampled, c.captureAudio)
+}
+
+// StreamSource connects if needed and streams source until it ends or ctx
+// is done. Recording is stopped either way; on cancellation ctx.Err() is
+// returned.
+func (c *VocalsClient) StreamSource(ctx context.Context, source AudioSource) error {
+	if err := c.EnsureConnectedContext(ctx); err != nil {
+		source.Close()
+		if ctx.Err() != nil {
+			return ctx.Err()
 		}
-	})
+		return fmt.Errorf("failed to establish connection: %v", err)
+	}
+
+	if err := c.StartRecordingFrom(source); err != nil {
+		source.Close()
+		return err
+	}
+	done := c.audioProcessor.recordingDone()
+	if done == nil {
+		// The source ended before we could wait for it
+		return nil
+	}
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		if err := c.StopRecording(); err != nil {
+			log.Printf("Error stopping recording: %v", err)
+		}
+		return ctx.Err()
+	}
+}
+
+// captureAudio sends a captured frame to the server
+func (c *VocalsClient) captureAudio(frame AudioFrame) {
+	// Keep recent audio for replay after a reconnect
+	if c.replayBuffer != nil {
+		c.replayBuffer.Write(frame.Samples, frame.CapturedAt)
+	}
+
+	// Check if we're connected before trying to send data
+	if !c.websocketClient.IsConnected() {
+		if c.config.DebugWebsocket {
+			log.Printf("Skipping audio data - not connected (state: %v)", c.websocketClient.GetState())
+		}
+		return
+	}
+
+	msg := CreateAudioMessage(frame.Samples, frame.SampleRate, c.audioConfig.Format)
+	msg.CapturedAt = frame.CapturedAt
+	if err := c.websocketClient.SendMessage(msg); err != nil {
+		log.Printf("Error sending audio data: %v", err)
+	}
+}
+
+// sendAudio sends PCM samples captured at capturedAt as a media event. The
+// WebSocket client writes it as a binary frame when binary media was
+// negotiated and as base64 inside JSON otherwise.
+func (c *VocalsClient) sendAudio(samples []float32, capturedAt time.Time) error {
+	msg := CreateAudioMessage(samples, c.audioConfig.SampleRate, c.audioConfig.Format)
+	msg.CapturedAt = capturedAt
+	return c.websocketClient.SendMessage(msg)
 }
 
 func (c *VocalsClient) StopRecording() error {
@@ -222,15 +311,30 @@ func (c *VocalsClient) StopRecording() error {
 }
 
 func (c *VocalsClient) StreamMicrophone(duration float64) error {
+	return c.StreamMicrophoneContext(context.Background(), duration)
+}
+
+// StreamMicrophoneContext streams the microphone for duration seconds or
+// until ctx is done. Recording is stopped either way; on cancellation
+// ctx.Err() is returned.
+func (c *VocalsClient) StreamMicrophoneContext(ctx context.Context, duration float64) error {
 	// Ensure we're connected before starting
-	if err := c.EnsureConnected(); err != nil {
+	if err := c.EnsureConnectedContext(ctx); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
 		return fmt.Errorf("failed to establish connection: %v", err)
 	}
 	
 	if err := c.StartRecording(); err != nil {
 		return err
 	}
-	time.Sleep(time.Duration(duration * float64(time.Second)))
+	if err := sleepContext(ctx, secondsToDuration(duration)); err != nil {
+		if stopErr := c.StopRecording(); stopErr != nil {
+			log.Printf("Error stopping recording: %v", stopErr)
+		}
+		return err
+	}
 	return c.StopRecording()
 }
 
@@ -241,6 +345,20 @@ func (c *VocalsClient) StreamMicrophoneWithStats(
 	audioLevelCallback AudioLevelCallback,
 	silenceThreshold float32,
 	silenceDetectionCallback SilenceDetectionCallback,
+) (*StreamStats, error) {
+	return c.StreamMicrophoneWithStatsContext(context.Background(), duration, statsCallback, audioLevelCallback, silenceThreshold, silenceDetectionCallback)
+}
+
+// StreamMicrophoneWithStatsContext is StreamMicrophoneWithStats bounded by
+// ctx. On cancellation the statistics gathered so far are returned with
+// ctx.Err().
+func (c *VocalsClient) StreamMicrophoneWithStatsContext(
+	ctx context.Context,
+	duration float64,
+	statsCallback StreamStatsCallback,
+	audioLevelCallback AudioLevelCallback,
+	silenceThreshold float32,
+	silenceDetectionCallback SilenceDetectionCallback,
 ) (*StreamStats, error) {
 	// Initialize statistics
 	stats := &StreamStats{
@@ -346,10 +464,10 @@ func (c *VocalsClient) StreamMicrophoneWithStats(
 	}
 
 	// Monitor for the specified duration
-	time.Sleep(time.Duration(duration * float64(time.Second)))
+	ctxErr := sleepContext(ctx, secondsToDuration(duration))
 
 	// Stop recording
-	if err := c.StopRecording(); err != nil {
+	if err := c.StopRecording(); err != nil && ctxErr == nil {
 		return stats, err
 	}
 
@@ -370,13 +488,21 @@ func (c *VocalsClient) StreamMicrophoneWithStats(
 	}
 	mu.Unlock()
 
-	return stats, nil
+	return stats, ctxErr
 }
 
 // StreamMicrophoneWithBasicStats provides enhanced streaming with basic statistics and logging
 func (c *VocalsClient) StreamMicrophoneWithBasicStats(duration float64, silenceThreshold float32, verbose bool) (*StreamStats, error) {
+	return c.StreamMicrophoneWithBasicStatsContext(context.Background(), duration, silenceThreshold, verbose)
+}
+
+// StreamMicrophoneWithBasicStatsContext is StreamMicrophoneWithBasicStats bounded by ctx
+func (c *VocalsClient) StreamMicrophoneWithBasicStatsContext(ctx context.Context, duration float64, silenceThreshold float32, verbose bool) (*StreamStats, error) {
 	// Ensure we're connected before starting
-	if err := c.EnsureConnected(); err != nil {
+	if err := c.EnsureConnectedContext(ctx); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
 		return nil, fmt.Errorf("failed to establish connection: %v", err)
 	}
 	
@@ -399,10 +525,16 @@ func (c *VocalsClient) StreamMicrophoneWithBasicStats(duration float64, silenceT
 		}
 	}
 
-	return c.StreamMicrophoneWithStats(duration, statsCallback, audioLevelCallback, silenceThreshold, silenceDetectionCallback)
+	return c.StreamMicrophoneWithStatsContext(ctx, duration, statsCallback, audioLevelCallback, silenceThreshold, silenceDetectionCallback)
 }
 
 func (c *VocalsClient) StreamAudioFile(filePath string) error {
+	return c.StreamAudioFileContext(context.Background(), filePath)
+}
+
+// StreamAudioFileContext streams a file in real time, stopping with
+// ctx.Err() when ctx is done
+func (c *VocalsClient) StreamAudioFileContext(ctx context.Context, filePath string) error {
 	samples := LoadAudioFile(filePath)
 	if samples == nil {
 		return fmt.Errorf("failed to load audio file")
@@ -421,70 +553,113 @@ func (c *VocalsClient) StreamAudioFile(filePath string) error {
 		}
 		chunk := samples[i:end]
 
-		// Convert chunk to raw bytes
-		buf := new(bytes.Buffer)
-		for _, sample := range chunk {
-			bits := math.Float32bits(sample)
-			binary.Write(buf, binary.LittleEndian, bits)
-		}
-		
-		// Send as JSON with raw bytes - Go's JSON marshaler will auto-base64 it
-		format := c.audioConfig.Format
-		sampleRate := c.audioConfig.SampleRate
-		msg := &WebSocketMessage{
-			Event:      "media",
-			Data:       buf.Bytes(),  // Raw []byte - JSON marshaler will auto-base64 it
-			Format:     &format,
-			SampleRate: &sampleRate,
-		}
-		err := c.websocketClient.SendMessage(msg)
-		if err != nil {
+		if err := c.sendAudio(chunk, time.Now()); err != nil {
 			return fmt.Errorf("failed to send audio data: %v", err)
 		}
 
 		// Sleep to simulate real-time playback
 		sleepDuration := time.Duration(float64(len(chunk))/float64(c.audioConfig.SampleRate)*1000) * time.Millisecond
-		time.Sleep(sleepDuration)
+		if err := sleepContext(ctx, sleepDuration); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
 func (c *VocalsClient) AddMessageHandler(handler MessageHandler) func() {
-	c.mu.Lock()
-	c.messageHandlers = append(c.messageHandlers, handler)
-	c.mu.Unlock()
 	return c.websocketClient.AddMessageHandler(handler)
 }
 
+// AddEventHandler registers a handler that receives every server message as
+// a typed Event such as TranscriptionEvent or TTSAudioEvent. Messages without
+// a registered decoder arrive as RawEvent.
+func (c *VocalsClient) AddEventHandler(handler EventHandler) func() {
+	return c.websocketClient.AddEventHandler(handler)
+}
+
 func (c *VocalsClient) AddConnectionHandler(handler ConnectionHandler) func() {
-	c.mu.Lock()
-	c.connectionHandlers = append(c.connectionHandlers, handler)
-	c.mu.Unlock()
 	return c.websocketClient.AddConnectionHandler(handler)
 }
 
 func (c *VocalsClient) AddErrorHandler(handler ErrorHandler) func() {
-	c.mu.Lock()
-	c.errorHandlers = append(c.errorHandlers, handler)
-	c.mu.Unlock()
 	return c.websocketClient.AddErrorHandler(handler)
 }
 
+// AddMiddleware appends m to the chain that sees every outbound message
+// before it is written and every server message before it reaches handlers.
+// It returns a function that removes the middleware.
+func (c *VocalsClient) AddMiddleware(m Middleware) func() {
+	return c.websocketClient.AddMiddleware(m)
+}
+
+// AddReconnectHandler registers a handler called after every failed connection
+// attempt with the attempt number and the delay before the next one
+func (c *VocalsClient) AddReconnectHandler(handler ReconnectHandler) func() {
+	return c.websocketClient.AddReconnectHandler(handler)
+}
+
+// AddResumeHandler registers a handler called after every automatic
+// reconnect, reporting whether the session was restored
+func (c *VocalsClient) AddResumeHandler(handler ResumeHandler) func() {
+	return c.websocketClient.AddResumeHandler(func(event ResumeEvent) {
+		if event.Success {
+			c.mu.Lock()
+			event.ReplayedAudio = c.lastReplayed
+			c.mu.Unlock()
+		}
+		handler(event)
+	})
+}
+
+// AddTokenRefreshHandler registers a handler called after every proactive
+// token refresh of the open connection, successful or not
+func (c *VocalsClient) AddTokenRefreshHandler(handler TokenRefreshHandler) func() {
+	return c.websocketClient.AddTokenRefreshHandler(handler)
+}
+
 func (c *VocalsClient) AddAudioDataHandler(handler AudioDataHandler) func() {
-	c.mu.Lock()
-	c.audioDataHandlers = append(c.audioDataHandlers, handler)
-	c.mu.Unlock()
 	return c.audioProcessor.AddAudioDataHandler(handler)
 }
 
+// Events returns a channel of typed server events for use in select loops.
+// The channel is closed once ctx is done. A reader that falls behind by more
+// than the channel buffer and HandlerQueueSize loses events according to
+// HandlerOverflowPolicy; with OverflowBlock it stalls every handler and the
+// connection's read loop instead.
+func (c *VocalsClient) Events(ctx context.Context) <-chan Event {
+	return eventChannel(ctx, c)
+}
+
 func (c *VocalsClient) ConnectionState() ConnectionState {
 	return c.websocketClient.GetState()
 }
 
+// GetOutboundStats returns outbound queue depths and drop counters
+func (c *VocalsClient) GetOutboundStats() OutboundStats {
+	return c.websocketClient.GetOutboundStats()
+}
+
+// GetHandlerStats returns the queue depth and drop counter of the handler
+// queues
+func (c *VocalsClient) GetHandlerStats() HandlerStats {
+	return c.websocketClient.GetHandlerStats()
+}
+
+// GetConnectionMetrics returns keepalive ping round trip times and counters
+func (c *VocalsClient) GetConnectionMetrics() ConnectionMetrics {
+	return c.websocketClient.GetConnectionMetrics()
+}
+
+// MediaMode returns the media mode negotiated for the current connection
+func (c *VocalsClient) MediaMode() string {
+	return c.websocketClient.MediaMode()
+}
+
 func (c *VocalsClient) Cleanup() {
 	c.cancel()
 	c.audioProcessor.Cleanup()
 	c.websocketClient.Disconnect()
+	c.websocketClient.closeRecorder()
 	log.Println("Vocals client cleaned up")
 }
 
@@ -492,6 +667,12 @@ func (c *VocalsClient) SendMessage(msg *WebSocketMessage) error {
 	return c.websocketClient.SendMessage(msg)
 }
 
+// Request sends msg and waits for the server reply carrying its ID, bounded
+// by ctx or RequestTimeout
+func (c *VocalsClient) Request(ctx context.Context, msg *WebSocketMessage) (*WebSocketResponse, error) {
+	return c.websocketClient.Request(ctx, msg)
+}
+
 func (c *VocalsClient) GetRecordingState() RecordingState {
 	return c.audioProcessor.GetRecordingState()
 }
@@ -524,6 +705,17 @@ func (c *VocalsClient) StopPlayback() error {
 	return c.audioProcessor.StopPlayback()
 }
 
+// SetInputDevice selects the microphone. A recording in progress moves to
+// the new device while the session stays connected.
+func (c *VocalsClient) SetInputDevice(deviceID int) error {
+	return c.audioProcessor.SetDeviceID(deviceID)
+}
+
+// SetOutputDevice selects the device TTS audio is played on
+func (c *VocalsClient) SetOutputDevice(deviceID int) error {
+	return c.audioProcessor.SetOutputDeviceID(deviceID)
+}
+
 // Helper functions for safe type assertions with error logging
 func getString(data map[string]interface{}, key string) string {
 	if val, ok := data[key]; ok {
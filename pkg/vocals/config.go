@@ -2,37 +2,72 @@ package vocals
 
 import (
 	"fmt"
+	"net/http"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type VocalsConfig struct {
-	TokenEndpoint        *string           `json:"token_endpoint,omitempty"`
-	Headers              map[string]string `json:"headers,omitempty"`
-	AutoConnect          bool              `json:"auto_connect"`
-	MaxReconnectAttempts int               `json:"max_reconnect_attempts"`
-	ReconnectDelay       float64           `json:"reconnect_delay"`
-	TokenRefreshBuffer   float64           `json:"token_refresh_buffer"`
-	WsEndpoint           *string           `json:"ws_endpoint,omitempty"`
-	UseTokenAuth         bool              `json:"use_token_auth"`
-	DebugLevel           string            `json:"debug_level"`
-	DebugWebsocket       bool              `json:"debug_websocket"`
-	DebugAudio           bool              `json:"debug_audio"`
-	AudioDeviceID        *int              `json:"audio_device_id,omitempty"`
+	TokenEndpoint          *string           `json:"token_endpoint,omitempty"`
+	TokenSource            TokenSource       `json:"-"` // Overrides TokenEndpoint and the API key when set
+	HTTPClient             *http.Client      `json:"-"` // Used for TokenEndpoint requests; a shared client when nil
+	APIKey                 string            `json:"-"` // Signs tokens locally; VOCALS_DEV_API_KEY is used when empty
+	Headers                map[string]string `json:"headers,omitempty"`
+	AutoConnect            bool              `json:"auto_connect"`
+	MaxReconnectAttempts   int               `json:"max_reconnect_attempts"`
+	ReconnectDelay         float64           `json:"reconnect_delay"`
+	MaxReconnectElapsed    float64           `json:"max_reconnect_elapsed"` // Seconds, 0 for no limit
+	ReconnectPolicy        ReconnectPolicy   `json:"-"`                     // Defaults to a constant ReconnectDelay
+	TokenRefreshBuffer     float64           `json:"token_refresh_buffer"`
+	TokenRefreshMode       string            `json:"token_refresh_mode"` // TokenRefreshReconnect, TokenRefreshReauth or TokenRefreshOff
+	PingInterval           float64           `json:"ping_interval"`      // Seconds between keepalive pings, 0 to disable
+	PongTimeout            float64           `json:"pong_timeout"`       // Seconds to wait for a pong before reconnecting
+	WriteTimeout           float64           `json:"write_timeout"`      // Seconds allowed for each frame write, 0 for no limit
+	HandshakeTimeout       float64           `json:"handshake_timeout"`  // Seconds allowed to dial and upgrade, 0 for no limit
+	WsEndpoint             *string           `json:"ws_endpoint,omitempty"`
+	UseTokenAuth           bool              `json:"use_token_auth"`
+	DebugLevel             string            `json:"debug_level"`
+	DebugWebsocket         bool              `json:"debug_websocket"`
+	DebugAudio             bool              `json:"debug_audio"`
+	AudioDeviceID          *int              `json:"audio_device_id,omitempty"`
+	AudioOutputDeviceID    *int              `json:"audio_output_device_id,omitempty"`
+	MediaMode              string            `json:"media_mode"`               // MediaModeJSON or MediaModeBinary
+	ResumeAudioReplayMs    int               `json:"resume_audio_replay_ms"`   // Microphone audio re-sent after a reconnect, 0 to disable
+	OutboundQueueSize      int               `json:"outbound_queue_size"`      // Frames buffered per outbound queue
+	OutboundOverflowPolicy string            `json:"outbound_overflow_policy"` // OverflowBlock, OverflowDropOldest or OverflowDropNewest
+	DispatchMode           string            `json:"dispatch_mode"`            // DispatchOrdered or DispatchConcurrent
+	HandlerQueueSize       int               `json:"handler_queue_size"`       // Messages buffered per handler in ordered mode
+	HandlerOverflowPolicy  string            `json:"handler_overflow_policy"`  // What a full handler queue does: OverflowBlock, OverflowDropOldest or OverflowDropNewest
+	RecordPath             string            `json:"record_path,omitempty"`    // Capture every frame to this JSONL file (.gz for gzip)
+	RequestTimeout         float64           `json:"request_timeout"`          // Seconds Request waits for a reply when ctx has no deadline, 0 for no limit
+	Transport              Transport         `json:"-"`                        // Defaults to gorilla/websocket when nil
 }
 
 func NewVocalsConfig() *VocalsConfig {
 	c := &VocalsConfig{
-		AutoConnect:          false,
-		MaxReconnectAttempts: 3,
-		ReconnectDelay:       1.0,
-		TokenRefreshBuffer:   60.0,
-		UseTokenAuth:         true,  // Use direct API key token generation, not token endpoint
-		DebugLevel:           "INFO",
-		Headers:              make(map[string]string),
+		AutoConnect:            false,
+		MaxReconnectAttempts:   3,
+		ReconnectDelay:         1.0,
+		TokenRefreshBuffer:     60.0,
+		TokenRefreshMode:       TokenRefreshReconnect,
+		PingInterval:           15.0,
+		PongTimeout:            10.0,
+		WriteTimeout:           10.0,
+		HandshakeTimeout:       10.0,
+		RequestTimeout:         30.0,
+		UseTokenAuth:           true, // Use direct API key token generation, not token endpoint
+		DebugLevel:             "INFO",
+		MediaMode:              MediaModeJSON,
+		OutboundQueueSize:      256,
+		OutboundOverflowPolicy: OverflowDropOldest,
+		DispatchMode:           DispatchOrdered,
+		HandlerQueueSize:       256,
+		HandlerOverflowPolicy:  OverflowDropOldest,
+		Headers:                make(map[string]string),
 	}
 
 	// Load from env
@@ -41,10 +76,15 @@ func NewVocalsConfig() *VocalsConfig {
 	return c
 }
 
-func (c *VocalsConfig) loadFromEnv() {
-	// Load .env if exists
-	_ = godotenv.Load()
+// LoadDotEnv loads environment variables from the given files, or from .env
+// in the working directory when none are given. Variables that are already
+// set are kept. The SDK never reads .env files on its own; call this before
+// NewVocalsConfig to opt in.
+func LoadDotEnv(filenames ...string) error {
+	return godotenv.Load(filenames...)
+}
 
+func (c *VocalsConfig) loadFromEnv() {
 	if endpoint := os.Getenv("VOCALS_TOKEN_ENDPOINT"); endpoint != "" {
 		c.TokenEndpoint = &endpoint
 	}
@@ -72,12 +112,46 @@ func (c *VocalsConfig) loadFromEnv() {
 		}
 	}
 	
+	if elapsed := os.Getenv("VOCALS_MAX_RECONNECT_ELAPSED"); elapsed != "" {
+		if val, err := strconv.ParseFloat(elapsed, 64); err == nil {
+			c.MaxReconnectElapsed = val
+		}
+	}
+
 	if buffer := os.Getenv("VOCALS_TOKEN_REFRESH_BUFFER"); buffer != "" {
 		if val, err := strconv.ParseFloat(buffer, 64); err == nil {
 			c.TokenRefreshBuffer = val
 		}
 	}
+
+	if refreshMode := os.Getenv("VOCALS_TOKEN_REFRESH_MODE"); refreshMode != "" {
+		c.TokenRefreshMode = refreshMode
+	}
 	
+	if interval := os.Getenv("VOCALS_PING_INTERVAL"); interval != "" {
+		if val, err := strconv.ParseFloat(interval, 64); err == nil {
+			c.PingInterval = val
+		}
+	}
+
+	if timeout := os.Getenv("VOCALS_PONG_TIMEOUT"); timeout != "" {
+		if val, err := strconv.ParseFloat(timeout, 64); err == nil {
+			c.PongTimeout = val
+		}
+	}
+
+	if timeout := os.Getenv("VOCALS_WRITE_TIMEOUT"); timeout != "" {
+		if val, err := strconv.ParseFloat(timeout, 64); err == nil {
+			c.WriteTimeout = val
+		}
+	}
+
+	if timeout := os.Getenv("VOCALS_HANDSHAKE_TIMEOUT"); timeout != "" {
+		if val, err := strconv.ParseFloat(timeout, 64); err == nil {
+			c.HandshakeTimeout = val
+		}
+	}
+
 	c.UseTokenAuth = os.Getenv("VOCALS_USE_TOKEN_AUTH") != "false"
 	
 	if level := os.Getenv("VOCALS_DEBUG_LEVEL"); level != "" {
@@ -87,23 +161,83 @@ func (c *VocalsConfig) loadFromEnv() {
 	c.DebugWebsocket = os.Getenv("VOCALS_DEBUG_WEBSOCKET") == "true"
 	c.DebugAudio = os.Getenv("VOCALS_DEBUG_AUDIO") == "true"
 
+	if replayMs := os.Getenv("VOCALS_RESUME_AUDIO_REPLAY_MS"); replayMs != "" {
+		if val, err := strconv.Atoi(replayMs); err == nil {
+			c.ResumeAudioReplayMs = val
+		}
+	}
+
+	if queueSize := os.Getenv("VOCALS_OUTBOUND_QUEUE_SIZE"); queueSize != "" {
+		if val, err := strconv.Atoi(queueSize); err == nil {
+			c.OutboundQueueSize = val
+		}
+	}
+
+	if policy := os.Getenv("VOCALS_OUTBOUND_OVERFLOW_POLICY"); policy != "" {
+		c.OutboundOverflowPolicy = policy
+	}
+
+	if dispatchMode := os.Getenv("VOCALS_DISPATCH_MODE"); dispatchMode != "" {
+		c.DispatchMode = dispatchMode
+	}
+
+	if queueSize := os.Getenv("VOCALS_HANDLER_QUEUE_SIZE"); queueSize != "" {
+		if val, err := strconv.Atoi(queueSize); err == nil {
+			c.HandlerQueueSize = val
+		}
+	}
+
+	if policy := os.Getenv("VOCALS_HANDLER_OVERFLOW_POLICY"); policy != "" {
+		c.HandlerOverflowPolicy = policy
+	}
+
+	if requestTimeout := os.Getenv("VOCALS_REQUEST_TIMEOUT"); requestTimeout != "" {
+		if val, err := strconv.ParseFloat(requestTimeout, 64); err == nil {
+			c.RequestTimeout = val
+		}
+	}
+
+	if recordPath := os.Getenv("VOCALS_RECORD_PATH"); recordPath != "" {
+		c.RecordPath = recordPath
+	}
+
+	if mediaMode := os.Getenv("VOCALS_MEDIA_MODE"); mediaMode != "" {
+		c.MediaMode = mediaMode
+	}
+
 	if deviceIDStr := os.Getenv("VOCALS_AUDIO_DEVICE_ID"); deviceIDStr != "" {
 		if deviceID, err := strconv.Atoi(deviceIDStr); err == nil {
 			c.AudioDeviceID = &deviceID
 		}
 	}
+
+	if deviceIDStr := os.Getenv("VOCALS_AUDIO_OUTPUT_DEVICE_ID"); deviceIDStr != "" {
+		if deviceID, err := strconv.Atoi(deviceIDStr); err == nil {
+			c.AudioOutputDeviceID = &deviceID
+		}
+	}
+}
+
+// resolvedAPIKey returns APIKey, falling back to VOCALS_DEV_API_KEY
+func (c *VocalsConfig) resolvedAPIKey() string {
+	if c.APIKey != "" {
+		return c.APIKey
+	}
+	return os.Getenv("VOCALS_DEV_API_KEY")
 }
 
 // Validate returns list of issues
 func (c *VocalsConfig) Validate() []string {
 	issues := []string{}
 
-	// Check API key
-	apiKey := os.Getenv("VOCALS_DEV_API_KEY")
-	if apiKey == "" {
-		issues = append(issues, "VOCALS_DEV_API_KEY environment variable not set")
-	} else if !strings.HasPrefix(apiKey, "vdev_") {
-		issues = append(issues, "Invalid API key format (should start with 'vdev_')")
+	// Check API key, which is only needed when tokens are signed locally
+	if c.UseTokenAuth && c.TokenSource == nil && c.TokenEndpoint == nil {
+		apiKey := c.resolvedAPIKey()
+		if apiKey == "" {
+			issues = append(issues, "API key not set (APIKey or VOCALS_DEV_API_KEY)")
+		} else if !strings.HasPrefix(apiKey, "vdev_") {
+			issues = append(issues, "Invalid API key format (should start with 'vdev_')")
+		}
 	}
 
 	// Check WebSocket endpoint
@@ -124,10 +258,94 @@ func (c *VocalsConfig) Validate() []string {
 		issues = append(issues, fmt.Sprintf("Invalid debug level: %s", c.DebugLevel))
 	}
 
-	// Check audio device (simplified, as Go has different audio handling)
-	if c.AudioDeviceID != nil {
-		// Audio device validation would require checking available devices
-		// For now, we accept any device ID
+	for _, issue := range c.fieldIssues() {
+		issues = append(issues, issue.message)
+	}
+
+	if c.PingInterval > 0 && c.PongTimeout == 0 {
+		issues = append(issues, "Pong timeout is 0, dead connections will not be detected")
+	}
+
+	return issues
+}
+
+// configIssue is an invalid VocalsConfig setting
+type configIssue struct {
+	message string
+	code    string
+}
+
+// fieldIssues checks the connection settings. Validate reports every issue
+// and ValidateVocalsConfig fails with the first.
+func (c *VocalsConfig) fieldIssues() []configIssue {
+	var issues []configIssue
+	add := func(message, code string) {
+		issues = append(issues, configIssue{message: message, code: code})
+	}
+
+	// Check reconnects
+	if c.MaxReconnectAttempts < 0 {
+		add("Invalid max reconnect attempts", "INVALID_RECONNECT_ATTEMPTS")
+	}
+	if c.ReconnectDelay < 0 {
+		add("Invalid reconnect delay", "INVALID_RECONNECT_DELAY")
+	}
+	if c.MaxReconnectElapsed < 0 {
+		add("Invalid max reconnect elapsed time", "INVALID_RECONNECT_ELAPSED")
+	}
+	if c.ResumeAudioReplayMs < 0 {
+		add("Invalid resume audio replay duration", "INVALID_RESUME_AUDIO_REPLAY")
+	}
+
+	// Check media mode
+	if c.MediaMode != "" && c.MediaMode != MediaModeJSON && c.MediaMode != MediaModeBinary {
+		add(fmt.Sprintf("Invalid media mode: %s", c.MediaMode), "INVALID_MEDIA_MODE")
+	}
+
+	// Check outbound queue
+	switch c.OutboundOverflowPolicy {
+	case "", OverflowBlock, OverflowDropOldest, OverflowDropNewest:
+	default:
+		add(fmt.Sprintf("Invalid outbound overflow policy: %s", c.OutboundOverflowPolicy), "INVALID_OVERFLOW_POLICY")
+	}
+	if c.OutboundQueueSize < 0 {
+		add("Outbound queue size must not be negative", "INVALID_OUTBOUND_QUEUE_SIZE")
+	}
+
+	// Check handler dispatch
+	if c.DispatchMode != "" && c.DispatchMode != DispatchOrdered && c.DispatchMode != DispatchConcurrent {
+		add(fmt.Sprintf("Invalid dispatch mode: %s", c.DispatchMode), "INVALID_DISPATCH_MODE")
+	}
+	if c.HandlerQueueSize < 0 {
+		add("Handler queue size must not be negative", "INVALID_HANDLER_QUEUE_SIZE")
+	}
+	switch c.HandlerOverflowPolicy {
+	case "", OverflowBlock, OverflowDropOldest, OverflowDropNewest:
+	default:
+		add(fmt.Sprintf("Invalid handler overflow policy: %s", c.HandlerOverflowPolicy), "INVALID_OVERFLOW_POLICY")
+	}
+
+	// Check tokens
+	if c.TokenRefreshBuffer < 0 {
+		add("Invalid token refresh buffer", "INVALID_TOKEN_REFRESH_BUFFER")
+	}
+	switch c.TokenRefreshMode {
+	case "", TokenRefreshReconnect, TokenRefreshReauth, TokenRefreshOff:
+	default:
+		add(fmt.Sprintf("Invalid token refresh mode: %s", c.TokenRefreshMode), "INVALID_TOKEN_REFRESH_MODE")
+	}
+
+	// Check keepalive and timeouts
+	if c.PingInterval < 0 || c.PongTimeout < 0 {
+		add("Ping interval and pong timeout must not be negative", "INVALID_KEEPALIVE")
+	}
+	if c.WriteTimeout < 0 || c.HandshakeTimeout < 0 || c.RequestTimeout < 0 {
+		add("Connection timeouts must not be negative", "INVALID_TIMEOUT")
+	}
+
+	// Devices are checked against the device list when a stream is opened
+	if (c.AudioDeviceID != nil && *c.AudioDeviceID < 0) || (c.AudioOutputDeviceID != nil && *c.AudioOutputDeviceID < 0) {
+		add("Audio device IDs must not be negative", ErrCodeInvalidAudioDevice)
 	}
 
 	return issues
@@ -137,9 +355,9 @@ func (c *VocalsConfig) PrintConfig() {
 	fmt.Println("🎤 Vocals SDK Configuration")
 	fmt.Println("==================================================")
 
-	apiKey := os.Getenv("VOCALS_DEV_API_KEY")
+	apiKey := c.resolvedAPIKey()
 	if apiKey != "" {
-		fmt.Printf("API Key: %s...\n", apiKey[:10])
+		fmt.Printf("API Key: %s...\n", apiKey[:min(len(apiKey), 10)])
 	} else {
 		fmt.Println("API Key: NOT SET")
 	}
@@ -150,15 +368,54 @@ func (c *VocalsConfig) PrintConfig() {
 	fmt.Printf("Auto Connect: %t\n", c.AutoConnect)
 	fmt.Printf("Max Reconnect Attempts: %d\n", c.MaxReconnectAttempts)
 	fmt.Printf("Reconnect Delay: %.1fs\n", c.ReconnectDelay)
+	if c.MaxReconnectElapsed > 0 {
+		fmt.Printf("Max Reconnect Elapsed: %.1fs\n", c.MaxReconnectElapsed)
+	}
 	fmt.Printf("Token Refresh Buffer: %.1fs\n", c.TokenRefreshBuffer)
+	fmt.Printf("Token Refresh Mode: %s\n", c.TokenRefreshMode)
+	if c.PingInterval > 0 {
+		fmt.Printf("Keepalive: ping every %.1fs, pong timeout %.1fs\n", c.PingInterval, c.PongTimeout)
+	} else {
+		fmt.Println("Keepalive: disabled")
+	}
+	fmt.Printf("Write Timeout: %.1fs\n", c.WriteTimeout)
+	fmt.Printf("Handshake Timeout: %.1fs\n", c.HandshakeTimeout)
+	fmt.Printf("Request Timeout: %.1fs\n", c.RequestTimeout)
 	fmt.Printf("Use Token Auth: %t\n", c.UseTokenAuth)
+	fmt.Printf("Token Source: %s\n", c.describeTokenSource())
 	fmt.Printf("Debug Level: %s\n", c.DebugLevel)
 	fmt.Printf("Debug WebSocket: %t\n", c.DebugWebsocket)
 	fmt.Printf("Debug Audio: %t\n", c.DebugAudio)
+	fmt.Printf("Media Mode: %s\n", c.MediaMode)
+	fmt.Printf("Resume Audio Replay: %dms\n", c.ResumeAudioReplayMs)
+	fmt.Printf("Outbound Queue: %d (%s)\n", c.OutboundQueueSize, c.OutboundOverflowPolicy)
+	fmt.Printf("Handler Dispatch: %s (queue %d, %s)\n", c.DispatchMode, c.HandlerQueueSize, c.HandlerOverflowPolicy)
+	if c.RecordPath != "" {
+		fmt.Printf("Recording To: %s\n", c.RecordPath)
+	}
 
 	if c.AudioDeviceID != nil {
 		fmt.Printf("Audio Device ID: %d\n", *c.AudioDeviceID)
 	} else {
 		fmt.Println("Audio Device: Default")
 	}
-}
\ No newline at end of file
+	if c.AudioOutputDeviceID != nil {
+		fmt.Printf("Audio Output Device ID: %d\n", *c.AudioOutputDeviceID)
+	} else {
+		fmt.Println("Audio Output Device: Default")
+	}
+}
+
+// GetReconnectPolicy returns the configured policy, falling back to a
+// constant ReconnectDelay
+func (c *VocalsConfig) GetReconnectPolicy() ReconnectPolicy {
+	if c.ReconnectPolicy != nil {
+		return c.ReconnectPolicy
+	}
+	return NewConstantBackoff(secondsToDuration(c.ReconnectDelay))
+}
+
+// secondsToDuration converts a config value in seconds to a time.Duration
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
@@ -0,0 +1,50 @@
+package vocals
+
+import (
+	"errors"
+	"testing"
+)
+
+const testAPIKey = "vdev_0123456789abcdef0123456789abcdef"
+
+func TestConfigValidationAgrees(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(c *VocalsConfig)
+		code   string
+	}{
+		{"reconnect attempts", func(c *VocalsConfig) { c.MaxReconnectAttempts = -1 }, "INVALID_RECONNECT_ATTEMPTS"},
+		{"media mode", func(c *VocalsConfig) { c.MediaMode = "carrier-pigeon" }, "INVALID_MEDIA_MODE"},
+		{"overflow policy", func(c *VocalsConfig) { c.OutboundOverflowPolicy = "shrug" }, "INVALID_OVERFLOW_POLICY"},
+		{"handler overflow policy", func(c *VocalsConfig) { c.HandlerOverflowPolicy = "shrug" }, "INVALID_OVERFLOW_POLICY"},
+		{"dispatch mode", func(c *VocalsConfig) { c.DispatchMode = "random" }, "INVALID_DISPATCH_MODE"},
+		{"replay", func(c *VocalsConfig) { c.ResumeAudioReplayMs = -5 }, "INVALID_RESUME_AUDIO_REPLAY"},
+	}
+	for _, tc := range tests {
+		config := NewVocalsConfig()
+		config.APIKey = testAPIKey
+		tc.modify(config)
+
+		err := ValidateVocalsConfig(config)
+		var vErr *VocalsError
+		if !errors.As(err, &vErr) || vErr.Code != tc.code {
+			t.Errorf("%s: ValidateVocalsConfig = %v, want %s", tc.name, err, tc.code)
+			continue
+		}
+		found := false
+		for _, issue := range config.Validate() {
+			found = found || issue == vErr.Message
+		}
+		if !found {
+			t.Errorf("%s: Validate does not report %q", tc.name, vErr.Message)
+		}
+	}
+}
+
+func TestDefaultConfigIsValid(t *testing.T) {
+	config := NewVocalsConfig()
+	config.APIKey = testAPIKey
+	if err := ValidateVocalsConfig(config); err != nil {
+		t.Errorf("ValidateVocalsConfig: %v", err)
+	}
+}
@@ -0,0 +1,90 @@
+package vocals_test
+
+import (
+	"context"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rojolang/vocals-sdk-go/pkg/vocals"
+)
+
+func TestConnectContextStopsRetrying(t *testing.T) {
+	srv := newTestServer(t)
+	srv.RejectConnections(1000)
+	config := srv.Config()
+	config.MaxReconnectAttempts = 1000
+	client := vocals.NewWebSocketClient(config, nil)
+	defer client.Disconnect()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := client.ConnectContext(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("ConnectContext = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("ConnectContext returned %s after its deadline", elapsed)
+	}
+	if client.IsConnected() {
+		t.Error("connected while every connection was rejected")
+	}
+
+	// The client can connect again once the server accepts it
+	srv.RejectConnections(0)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect after the cancelled attempt: %v", err)
+	}
+}
+
+func TestEnsureConnectedContextReturnsContextError(t *testing.T) {
+	srv := newTestServer(t)
+	srv.RejectConnections(1000)
+	config := srv.Config()
+	config.MaxReconnectAttempts = 1000
+	client := vocals.NewVocalsClient(config, vocals.NewAudioConfig(), nil, []string{"transcription"})
+	defer client.Cleanup()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	if err := client.EnsureConnectedContext(ctx); err != context.Canceled {
+		t.Errorf("EnsureConnectedContext = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestStreamAudioFileContextStopsOnCancel(t *testing.T) {
+	srv := newTestServer(t)
+	audioConfig := vocals.NewAudioConfig()
+	audioConfig.SampleRate = 16000
+	client := vocals.NewVocalsClient(srv.Config(), audioConfig, nil, []string{"transcription"})
+	defer client.Cleanup()
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+
+	// Ten seconds of float32 audio behind a 44 byte header
+	data := make([]byte, 44+4*16000*10)
+	for i := 44; i < len(data); i += 4 {
+		binary.LittleEndian.PutUint32(data[i:], math.Float32bits(0.5))
+	}
+	path := filepath.Join(t.TempDir(), "speech.wav")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := client.StreamAudioFileContext(ctx, path); err != context.DeadlineExceeded {
+		t.Fatalf("StreamAudioFileContext = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("streaming stopped after %s, want it to stop at the deadline", elapsed)
+	}
+	if _, err := srv.WaitForEvent("media", 1, time.Second); err != nil {
+		t.Errorf("no audio streamed before the deadline: %v", err)
+	}
+}
@@ -43,7 +43,6 @@ type Conversation struct {
 	cancel         context.CancelFunc
 	mu             sync.Mutex
 	interruptTimer *time.Timer
-	responseChan   chan string
 }
 
 func NewConversation(wsClient *WebSocketClient, audioProcessor *AudioProcessor, config *ConversationConfig) *Conversation {
@@ -59,11 +58,10 @@ func NewConversation(wsClient *WebSocketClient, audioProcessor *AudioProcessor,
 		audioProcessor: audioProcessor,
 		ctx:            ctx,
 		cancel:         cancel,
-		responseChan:   make(chan string, 10),
 	}
 
 	// Setup handlers
-	wsClient.AddMessageHandler(conv.handleIncomingMessage)
+	wsClient.AddEventHandler(conv.handleEvent)
 	if config.AutoInterrupt {
 		audioProcessor.AddAudioDataHandler(conv.handleAudioForInterrupt)
 	}
@@ -71,64 +69,40 @@ func NewConversation(wsClient *WebSocketClient, audioProcessor *AudioProcessor,
 	return conv
 }
 
-func (c *Conversation) handleIncomingMessage(msg *WebSocketResponse) {
+func (c *Conversation) handleEvent(event Event) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	msgType := "unknown"
-	if msg.Type != nil {
-		msgType = *msg.Type
-	}
-
-	switch msgType {
-	case "transcription":
-		if data, ok := msg.Data.(map[string]interface{}); ok {
-			text := getString(data, "text")
-			isFinal := getBoolUtil(data, "is_final")
-			
-			if len(c.currentText)+len(text) > c.config.MaxTextLength {
-				text = text[:c.config.MaxTextLength-len(c.currentText)]
-			}
-			c.currentText += text
-			c.tracker.AddTranscription(text)
-			
-			if isFinal {
-				c.addToHistory("user", c.currentText)
-				c.currentText = ""
-				go c.sendToAI()
-			}
-		}
-	case "partial_transcription":
-		if data, ok := msg.Data.(map[string]interface{}); ok {
-			text := getString(data, "text")
-			log.Printf("Partial transcription: %s", text)
+	switch e := event.(type) {
+	case TranscriptionEvent:
+		text := e.Text
+		if len(c.currentText)+len(text) > c.config.MaxTextLength {
+			text = text[:c.config.MaxTextLength-len(c.currentText)]
 		}
-	case "response":
-		if data, ok := msg.Data.(map[string]interface{}); ok {
-			text := getString(data, "text")
-			c.addToHistory("assistant", text)
-			c.tracker.AddResponse(text)
-			select {
-			case c.responseChan <- text:
-			default:
-			}
+		c.currentText += text
+		c.tracker.AddTranscription(text)
+
+		if e.IsFinal {
+			c.addToHistory("user", c.currentText)
+			c.currentText = ""
+			go c.sendToAI()
 		}
-	case "interruption":
+	case PartialTranscriptionEvent:
+		log.Printf("Partial transcription: %s", e.Text)
+	case ResponseEvent:
+		c.addToHistory("assistant", e.Text)
+		c.tracker.AddResponse(e.Text)
+	case InterruptionEvent:
 		log.Println("Interruption detected by server")
 		if c.config.AutoInterrupt {
 			if err := c.Interrupt(); err != nil {
 				log.Printf("Interrupt failed: %v", err)
 			}
 		}
-	case "error":
-		if data, ok := msg.Data.(map[string]interface{}); ok {
-			message := getString(data, "message")
-			code := getString(data, "code")
-			err := NewVocalsError(message, code)
-			log.Printf("Conversation error: %v", err)
-		}
+	case ServerErrorEvent:
+		log.Printf("Conversation error: %v", e.VocalsError())
 	default:
-		log.Printf("Unhandled message type: %s", msgType)
+		log.Printf("Unhandled message type: %s", event.EventType())
 	}
 }
 
@@ -170,15 +144,19 @@ func (c *Conversation) addToHistory(role, content string) {
 	log.Printf("Added to history: %s - %s (History size: %d)", role, contentPreview, len(c.history))
 }
 
-func (c *Conversation) sendToAI() {
+// sendToAI sends the history as a prompt and returns the reply to it.
+// Replies are matched to the prompt by message ID, so overlapping prompts
+// never see each other's answers.
+func (c *Conversation) sendToAI() (string, error) {
 	c.mu.Lock()
 	historyCopy := append([]map[string]string(nil), c.history...)
 	language := c.config.Language
 	prompt := c.config.Prompt
+	responseTimeout := c.config.ResponseTimeout
 	c.mu.Unlock()
 
 	if len(historyCopy) == 0 {
-		return
+		return "", nil
 	}
 
 	var fullPrompt string
@@ -196,22 +174,37 @@ func (c *Conversation) sendToAI() {
 			"language": language,
 		},
 	}
-	if err := c.wsClient.SendMessage(wsMsg); err != nil {
-		log.Printf("Failed to send AI prompt: %v", err)
-		return
-	}
 
-	// Wait for response with timeout
-	select {
-	case <-time.After(c.config.ResponseTimeout):
-		log.Println("AI response timeout")
-	case response := <-c.responseChan:
-		responsePreview := response
-		if len(response) > 50 {
-			responsePreview = response[:50] + "..."
+	// Wait for the reply to this prompt; the event handler records it in
+	// the history
+	ctx, cancel := context.WithTimeout(c.ctx, responseTimeout)
+	defer cancel()
+	reply, err := c.wsClient.Request(ctx, wsMsg)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			log.Println("AI response timeout")
+		} else {
+			log.Printf("AI prompt failed: %v", err)
 		}
-		log.Printf("Received AI response: %s", responsePreview)
+		return "", err
+	}
+
+	event, err := reply.Decode()
+	if err != nil {
+		log.Printf("Invalid AI response: %v", err)
+		return "", err
+	}
+	response, ok := event.(ResponseEvent)
+	if !ok {
+		return "", NewVocalsError(fmt.Sprintf("Unexpected %s reply to AI prompt", event.EventType()), "UNEXPECTED_REPLY")
+	}
+
+	responsePreview := response.Text
+	if len(response.Text) > 50 {
+		responsePreview = response.Text[:50] + "..."
 	}
+	log.Printf("Received AI response: %s", responsePreview)
+	return response.Text, nil
 }
 
 func (c *Conversation) SendText(text string) error {
@@ -370,7 +363,6 @@ func (c *Conversation) Cleanup() {
 		c.interruptTimer.Stop()
 		c.interruptTimer = nil
 	}
-	close(c.responseChan)
 	c.mu.Unlock()
 	log.Println("Conversation cleaned up")
 }
\ No newline at end of file
@@ -0,0 +1,52 @@
+package vocals
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConversationMatchesOverlappingReplies(t *testing.T) {
+	client, conn := newPipeClient(t, nil)
+	config := NewConversationConfig()
+	config.AutoInterrupt = false
+	conv := NewConversation(client, NewAudioProcessor(NewAudioConfig()), config)
+	defer conv.Cleanup()
+
+	type result struct {
+		text string
+		err  error
+	}
+	ask := func(text string) (<-chan result, string) {
+		conv.mu.Lock()
+		conv.addToHistory("user", text)
+		conv.mu.Unlock()
+		done := make(chan result, 1)
+		go func() {
+			text, err := conv.sendToAI()
+			done <- result{text, err}
+		}()
+		return done, conn.next(t, "ai_prompt").ID
+	}
+	first, firstID := ask("first question")
+	second, secondID := ask("second question")
+
+	// A reply without an ID answers neither prompt, and the prompts are
+	// answered in reverse order
+	conn.reply(t, "", "response", map[string]string{"text": "unsolicited"})
+	conn.reply(t, secondID, "response", map[string]string{"text": "second answer"})
+	conn.reply(t, firstID, "response", map[string]string{"text": "first answer"})
+
+	for _, tc := range []struct {
+		done <-chan result
+		want string
+	}{{first, "first answer"}, {second, "second answer"}} {
+		select {
+		case r := <-tc.done:
+			if r.err != nil || r.text != tc.want {
+				t.Errorf("got %q (%v), want %q", r.text, r.err, tc.want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no reply, want %q", tc.want)
+		}
+	}
+}
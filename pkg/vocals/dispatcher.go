@@ -0,0 +1,211 @@
+package vocals
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+)
+
+// Handler dispatch modes
+const (
+	DispatchOrdered    = "ordered"    // Each handler receives messages in order on its own worker
+	DispatchConcurrent = "concurrent" // Every delivery runs on a new goroutine, in no particular order
+)
+
+// HandlerStats reports the state of the handler queues
+type HandlerStats struct {
+	QueueDepth int   // Deliveries waiting for their handler
+	Dropped    int64 // Deliveries discarded because a handler fell behind
+}
+
+// dispatcher delivers values to registered handlers. In ordered mode every
+// handler has a worker goroutine fed by a bounded queue, so a handler sees
+// values in the order they were dispatched and never runs concurrently with
+// itself. When a handler's queue is full the overflow policy applies to that
+// handler alone: its values are dropped and counted, or with OverflowBlock
+// the dispatcher waits for it to catch up. Blocking dispatch must never be
+// called with a lock a handler may need; the WebSocketClient queues
+// deliveries made under its lock until it is released. Panics and the start
+// of an overflow are passed to onError.
+type dispatcher[T any, H ~func(T)] struct {
+	kind      string // Handler kind used in error reports
+	mode      string
+	queueSize int
+	policy    string
+	onError   func(*VocalsError)
+	entries   []*dispatchEntry[T, H]
+	nextID    uint64
+	dropped   atomic.Int64 // Values discarded because a handler fell behind
+	mu        sync.Mutex
+}
+
+type dispatchEntry[T any, H ~func(T)] struct {
+	id          uint64
+	handler     H
+	queue       chan T
+	done        chan struct{}
+	overflowing atomic.Bool // Set from the first drop until a value is queued again
+}
+
+func newDispatcher[T any, H ~func(T)](kind, mode string, queueSize int, policy string, onError func(*VocalsError)) *dispatcher[T, H] {
+	if queueSize <= 0 {
+		queueSize = 1
+	}
+	return &dispatcher[T, H]{
+		kind:      kind,
+		mode:      mode,
+		queueSize: queueSize,
+		policy:    policy,
+		onError:   onError,
+	}
+}
+
+// add registers a handler and returns a function that removes it. Values
+// still queued for the handler are discarded on removal.
+func (d *dispatcher[T, H]) add(handler H) func() {
+	d.mu.Lock()
+	d.nextID++
+	entry := &dispatchEntry[T, H]{id: d.nextID, handler: handler}
+	if d.mode != DispatchConcurrent {
+		entry.queue = make(chan T, d.queueSize)
+		entry.done = make(chan struct{})
+		go d.run(entry)
+	}
+	d.entries = append(d.entries, entry)
+	d.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			d.mu.Lock()
+			defer d.mu.Unlock()
+			for i, e := range d.entries {
+				if e.id == entry.id {
+					d.entries = append(d.entries[:i], d.entries[i+1:]...)
+					break
+				}
+			}
+			if entry.done != nil {
+				close(entry.done)
+			}
+		})
+	}
+}
+
+// dispatch delivers value to every registered handler
+func (d *dispatcher[T, H]) dispatch(value T) {
+	d.mu.Lock()
+	entries := append([]*dispatchEntry[T, H](nil), d.entries...)
+	d.mu.Unlock()
+
+	for _, entry := range entries {
+		if entry.queue == nil {
+			go d.call(entry.handler, value)
+			continue
+		}
+		select {
+		case entry.queue <- value:
+			entry.overflowing.Store(false)
+		case <-entry.done:
+		default:
+			d.overflow(entry, value)
+		}
+	}
+}
+
+// overflow applies the overflow policy to a handler whose queue is full
+func (d *dispatcher[T, H]) overflow(entry *dispatchEntry[T, H], value T) {
+	switch d.policy {
+	case OverflowBlock:
+		select {
+		case entry.queue <- value:
+		case <-entry.done:
+		}
+		return
+	case OverflowDropNewest:
+		d.dropped.Add(1)
+	default:
+		if !d.replaceOldest(entry, value) {
+			return
+		}
+	}
+
+	// Report once per overflow rather than for every dropped value
+	if entry.overflowing.Swap(true) {
+		return
+	}
+	err := NewVocalsError(fmt.Sprintf("%s is not keeping up, dropping messages", d.kind), "HANDLER_OVERFLOW").
+		AddDetail("handler", d.kind).
+		AddDetail("policy", d.policy)
+	if d.onError != nil {
+		d.onError(err)
+	} else {
+		log.Printf("%s (%s)", err.Message, err.Code)
+	}
+}
+
+// replaceOldest discards queued values until value fits in the queue. It
+// reports whether anything was discarded.
+func (d *dispatcher[T, H]) replaceOldest(entry *dispatchEntry[T, H], value T) bool {
+	dropped := false
+	for {
+		select {
+		case entry.queue <- value:
+			return dropped
+		case <-entry.done:
+			return dropped
+		default:
+		}
+		select {
+		case <-entry.queue:
+			d.dropped.Add(1)
+			dropped = true
+		default:
+		}
+	}
+}
+
+// queueDepth returns the number of values waiting in handler queues
+func (d *dispatcher[T, H]) queueDepth() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	depth := 0
+	for _, entry := range d.entries {
+		depth += len(entry.queue)
+	}
+	return depth
+}
+
+func (d *dispatcher[T, H]) len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.entries)
+}
+
+func (d *dispatcher[T, H]) run(entry *dispatchEntry[T, H]) {
+	for {
+		select {
+		case value := <-entry.queue:
+			d.call(entry.handler, value)
+		case <-entry.done:
+			return
+		}
+	}
+}
+
+func (d *dispatcher[T, H]) call(handler H, value T) {
+	defer func() {
+		if r := recover(); r != nil {
+			err := NewVocalsError(fmt.Sprintf("%s panicked: %v", d.kind, r), "HANDLER_PANIC").
+				AddDetail("handler", d.kind).
+				AddDetail("panic", r)
+			if d.onError != nil {
+				d.onError(err)
+			} else {
+				log.Printf("Recovered from %s panic: %v\n%s", d.kind, r, err.Stack)
+			}
+		}
+	}()
+	handler(value)
+}
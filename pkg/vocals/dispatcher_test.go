@@ -0,0 +1,258 @@
+package vocals
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+type intHandler func(int)
+
+func TestOrderedDispatchKeepsOrderPerHandler(t *testing.T) {
+	d := newDispatcher[int, intHandler]("test handler", DispatchOrdered, 4, OverflowBlock, nil)
+
+	const count = 200
+	var mu sync.Mutex
+	received := map[string][]int{}
+	var wg sync.WaitGroup
+	wg.Add(2 * count)
+	for _, name := range []string{"fast", "slow"} {
+		d.add(func(v int) {
+			if name == "slow" && v%20 == 0 {
+				time.Sleep(time.Millisecond)
+			}
+			mu.Lock()
+			received[name] = append(received[name], v)
+			mu.Unlock()
+			wg.Done()
+		})
+	}
+
+	for i := 0; i < count; i++ {
+		d.dispatch(i)
+	}
+	wg.Wait()
+
+	for name, values := range received {
+		for i, v := range values {
+			if v != i {
+				t.Fatalf("%s handler got %d at position %d", name, v, i)
+			}
+		}
+	}
+}
+
+func TestOrderedDispatchNeverRunsHandlerConcurrently(t *testing.T) {
+	d := newDispatcher[int, intHandler]("test handler", DispatchOrdered, 8, OverflowBlock, nil)
+
+	var running, overlaps int
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	wg.Add(50)
+	d.add(func(int) {
+		mu.Lock()
+		running++
+		if running > 1 {
+			overlaps++
+		}
+		mu.Unlock()
+		time.Sleep(100 * time.Microsecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+		wg.Done()
+	})
+
+	var senders sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		senders.Add(1)
+		go func() {
+			defer senders.Done()
+			for j := 0; j < 10; j++ {
+				d.dispatch(j)
+			}
+		}()
+	}
+	senders.Wait()
+	wg.Wait()
+
+	if overlaps > 0 {
+		t.Errorf("handler ran concurrently with itself %d times", overlaps)
+	}
+}
+
+func TestConcurrentDispatchDeliversEverything(t *testing.T) {
+	d := newDispatcher[int, intHandler]("test handler", DispatchConcurrent, 0, OverflowBlock, nil)
+
+	var mu sync.Mutex
+	seen := map[int]bool{}
+	var wg sync.WaitGroup
+	wg.Add(100)
+	d.add(func(v int) {
+		mu.Lock()
+		seen[v] = true
+		mu.Unlock()
+		wg.Done()
+	})
+	for i := 0; i < 100; i++ {
+		d.dispatch(i)
+	}
+	wg.Wait()
+
+	if len(seen) != 100 {
+		t.Errorf("delivered %d distinct values, want 100", len(seen))
+	}
+}
+
+func TestDispatcherRemoveStopsDelivery(t *testing.T) {
+	d := newDispatcher[int, intHandler]("test handler", DispatchOrdered, 1, OverflowBlock, nil)
+
+	block := make(chan struct{})
+	calls := make(chan int, 10)
+	remove := d.add(func(v int) {
+		calls <- v
+		<-block
+	})
+
+	d.dispatch(1)
+	<-calls
+	d.dispatch(2) // Fills the queue while the handler is busy
+
+	// A dispatch to the removed handler's full queue must not block
+	remove()
+	done := make(chan struct{})
+	go func() {
+		d.dispatch(3)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("dispatch blocked on a removed handler")
+	}
+	close(block)
+
+	if d.len() != 0 {
+		t.Errorf("%d handlers registered after removal, want 0", d.len())
+	}
+	select {
+	case v := <-calls:
+		if v == 3 {
+			t.Errorf("removed handler received %d", v)
+		}
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestDispatcherRecoversPanics(t *testing.T) {
+	panics := make(chan *VocalsError, 1)
+	d := newDispatcher[int, intHandler]("test handler", DispatchOrdered, 1, OverflowBlock, func(err *VocalsError) {
+		panics <- err
+	})
+
+	delivered := make(chan int, 1)
+	d.add(func(v int) {
+		if v == 1 {
+			panic("boom")
+		}
+		delivered <- v
+	})
+	d.dispatch(1)
+	d.dispatch(2)
+
+	select {
+	case err := <-panics:
+		if err.Code != "HANDLER_PANIC" {
+			t.Errorf("panic reported with code %s, want HANDLER_PANIC", err.Code)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("panic was not reported")
+	}
+	select {
+	case v := <-delivered:
+		if v != 2 {
+			t.Errorf("delivered %d after the panic, want 2", v)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("handler stopped receiving after a panic")
+	}
+}
+
+func TestOverflowOnlyAffectsTheSlowHandler(t *testing.T) {
+	for _, tc := range []struct {
+		policy string
+		want   []int // What the blocked handler sees once released
+	}{
+		{OverflowDropOldest, []int{0, 98, 99}},
+		{OverflowDropNewest, []int{0, 1, 2}},
+	} {
+		errs := make(chan *VocalsError, 10)
+		d := newDispatcher[int, intHandler]("test handler", DispatchOrdered, 2, tc.policy, func(err *VocalsError) {
+			errs <- err
+		})
+
+		started := make(chan struct{})
+		block := make(chan struct{})
+		slow := make(chan int, 100)
+		d.add(func(v int) {
+			if v == 0 {
+				close(started)
+			}
+			<-block
+			slow <- v
+		})
+		fast := make(chan int)
+		d.add(func(v int) { fast <- v })
+
+		// Every dispatch must return while the slow handler is stuck, and the
+		// fast handler keeps receiving everything in order
+		for i := 0; i < 100; i++ {
+			dispatched := make(chan struct{})
+			go func() {
+				d.dispatch(i)
+				close(dispatched)
+			}()
+			select {
+			case v := <-fast:
+				if v != i {
+					t.Fatalf("%s: fast handler got %d, want %d", tc.policy, v, i)
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("%s: fast handler starved at %d", tc.policy, i)
+			}
+			select {
+			case <-dispatched:
+			case <-time.After(time.Second):
+				t.Fatalf("%s: dispatch blocked behind the slow handler", tc.policy)
+			}
+			if i == 0 {
+				<-started
+			}
+		}
+
+		close(block)
+		var got []int
+		for len(got) < len(tc.want) {
+			select {
+			case v := <-slow:
+				got = append(got, v)
+			case <-time.After(time.Second):
+				t.Fatalf("%s: slow handler got %v, want %v", tc.policy, got, tc.want)
+			}
+		}
+		for i := range got {
+			if got[i] != tc.want[i] {
+				t.Errorf("%s: slow handler got %v, want %v", tc.policy, got, tc.want)
+				break
+			}
+		}
+		if n := d.dropped.Load(); n != 97 {
+			t.Errorf("%s: dropped %d values, want 97", tc.policy, n)
+		}
+		if len(errs) != 1 {
+			t.Errorf("%s: reported %d overflows, want 1", tc.policy, len(errs))
+		} else if err := <-errs; err.Code != "HANDLER_OVERFLOW" {
+			t.Errorf("%s: overflow reported with code %s", tc.policy, err.Code)
+		}
+	}
+}
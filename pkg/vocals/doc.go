@@ -84,6 +84,32 @@
 //		// Handle custom messages
 //	})
 //
+// Event handlers receive the same messages decoded into typed events such as
+// TranscriptionEvent and TTSAudioEvent; types without a registered decoder
+// arrive as RawEvent:
+//
+//	client.AddEventHandler(func(event vocals.Event) {
+//		if e, ok := event.(vocals.TranscriptionEvent); ok {
+//			fmt.Println(e.Text)
+//		}
+//	})
+//
+// On subscribes to a single event type and returns a Subscription:
+//
+//	sub := vocals.On(client, func(e vocals.TTSAudioEvent) {
+//		fmt.Println(e.SegmentID)
+//	})
+//	defer sub.Unsubscribe()
+//
+// # Transports
+//
+// WebSocketClient talks to the server through a Transport. The default
+// uses gorilla/websocket; set VocalsConfig.Transport to plug in an
+// in-memory pipe, a recording wrapper or a custom network stack:
+//
+//	config := vocals.NewVocalsConfig()
+//	config.Transport = myTransport
+//
 // # Statistics and Monitoring
 //
 // Advanced streaming with real-time statistics:
@@ -171,10 +197,11 @@
 // # Dependencies
 //
 // The SDK depends on:
-//   - github.com/gordonklaus/portaudio: Audio I/O
+//   - github.com/gordonklaus/portaudio: Audio I/O, only in cgo builds without
+//     the vocals_headless tag
 //   - github.com/gorilla/websocket: WebSocket client
 //   - github.com/rs/zerolog: Structured logging
 //   - github.com/spf13/cobra: CLI framework
 //   - github.com/golang-jwt/jwt/v4: JWT handling
-//   - github.com/joho/godotenv: Environment variables
+//   - github.com/joho/godotenv: Optional .env loading via LoadDotEnv
 package vocals
\ No newline at end of file
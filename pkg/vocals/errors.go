@@ -1,6 +1,7 @@
 package vocals
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"runtime"
@@ -24,6 +25,9 @@ const (
 	ErrCodeUnknown             = "UNKNOWN_ERROR"
 	ErrCodeTimeout             = "TIMEOUT_ERROR"
 	ErrCodeAuthFailed          = "AUTH_FAILED"
+	ErrCodeAuthRejected        = "AUTH_REJECTED"      // The server refused the credentials; retrying will not help
+	ErrCodeTransient           = "TRANSIENT_ERROR"    // A network failure or server error that may clear up
+	ErrCodeMalformedResponse   = "MALFORMED_RESPONSE" // The server answered with something unusable, such as a proxy error page
 )
 
 // VocalsError represents an enhanced error with additional context
@@ -183,6 +187,8 @@ func IsRetryableError(err *VocalsError) bool {
 		ErrCodeReconnectFailed,
 		ErrCodeWebSocket,
 		ErrCodeTimeout,
+		ErrCodeTransient,
+		ErrCodeMalformedResponse,
 	}
 	for _, code := range retryableCodes {
 		if err.Code == code {
@@ -192,6 +198,20 @@ func IsRetryableError(err *VocalsError) bool {
 	return false
 }
 
+// permanentError returns the VocalsError in err's chain when it reports a
+// failure that retrying cannot fix, such as rejected credentials
+func permanentError(err error) (*VocalsError, bool) {
+	var vErr *VocalsError
+	if !errors.As(err, &vErr) {
+		return nil, false
+	}
+	switch vErr.Code {
+	case ErrCodeAuthRejected, ErrCodeConfigInvalid, "INVALID_API_KEY_FORMAT", "MISSING_API_KEY":
+		return vErr, true
+	}
+	return nil, false
+}
+
 // Helper to check if error is critical
 func IsCriticalError(err *VocalsError) bool {
 	if err == nil {
@@ -201,6 +221,7 @@ func IsCriticalError(err *VocalsError) bool {
 		ErrCodeAuthFailed,
 		ErrCodeTokenExpired,
 		ErrCodeConfigInvalid,
+		ErrCodeAuthRejected,
 	}
 	for _, code := range criticalCodes {
 		if err.Code == code {
@@ -0,0 +1,341 @@
+package vocals
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Server message types with built-in decoders
+const (
+	EventTypeTranscription        = "transcription"
+	EventTypePartialTranscription = "partial_transcription"
+	EventTypeResponse             = "response"
+	EventTypeTTSAudio             = "tts_audio"
+	EventTypeInterruption         = "interruption"
+	EventTypeError                = "error"
+)
+
+// Event is a decoded server message
+type Event interface {
+	EventType() string
+}
+
+// TranscriptionEvent carries recognized user speech
+type TranscriptionEvent struct {
+	Text    string `json:"text"`
+	IsFinal bool   `json:"is_final"`
+}
+
+func (TranscriptionEvent) EventType() string { return EventTypeTranscription }
+
+// PartialTranscriptionEvent carries an interim transcription that may still change
+type PartialTranscriptionEvent struct {
+	Text string `json:"text"`
+}
+
+func (PartialTranscriptionEvent) EventType() string { return EventTypePartialTranscription }
+
+// ResponseEvent carries the assistant's text response
+type ResponseEvent struct {
+	Text string `json:"text"`
+}
+
+func (ResponseEvent) EventType() string { return EventTypeResponse }
+
+// TTSAudioEvent carries one synthesized speech segment
+type TTSAudioEvent struct {
+	SegmentID        string  `json:"segment_id"`
+	SentenceNumber   int     `json:"sentence_number"`
+	AudioData        string  `json:"audio_data"` // Base64 encoded audio
+	SampleRate       int     `json:"sample_rate"`
+	Text             string  `json:"text"`
+	Format           string  `json:"format"`
+	DurationSeconds  float64 `json:"duration_seconds"`
+	GenerationTimeMs int     `json:"generation_time_ms"`
+}
+
+func (TTSAudioEvent) EventType() string { return EventTypeTTSAudio }
+
+// Validate checks that the fields needed for playback are present
+func (e TTSAudioEvent) Validate() error {
+	if e.SegmentID == "" {
+		return NewVocalsError("TTS audio event is missing segment_id", "INVALID_TTS_MESSAGE")
+	}
+	if e.AudioData == "" {
+		return NewVocalsError(fmt.Sprintf("TTS audio event %s is missing audio_data", e.SegmentID), "INVALID_TTS_MESSAGE")
+	}
+	return nil
+}
+
+// defaultTTSSampleRate is assumed for TTS audio without a sample_rate
+const defaultTTSSampleRate = 24000
+
+// Segment converts the event to a TTSAudioSegment for the playback queue.
+// A missing sample rate defaults to 24000 Hz.
+func (e TTSAudioEvent) Segment() TTSAudioSegment {
+	if e.SampleRate <= 0 {
+		e.SampleRate = defaultTTSSampleRate
+	}
+	return TTSAudioSegment{
+		Text:             e.Text,
+		AudioData:        e.AudioData,
+		SampleRate:       e.SampleRate,
+		SegmentID:        e.SegmentID,
+		SentenceNumber:   e.SentenceNumber,
+		GenerationTimeMs: e.GenerationTimeMs,
+		Format:           e.Format,
+		DurationSeconds:  e.DurationSeconds,
+	}
+}
+
+// InterruptionEvent signals that the server interrupted speech playback
+type InterruptionEvent struct {
+	SegmentID    string   `json:"segment_id"`
+	StartTime    float64  `json:"start_time"`
+	Reason       string   `json:"reason"`
+	ConnectionID *int     `json:"connection_id,omitempty"`
+	Timestamp    *float64 `json:"timestamp,omitempty"`
+}
+
+func (InterruptionEvent) EventType() string { return EventTypeInterruption }
+
+// ServerErrorEvent is an error reported by the server
+type ServerErrorEvent struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (ServerErrorEvent) EventType() string { return EventTypeError }
+
+// VocalsError converts the event to a *VocalsError
+func (e ServerErrorEvent) VocalsError() *VocalsError {
+	code := e.Code
+	if code == "" {
+		code = "SERVER_ERROR"
+	}
+	return NewVocalsError(e.Message, code)
+}
+
+// RawEvent is a message without a registered decoder. Data holds the
+// undecoded data payload.
+type RawEvent struct {
+	Type string
+	Data json.RawMessage
+}
+
+func (e RawEvent) EventType() string { return e.Type }
+
+// EventDecoder decodes the data payload of a server message
+type EventDecoder func(data json.RawMessage) (Event, error)
+
+// EventHandler receives decoded server messages
+type EventHandler func(Event)
+
+var (
+	eventDecoders = map[string]EventDecoder{
+		EventTypeTranscription:        jsonEventDecoder[TranscriptionEvent](),
+		EventTypePartialTranscription: jsonEventDecoder[PartialTranscriptionEvent](),
+		EventTypeResponse:             jsonEventDecoder[ResponseEvent](),
+		EventTypeTTSAudio:             jsonEventDecoder[TTSAudioEvent](),
+		EventTypeInterruption:         jsonEventDecoder[InterruptionEvent](),
+		EventTypeError:                jsonEventDecoder[ServerErrorEvent](),
+	}
+	eventDecodersMu sync.RWMutex
+)
+
+// jsonEventDecoder returns a decoder that unmarshals the payload into T
+// leniently: a field of the wrong type, such as a sample_rate sent as a
+// string, is converted when possible and left empty otherwise instead of
+// failing the whole event
+func jsonEventDecoder[T Event]() EventDecoder {
+	return func(data json.RawMessage) (Event, error) {
+		var event T
+		if len(data) > 0 && string(data) != "null" {
+			if err := unmarshalLenient(data, &event); err != nil {
+				return nil, err
+			}
+		}
+		return event, nil
+	}
+}
+
+// unmarshalLenient unmarshals a JSON object into the struct v, converting
+// mistyped fields with coerceJSONField. Malformed JSON is still an error.
+func unmarshalLenient(data json.RawMessage, v interface{}) error {
+	err := json.Unmarshal(data, v)
+	var typeErr *json.UnmarshalTypeError
+	if !errors.As(err, &typeErr) {
+		return err
+	}
+
+	// json.Unmarshal filled in every field it could; retry the others
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	value := reflect.ValueOf(v).Elem()
+	if value.Kind() != reflect.Struct {
+		return err
+	}
+	for i := 0; i < value.NumField(); i++ {
+		key, _, _ := strings.Cut(value.Type().Field(i).Tag.Get("json"), ",")
+		raw, ok := fields[key]
+		if !ok || key == "" || key == "-" {
+			continue
+		}
+		field := value.Field(i)
+		if json.Unmarshal(raw, field.Addr().Interface()) == nil {
+			continue
+		}
+		field.SetZero()
+		if !coerceJSONField(field, raw) {
+			log.Printf("Type assertion failed for key '%s': expected %s, got %s", key, field.Type(), raw)
+		}
+	}
+	return nil
+}
+
+// coerceJSONField stores raw in field when it is a number, string or bool
+// that converts to the field's type, and reports whether it did
+func coerceJSONField(field reflect.Value, raw json.RawMessage) bool {
+	var value interface{}
+	if err := json.Unmarshal(raw, &value); err != nil || value == nil {
+		return false
+	}
+
+	switch field.Kind() {
+	case reflect.Pointer:
+		elem := reflect.New(field.Type().Elem())
+		if !coerceJSONField(elem.Elem(), raw) {
+			return false
+		}
+		field.Set(elem)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, ok := jsonNumber(value)
+		if !ok || field.OverflowInt(int64(n)) {
+			return false
+		}
+		field.SetInt(int64(n))
+	case reflect.Float32, reflect.Float64:
+		n, ok := jsonNumber(value)
+		if !ok {
+			return false
+		}
+		field.SetFloat(n)
+	case reflect.String:
+		switch v := value.(type) {
+		case float64:
+			field.SetString(strconv.FormatFloat(v, 'f', -1, 64))
+		case bool:
+			field.SetString(strconv.FormatBool(v))
+		default:
+			return false
+		}
+	case reflect.Bool:
+		s, ok := value.(string)
+		if !ok {
+			return false
+		}
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return false
+		}
+		field.SetBool(b)
+	default:
+		return false
+	}
+	return true
+}
+
+// jsonNumber returns a JSON number, or a string holding one, as a float64
+func jsonNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case string:
+		n, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+// RegisterEventDecoder sets the decoder used for a message type, replacing
+// any existing one. Register decoders before connecting.
+func RegisterEventDecoder(eventType string, decoder EventDecoder) {
+	eventDecodersMu.Lock()
+	defer eventDecodersMu.Unlock()
+	eventDecoders[eventType] = decoder
+}
+
+// DecodeEvent decodes the data payload of a message of the given type.
+// Types without a registered decoder are returned as a RawEvent.
+func DecodeEvent(eventType string, data json.RawMessage) (Event, error) {
+	eventDecodersMu.RLock()
+	decoder, ok := eventDecoders[eventType]
+	eventDecodersMu.RUnlock()
+
+	if !ok {
+		return RawEvent{Type: eventType, Data: data}, nil
+	}
+	event, err := decoder(data)
+	if err != nil {
+		return nil, NewJSONError(fmt.Sprintf("Failed to decode %s event: %v", eventType, err))
+	}
+	return event, nil
+}
+
+// Decode returns the typed event for the message
+func (r *WebSocketResponse) Decode() (Event, error) {
+	eventType := r.messageType()
+
+	data := r.rawData
+	if data == nil && r.Data != nil {
+		// Built by hand rather than received; re-encode the payload
+		encoded, err := json.Marshal(r.Data)
+		if err != nil {
+			return nil, NewJSONError(fmt.Sprintf("Failed to encode %s event: %v", eventType, err))
+		}
+		data = encoded
+	}
+	return DecodeEvent(eventType, data)
+}
+
+func (r *WebSocketResponse) messageType() string {
+	if r.Type != nil {
+		return *r.Type
+	}
+	return ""
+}
+
+// parseResponse decodes a server frame, keeping the raw data payload so the
+// typed event can be decoded without re-encoding
+func parseResponse(frame []byte) (*WebSocketResponse, error) {
+	var envelope struct {
+		ID    string          `json:"id"`
+		Event string          `json:"event"`
+		Type  *string         `json:"type"`
+		Data  json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(frame, &envelope); err != nil {
+		return nil, err
+	}
+
+	response := &WebSocketResponse{
+		ID:      envelope.ID,
+		Event:   envelope.Event,
+		Type:    envelope.Type,
+		rawData: envelope.Data,
+	}
+	if len(envelope.Data) > 0 {
+		if err := json.Unmarshal(envelope.Data, &response.Data); err != nil {
+			return nil, err
+		}
+	}
+	return response, nil
+}
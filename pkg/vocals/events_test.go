@@ -0,0 +1,125 @@
+package vocals
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDecodeEventTypes(t *testing.T) {
+	tests := []struct {
+		eventType string
+		data      string
+		want      Event
+	}{
+		{EventTypeTranscription, `{"text":"hello","is_final":true}`, TranscriptionEvent{Text: "hello", IsFinal: true}},
+		{EventTypePartialTranscription, `{"text":"hel"}`, PartialTranscriptionEvent{Text: "hel"}},
+		{EventTypeResponse, `{"text":"hi"}`, ResponseEvent{Text: "hi"}},
+		{EventTypeError, `{"code":"BAD","message":"nope"}`, ServerErrorEvent{Code: "BAD", Message: "nope"}},
+		{EventTypeResponse, `null`, ResponseEvent{}},
+	}
+	for _, tc := range tests {
+		got, err := DecodeEvent(tc.eventType, json.RawMessage(tc.data))
+		if err != nil {
+			t.Errorf("DecodeEvent(%s, %s): %v", tc.eventType, tc.data, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("DecodeEvent(%s, %s) = %#v, want %#v", tc.eventType, tc.data, got, tc.want)
+		}
+	}
+}
+
+func TestDecodeEventUnknownTypeIsRaw(t *testing.T) {
+	got, err := DecodeEvent("custom_event", json.RawMessage(`{"x":1}`))
+	if err != nil {
+		t.Fatalf("DecodeEvent: %v", err)
+	}
+	raw, ok := got.(RawEvent)
+	if !ok || raw.Type != "custom_event" || string(raw.Data) != `{"x":1}` {
+		t.Errorf("got %#v, want RawEvent carrying the payload", got)
+	}
+}
+
+func TestDecodeEventIsLenient(t *testing.T) {
+	data := `{"segment_id":7,"audio_data":"AAAA","sample_rate":"16000","sentence_number":2.0,"duration_seconds":"1.5"}`
+	got, err := DecodeEvent(EventTypeTTSAudio, json.RawMessage(data))
+	if err != nil {
+		t.Fatalf("DecodeEvent: %v", err)
+	}
+	want := TTSAudioEvent{SegmentID: "7", AudioData: "AAAA", SampleRate: 16000, SentenceNumber: 2, DurationSeconds: 1.5}
+	if got != want {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+
+	// Fields that cannot be converted are left empty
+	got, err = DecodeEvent(EventTypeTTSAudio, json.RawMessage(`{"segment_id":"s","audio_data":"AAAA","sample_rate":{"hz":1}}`))
+	if err != nil {
+		t.Fatalf("DecodeEvent: %v", err)
+	}
+	if tts := got.(TTSAudioEvent); tts.SampleRate != 0 || tts.AudioData != "AAAA" {
+		t.Errorf("got %#v, want the other fields kept and sample_rate empty", tts)
+	}
+
+	got, err = DecodeEvent(EventTypeInterruption, json.RawMessage(`{"connection_id":"3","timestamp":"12.5"}`))
+	if err != nil {
+		t.Fatalf("DecodeEvent: %v", err)
+	}
+	interruption := got.(InterruptionEvent)
+	if interruption.ConnectionID == nil || *interruption.ConnectionID != 3 || interruption.Timestamp == nil || *interruption.Timestamp != 12.5 {
+		t.Errorf("got %#v, want connection 3 at 12.5", interruption)
+	}
+
+	got, err = DecodeEvent(EventTypeTranscription, json.RawMessage(`{"text":"hi","is_final":"true"}`))
+	if err != nil {
+		t.Fatalf("DecodeEvent: %v", err)
+	}
+	if got != (TranscriptionEvent{Text: "hi", IsFinal: true}) {
+		t.Errorf("got %#v, want a final transcription", got)
+	}
+}
+
+func TestDecodeEventRejectsMalformedJSON(t *testing.T) {
+	if _, err := DecodeEvent(EventTypeTTSAudio, json.RawMessage(`{"segment_id":`)); err == nil {
+		t.Error("decoded a truncated payload")
+	}
+}
+
+func TestTTSAudioSegmentDefaultsSampleRate(t *testing.T) {
+	event := TTSAudioEvent{SegmentID: "s", AudioData: "AAAA"}
+	if rate := event.Segment().SampleRate; rate != 24000 {
+		t.Errorf("segment sample rate = %d, want 24000", rate)
+	}
+	event.SampleRate = 16000
+	if rate := event.Segment().SampleRate; rate != 16000 {
+		t.Errorf("segment sample rate = %d, want 16000", rate)
+	}
+}
+
+func TestTTSAudioEventValidate(t *testing.T) {
+	if err := (TTSAudioEvent{AudioData: "AAAA"}).Validate(); err == nil {
+		t.Error("accepted an event without segment_id")
+	}
+	if err := (TTSAudioEvent{SegmentID: "s"}).Validate(); err == nil {
+		t.Error("accepted an event without audio_data")
+	}
+	if err := (TTSAudioEvent{SegmentID: "s", AudioData: "AAAA"}).Validate(); err != nil {
+		t.Errorf("rejected a complete event: %v", err)
+	}
+}
+
+func TestParseResponseKeepsID(t *testing.T) {
+	response, err := parseResponse([]byte(`{"id":"abc","type":"response","data":{"text":"hi"}}`))
+	if err != nil {
+		t.Fatalf("parseResponse: %v", err)
+	}
+	if response.ID != "abc" {
+		t.Errorf("ID = %q, want abc", response.ID)
+	}
+	event, err := response.Decode()
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if event != (ResponseEvent{Text: "hi"}) {
+		t.Errorf("got %#v, want the response text", event)
+	}
+}
@@ -30,25 +30,28 @@ func CreateTranscriptionHandler(callback func(string, bool)) MessageHandler {
 			return
 		}
 
-		if *msg.Type != "transcription" && *msg.Type != "partial_transcription" {
+		if *msg.Type != EventTypeTranscription && *msg.Type != EventTypePartialTranscription {
 			return
 		}
 
-		data, ok := msg.Data.(map[string]interface{})
-		if !ok {
-			log.Printf("Invalid transcription data: expected map[string]interface{}, got %T", msg.Data)
+		event, err := msg.Decode()
+		if err != nil {
+			log.Printf("Invalid transcription data: %v", err)
 			return
 		}
 
-		text := getString(data, "text")
-		if text == "" {
-			log.Printf("Empty transcription text in message data: %+v", data)
-			return
+		var text string
+		var isFinal bool
+		switch e := event.(type) {
+		case TranscriptionEvent:
+			text, isFinal = e.Text, e.IsFinal
+		case PartialTranscriptionEvent:
+			text = e.Text
 		}
 
-		isFinal := false
-		if _, exists := data["is_final"]; exists {
-			isFinal = getBool(data, "is_final")
+		if text == "" {
+			log.Printf("Empty transcription text in message data: %+v", msg.Data)
+			return
 		}
 
 		callback(text, isFinal)
@@ -57,64 +60,42 @@ func CreateTranscriptionHandler(callback func(string, bool)) MessageHandler {
 
 func CreateTTSHandler(callback func(TTSAudioSegment)) MessageHandler {
 	return func(msg *WebSocketResponse) {
-		if msg.Type == nil || *msg.Type != "tts_audio" {
+		if msg.Type == nil || *msg.Type != EventTypeTTSAudio {
 			return
 		}
 
-		data, ok := msg.Data.(map[string]interface{})
-		if !ok {
-			log.Printf("Invalid TTS data: expected map[string]interface{}, got %T", msg.Data)
+		event, err := msg.Decode()
+		if err != nil {
+			log.Printf("Invalid TTS data: %v", err)
 			return
 		}
-
-		segmentID := getString(data, "segment_id")
-		if segmentID == "" {
-			log.Printf("Invalid TTS segment ID in message data: %+v", data)
+		tts, ok := event.(TTSAudioEvent)
+		if !ok {
 			return
 		}
-
-		sentenceNumber := getInt(data, "sentence_number")
-		audioData := getString(data, "audio_data")
-		if audioData == "" {
-			log.Printf("Invalid TTS audio data in message data: %+v", data)
+		if err := tts.Validate(); err != nil {
+			log.Printf("Invalid TTS message: %v", err)
 			return
 		}
 
-		sampleRate := getInt(data, "sample_rate")
-		if sampleRate == 0 {
-			sampleRate = 24000 // Default
-		}
-
-		segment := TTSAudioSegment{
-			SegmentID:        segmentID,
-			SentenceNumber:   sentenceNumber,
-			AudioData:        audioData,
-			SampleRate:       sampleRate,
-			Text:             getString(data, "text"),
-			Format:           getString(data, "format"),
-			DurationSeconds:  getFloat64(data, "duration_seconds"),
-			GenerationTimeMs: getInt(data, "generation_time_ms"),
-		}
-
-		callback(segment)
+		callback(tts.Segment())
 	}
 }
 
 func CreateResponseHandler(callback func(string)) MessageHandler {
 	return func(msg *WebSocketResponse) {
-		if msg.Type == nil || *msg.Type != "response" {
+		if msg.Type == nil || *msg.Type != EventTypeResponse {
 			return
 		}
 
-		data, ok := msg.Data.(map[string]interface{})
-		if !ok {
+		event, err := msg.Decode()
+		if err != nil {
 			log.Println("Invalid response data")
 			return
 		}
 
-		text := getString(data, "text")
-		if text != "" {
-			callback(text)
+		if response, ok := event.(ResponseEvent); ok && response.Text != "" {
+			callback(response.Text)
 		} else {
 			log.Println("Empty response text")
 		}
@@ -123,7 +104,7 @@ func CreateResponseHandler(callback func(string)) MessageHandler {
 
 func CreateInterruptionHandler(callback func()) MessageHandler {
 	return func(msg *WebSocketResponse) {
-		if msg.Type != nil && *msg.Type == "interruption" {
+		if msg.Type != nil && *msg.Type == EventTypeInterruption {
 			callback()
 		}
 	}
@@ -349,19 +330,19 @@ func CreateMetricsHandler(callback func(string, map[string]interface{})) Message
 			"type":      msgType,
 		}
 
-		if data, ok := msg.Data.(map[string]interface{}); ok {
+		if event, err := msg.Decode(); err == nil {
 			// Add specific metrics based on message type
-			switch msgType {
-			case "transcription":
-				if text := getString(data, "text"); text != "" {
-					metrics["text_length"] = len(text)
+			switch e := event.(type) {
+			case TranscriptionEvent:
+				if e.Text != "" {
+					metrics["text_length"] = len(e.Text)
 				}
-			case "tts_audio":
-				if segmentID := getString(data, "segment_id"); segmentID != "" {
-					metrics["segment_id"] = segmentID
+			case TTSAudioEvent:
+				if e.SegmentID != "" {
+					metrics["segment_id"] = e.SegmentID
 				}
-				if sampleRate := getInt(data, "sample_rate"); sampleRate > 0 {
-					metrics["sample_rate"] = sampleRate
+				if e.SampleRate > 0 {
+					metrics["sample_rate"] = e.SampleRate
 				}
 			}
 		}
@@ -0,0 +1,59 @@
+package vocals_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/rojolang/vocals-sdk-go/pkg/vocals"
+	"github.com/rojolang/vocals-sdk-go/pkg/vocalstest"
+)
+
+func TestStalledEventsReaderDoesNotStarveOthers(t *testing.T) {
+	srv := newTestServer(t)
+	config := srv.Config()
+	config.HandlerQueueSize = 4
+	config.PingInterval = 0.05
+	config.PongTimeout = 0.5
+	client := connectClient(t, config)
+
+	errs := make(chan *vocals.VocalsError, 100)
+	client.AddErrorHandler(func(err *vocals.VocalsError) { errs <- err })
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client.Events(ctx) // Never read
+	responses := make(chan vocals.ResponseEvent, 1000)
+	vocals.On(client, func(e vocals.ResponseEvent) { responses <- e })
+
+	// Far more than the Events buffer and the handler queue hold
+	for i := 0; i < 200; i++ {
+		if err := srv.Send(vocalstest.Response(strconv.Itoa(i))); err != nil {
+			t.Fatalf("Send: %v", err)
+		}
+		if e := waitFor(t, responses, "the response"); e.Text != strconv.Itoa(i) {
+			t.Fatalf("got response %q, want %d", e.Text, i)
+		}
+	}
+
+	// Pongs keep arriving, so the keepalive never gives up on the connection
+	time.Sleep(time.Second)
+	if n := client.GetConnectionMetrics().MissedPongs; n != 0 {
+		t.Errorf("%d pongs missed", n)
+	}
+	if !client.IsConnected() || srv.ConnectionCount() != 1 {
+		t.Errorf("connected = %t after %d connections, want the first one kept", client.IsConnected(), srv.ConnectionCount())
+	}
+	if client.GetHandlerStats().Dropped == 0 {
+		t.Error("no deliveries to the stalled reader were dropped")
+	}
+	overflows := 0
+	for len(errs) > 0 {
+		if err := <-errs; err.Code == "HANDLER_OVERFLOW" {
+			overflows++
+		}
+	}
+	if overflows != 1 {
+		t.Errorf("reported %d overflows, want 1", overflows)
+	}
+}
@@ -0,0 +1,154 @@
+package vocals
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ConnectionMetrics reports keepalive measurements for the connection
+type ConnectionMetrics struct {
+	LastRTT       time.Duration // Round trip time of the most recent ping
+	AverageRTT    time.Duration // Moving average of ping round trip times
+	MinRTT        time.Duration
+	MaxRTT        time.Duration
+	PingsSent     int64
+	PongsReceived int64
+	MissedPongs   int64     // Pings that timed out and forced a reconnect
+	LastPongAt    time.Time // Zero until the first pong arrives
+}
+
+// connectionMetrics accumulates ConnectionMetrics across connections
+type connectionMetrics struct {
+	metrics ConnectionMetrics
+	mu      sync.Mutex
+}
+
+func (m *connectionMetrics) pingSent() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.metrics.PingsSent++
+}
+
+func (m *connectionMetrics) pongReceived(rtt time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.metrics.PongsReceived++
+	m.metrics.LastPongAt = time.Now()
+	m.metrics.LastRTT = rtt
+	if m.metrics.AverageRTT == 0 {
+		m.metrics.AverageRTT = rtt
+	} else {
+		// Weight recent samples like TCP's smoothed RTT (alpha = 1/8)
+		m.metrics.AverageRTT += (rtt - m.metrics.AverageRTT) / 8
+	}
+	if m.metrics.MinRTT == 0 || rtt < m.metrics.MinRTT {
+		m.metrics.MinRTT = rtt
+	}
+	if rtt > m.metrics.MaxRTT {
+		m.metrics.MaxRTT = rtt
+	}
+}
+
+func (m *connectionMetrics) pongMissed() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.metrics.MissedPongs++
+}
+
+func (m *connectionMetrics) snapshot() ConnectionMetrics {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.metrics
+}
+
+// startKeepalive installs the pong handler on conn and starts pinging it
+// every PingInterval. When a pong does not arrive within PongTimeout the
+// connection is closed, which sends the read loop down the normal reconnect
+// path. It must be called before the read loop starts.
+func (wsc *WebSocketClient) startKeepalive(conn TransportConn, writer *outboundWriter) {
+	interval := secondsToDuration(wsc.config.PingInterval)
+	if interval <= 0 {
+		return
+	}
+	pinger, ok := conn.(PingConn)
+	if !ok {
+		if wsc.config.DebugWebsocket {
+			log.Printf("Transport does not support pings, keepalive disabled")
+		}
+		return
+	}
+
+	pongs := make(chan string, 1)
+	pinger.SetPongHandler(func(data []byte) {
+		select {
+		case pongs <- string(data):
+		default:
+		}
+	})
+
+	go wsc.keepalive(conn, pinger, writer, interval, pongs)
+}
+
+func (wsc *WebSocketClient) keepalive(conn TransportConn, pinger PingConn, writer *outboundWriter, interval time.Duration, pongs <-chan string) {
+	pongTimeout := secondsToDuration(wsc.config.PongTimeout)
+	writeTimeout := secondsToDuration(wsc.config.WriteTimeout)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var payload string
+	var sentAt time.Time
+	var timeout <-chan time.Time // Set while waiting for a pong
+
+	for {
+		select {
+		case <-writer.done:
+			return
+
+		case <-ticker.C:
+			if payload != "" {
+				continue // Still waiting for the previous pong
+			}
+			sentAt = time.Now()
+			payload = strconv.FormatInt(sentAt.UnixNano(), 10)
+
+			var deadline time.Time
+			if writeTimeout > 0 {
+				deadline = sentAt.Add(writeTimeout)
+			}
+			if err := pinger.WritePing([]byte(payload), deadline); err != nil {
+				if wsc.config.DebugWebsocket {
+					log.Printf("Failed to send ping, closing connection: %v", err)
+				}
+				conn.Close()
+				return
+			}
+			wsc.metrics.pingSent()
+			if pongTimeout > 0 {
+				timeout = time.After(pongTimeout)
+			}
+
+		case data := <-pongs:
+			if data != payload {
+				continue // Unsolicited or stale pong
+			}
+			rtt := time.Since(sentAt)
+			wsc.metrics.pongReceived(rtt)
+			payload = ""
+			timeout = nil
+			if wsc.config.DebugWebsocket {
+				log.Printf("Ping RTT: %s", rtt)
+			}
+
+		case <-timeout:
+			wsc.metrics.pongMissed()
+			wsc.handleError(NewVocalsError(fmt.Sprintf("No pong received within %s, reconnecting", pongTimeout), "PONG_TIMEOUT"))
+			conn.Close()
+			return
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package vocals_test
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/rojolang/vocals-sdk-go/pkg/vocals"
+)
+
+func TestKeepaliveMeasuresRTT(t *testing.T) {
+	srv := newTestServer(t)
+	config := srv.Config()
+	config.PingInterval = 0.02
+	client := connectClient(t, config)
+
+	deadline := time.Now().Add(5 * time.Second)
+	metrics := client.GetConnectionMetrics()
+	for metrics.PongsReceived < 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("metrics = %+v, want 3 pongs", metrics)
+		}
+		time.Sleep(10 * time.Millisecond)
+		metrics = client.GetConnectionMetrics()
+	}
+
+	if metrics.PingsSent < metrics.PongsReceived || metrics.MissedPongs != 0 {
+		t.Errorf("metrics = %+v, want a pong for each ping", metrics)
+	}
+	if metrics.LastRTT <= 0 || metrics.MinRTT <= 0 || metrics.MinRTT > metrics.MaxRTT ||
+		metrics.AverageRTT < metrics.MinRTT || metrics.AverageRTT > metrics.MaxRTT {
+		t.Errorf("metrics = %+v, want consistent round trip times", metrics)
+	}
+	if metrics.LastPongAt.IsZero() {
+		t.Error("LastPongAt not set")
+	}
+}
+
+func TestMissedPongReconnects(t *testing.T) {
+	srv := newTestServer(t)
+	srv.IgnorePings(true)
+	config := srv.Config()
+	config.PingInterval = 0.05
+	config.PongTimeout = 0.1
+	client := connectClient(t, config)
+	errs := make(chan *vocals.VocalsError, 10)
+	client.AddErrorHandler(func(err *vocals.VocalsError) { errs <- err })
+	resumed := make(chan vocals.ResumeEvent, 10)
+	client.AddResumeHandler(func(event vocals.ResumeEvent) { resumed <- event })
+
+	for err := waitFor(t, errs, "the pong timeout"); err.Code != "PONG_TIMEOUT"; {
+		err = waitFor(t, errs, "the pong timeout")
+	}
+	srv.IgnorePings(false)
+
+	if event := waitFor(t, resumed, "resume"); !event.Success {
+		t.Fatalf("resume failed: %v", event.Err)
+	}
+	if n := client.GetConnectionMetrics().MissedPongs; n < 1 {
+		t.Errorf("%d missed pongs recorded, want at least 1", n)
+	}
+	if n := srv.ConnectionCount(); n < 2 {
+		t.Errorf("%d connections opened, want a new one after the dead peer", n)
+	}
+}
+
+func TestHandshakeTimeout(t *testing.T) {
+	// A peer that accepts TCP connections but never answers the upgrade
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	config := vocals.NewVocalsConfig()
+	endpoint := "ws://" + listener.Addr().String()
+	config.WsEndpoint = &endpoint
+	config.UseTokenAuth = false
+	config.HandshakeTimeout = 0.1
+	config.MaxReconnectAttempts = 1
+	client := vocals.NewWebSocketClient(config, nil)
+	defer client.Disconnect()
+
+	start := time.Now()
+	if err := client.Connect(); err == nil {
+		t.Fatal("connected to a peer that never completed the handshake")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Connect took %s, want the handshake timeout to end it", elapsed)
+	}
+}
@@ -0,0 +1,61 @@
+package vocals
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+// Media transfer modes for outbound audio
+const (
+	MediaModeJSON   = "json"   // base64 PCM inside a JSON "media" event
+	MediaModeBinary = "binary" // raw PCM in binary frames with a MediaFrameHeader
+)
+
+const (
+	// MediaFrameVersion is the binary media frame format version
+	MediaFrameVersion = 1
+	// MediaFrameHeaderSize is the size in bytes of a binary media frame header
+	MediaFrameHeaderSize = 16
+)
+
+// MediaFrameHeader precedes the PCM payload of a binary media frame.
+//
+// Layout (little-endian):
+//
+//	[0]     version
+//	[1:4]   reserved, zero
+//	[4:8]   sequence number (uint32)
+//	[8:16]  capture timestamp, microseconds since the Unix epoch (int64)
+type MediaFrameHeader struct {
+	Version   uint8
+	Sequence  uint32
+	Timestamp time.Time
+}
+
+// EncodeMediaFrame prepends a header to raw PCM bytes
+func EncodeMediaFrame(sequence uint32, timestamp time.Time, pcm []byte) []byte {
+	frame := make([]byte, MediaFrameHeaderSize+len(pcm))
+	frame[0] = MediaFrameVersion
+	binary.LittleEndian.PutUint32(frame[4:8], sequence)
+	binary.LittleEndian.PutUint64(frame[8:16], uint64(timestamp.UnixMicro()))
+	copy(frame[MediaFrameHeaderSize:], pcm)
+	return frame
+}
+
+// DecodeMediaFrame splits a binary media frame into its header and PCM payload
+func DecodeMediaFrame(frame []byte) (MediaFrameHeader, []byte, error) {
+	if len(frame) < MediaFrameHeaderSize {
+		return MediaFrameHeader{}, nil, fmt.Errorf("media frame too short: %d bytes", len(frame))
+	}
+	if frame[0] != MediaFrameVersion {
+		return MediaFrameHeader{}, nil, fmt.Errorf("unsupported media frame version: %d", frame[0])
+	}
+
+	header := MediaFrameHeader{
+		Version:   frame[0],
+		Sequence:  binary.LittleEndian.Uint32(frame[4:8]),
+		Timestamp: time.UnixMicro(int64(binary.LittleEndian.Uint64(frame[8:16]))),
+	}
+	return header, frame[MediaFrameHeaderSize:], nil
+}
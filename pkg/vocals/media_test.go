@@ -0,0 +1,198 @@
+package vocals_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/rojolang/vocals-sdk-go/pkg/vocals"
+)
+
+func TestMediaFrameRoundTrip(t *testing.T) {
+	timestamp := time.UnixMicro(1700000000123456)
+	pcm := []byte{1, 2, 3, 4, 5, 6}
+
+	frame := vocals.EncodeMediaFrame(42, timestamp, pcm)
+	if len(frame) != vocals.MediaFrameHeaderSize+len(pcm) {
+		t.Fatalf("frame is %d bytes, want %d", len(frame), vocals.MediaFrameHeaderSize+len(pcm))
+	}
+	if !bytes.Equal(frame[1:4], []byte{0, 0, 0}) {
+		t.Errorf("reserved bytes are %v, want zero", frame[1:4])
+	}
+
+	header, payload, err := vocals.DecodeMediaFrame(frame)
+	if err != nil {
+		t.Fatalf("DecodeMediaFrame: %v", err)
+	}
+	if header.Version != vocals.MediaFrameVersion || header.Sequence != 42 || !header.Timestamp.Equal(timestamp) {
+		t.Errorf("header = %+v, want version %d, sequence 42, timestamp %v", header, vocals.MediaFrameVersion, timestamp)
+	}
+	if !bytes.Equal(payload, pcm) {
+		t.Errorf("payload = %v, want %v", payload, pcm)
+	}
+}
+
+func TestMediaFrameLayout(t *testing.T) {
+	frame := vocals.EncodeMediaFrame(0x01020304, time.UnixMicro(0x0A0B0C0D), nil)
+	want := []byte{
+		vocals.MediaFrameVersion, 0, 0, 0,
+		0x04, 0x03, 0x02, 0x01,
+		0x0D, 0x0C, 0x0B, 0x0A, 0, 0, 0, 0,
+	}
+	if !bytes.Equal(frame, want) {
+		t.Errorf("frame = % x, want % x", frame, want)
+	}
+}
+
+func TestDecodeMediaFrameRejectsBadFrames(t *testing.T) {
+	if _, _, err := vocals.DecodeMediaFrame(make([]byte, vocals.MediaFrameHeaderSize-1)); err == nil {
+		t.Error("accepted a frame shorter than the header")
+	}
+
+	frame := vocals.EncodeMediaFrame(1, time.Now(), []byte{1})
+	frame[0] = vocals.MediaFrameVersion + 1
+	if _, _, err := vocals.DecodeMediaFrame(frame); err == nil {
+		t.Error("accepted an unknown frame version")
+	}
+}
+
+func TestBinaryMediaFraming(t *testing.T) {
+	srv := newTestServer(t)
+	srv.EnableBinaryMedia(true)
+	config := srv.Config()
+	config.MediaMode = vocals.MediaModeBinary
+	client := connectClient(t, config)
+
+	for deadline := time.Now().Add(time.Second); client.MediaMode() != vocals.MediaModeBinary; {
+		if time.Now().After(deadline) {
+			t.Fatalf("media mode = %s, want binary", client.MediaMode())
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	blocks := [][]float32{{0.1, 0.2}, {-0.3, 0.4}, {0.5, -0.6}}
+	for _, samples := range blocks {
+		if err := client.SendMessage(vocals.CreateAudioMessage(samples, 16000, vocals.FormatPCMF32LE)); err != nil {
+			t.Fatalf("SendMessage: %v", err)
+		}
+	}
+
+	frames, err := srv.WaitForEvent("media", len(blocks), time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, frame := range frames {
+		if frame.Binary == nil {
+			t.Fatalf("media frame %d was sent as JSON", i)
+		}
+		if frame.Sequence != frames[0].Sequence+uint32(i) {
+			t.Errorf("frame %d has sequence %d, want %d", i, frame.Sequence, frames[0].Sequence+uint32(i))
+		}
+		samples, err := frame.Samples()
+		if err != nil {
+			t.Fatalf("decoding frame %d: %v", i, err)
+		}
+		if len(samples) != 2 || samples[0] != blocks[i][0] || samples[1] != blocks[i][1] {
+			t.Errorf("frame %d carries %v, want %v", i, samples, blocks[i])
+		}
+	}
+}
+
+func TestStreamSourceSendsWireChannels(t *testing.T) {
+	srv := newTestServer(t)
+	audioConfig := vocals.NewAudioConfig()
+	audioConfig.SampleRate = 16000
+	client := vocals.NewVocalsClient(srv.Config(), audioConfig, nil, []string{"transcription"})
+	defer client.Cleanup()
+
+	stereo := make([]float32, 2*160)
+	for i := 0; i < len(stereo); i += 2 {
+		stereo[i], stereo[i+1] = 0.5, 0.25
+	}
+	audio := make(chan []float32, 1)
+	audio <- stereo
+	close(audio)
+	source, err := vocals.NewChannelSource(audio, 16000, 2)
+	if err != nil {
+		t.Fatalf("NewChannelSource: %v", err)
+	}
+	if err := client.StreamSource(context.Background(), source); err != nil {
+		t.Fatalf("StreamSource: %v", err)
+	}
+
+	frames, err := srv.WaitForEvent("media", 1, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	samples, err := frames[0].Samples()
+	if err != nil {
+		t.Fatalf("decoding media: %v", err)
+	}
+	if len(samples) != 160 || samples[0] != 0.375 {
+		t.Errorf("sent %d samples starting %v, want 160 mono samples of 0.375", len(samples), samples[:1])
+	}
+}
+
+// stampedSource replays frames that were captured earlier
+type stampedSource struct {
+	frames []vocals.AudioFrame
+}
+
+func (s *stampedSource) ReadFrame(ctx context.Context) (vocals.AudioFrame, error) {
+	if len(s.frames) == 0 {
+		return vocals.AudioFrame{}, io.EOF
+	}
+	frame := s.frames[0]
+	s.frames = s.frames[1:]
+	return frame, nil
+}
+
+func (s *stampedSource) Close() error { return nil }
+
+func TestBinaryMediaCarriesCaptureTime(t *testing.T) {
+	srv := newTestServer(t)
+	srv.EnableBinaryMedia(true)
+	config := srv.Config()
+	config.MediaMode = vocals.MediaModeBinary
+	audioConfig := vocals.NewAudioConfig()
+	audioConfig.SampleRate = 16000
+	client := vocals.NewVocalsClient(config, audioConfig, nil, []string{"transcription"})
+	defer client.Cleanup()
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	for deadline := time.Now().Add(time.Second); client.MediaMode() != vocals.MediaModeBinary; {
+		if time.Now().After(deadline) {
+			t.Fatalf("media mode = %s, want binary", client.MediaMode())
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// Audio captured a minute ago keeps its capture time when sent now
+	captured := time.UnixMicro(time.Now().Add(-time.Minute).UnixMicro())
+	source := &stampedSource{}
+	for i := 0; i < 3; i++ {
+		source.frames = append(source.frames, vocals.AudioFrame{
+			Samples:    make([]float32, 160),
+			SampleRate: 16000,
+			Channels:   1,
+			CapturedAt: captured.Add(time.Duration(i) * 10 * time.Millisecond),
+		})
+	}
+	if err := client.StreamSource(context.Background(), source); err != nil {
+		t.Fatalf("StreamSource: %v", err)
+	}
+
+	frames, err := srv.WaitForEvent("media", 3, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, frame := range frames {
+		want := captured.Add(time.Duration(i) * 10 * time.Millisecond)
+		if frame.Binary == nil || !frame.Timestamp.Equal(want) {
+			t.Errorf("frame %d stamped %v (binary %v), want the capture time %v", i, frame.Timestamp, frame.Binary != nil, want)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package vocals
+
+import (
+	"fmt"
+	"log"
+)
+
+// Middleware intercepts messages in both directions. Outbound sees every
+// WebSocketMessage before it is written, including the start and settings
+// events sent on connect; Inbound sees every WebSocketResponse before it
+// reaches the message and event handlers.
+//
+// Either method may modify the message in place or return a replacement.
+// Returning a nil message drops it silently and returning an error vetoes
+// it: SendMessage returns the error, and inbound errors are reported to the
+// error handlers. Middlewares run in registration order on the sending or
+// reading goroutine, so they should be quick and must not call back into
+// the client's connection methods.
+type Middleware interface {
+	Outbound(message *WebSocketMessage) (*WebSocketMessage, error)
+	Inbound(message *WebSocketResponse) (*WebSocketResponse, error)
+}
+
+// MiddlewareFuncs adapts a pair of functions to Middleware. A nil function
+// passes messages through unchanged.
+type MiddlewareFuncs struct {
+	OutboundFunc func(*WebSocketMessage) (*WebSocketMessage, error)
+	InboundFunc  func(*WebSocketResponse) (*WebSocketResponse, error)
+}
+
+// Outbound calls OutboundFunc
+func (m MiddlewareFuncs) Outbound(message *WebSocketMessage) (*WebSocketMessage, error) {
+	if m.OutboundFunc == nil {
+		return message, nil
+	}
+	return m.OutboundFunc(message)
+}
+
+// Inbound calls InboundFunc
+func (m MiddlewareFuncs) Inbound(message *WebSocketResponse) (*WebSocketResponse, error) {
+	if m.InboundFunc == nil {
+		return message, nil
+	}
+	return m.InboundFunc(message)
+}
+
+// applyOutbound runs message through the middlewares. A nil result without
+// an error means the message was dropped.
+func (wsc *WebSocketClient) applyOutbound(message *WebSocketMessage) (*WebSocketMessage, error) {
+	for _, m := range wsc.middlewares.snapshot() {
+		event := message.Event
+		var err error
+		if message, err = m.Outbound(message); err != nil {
+			return nil, NewVocalsError(fmt.Sprintf("Outbound %s event rejected by middleware: %v", event, err), "MIDDLEWARE_REJECTED").
+				AddDetail("event", event)
+		}
+		if message == nil {
+			if wsc.config.DebugWebsocket {
+				log.Printf("Outbound %s event dropped by middleware", event)
+			}
+			return nil, nil
+		}
+	}
+	return message, nil
+}
+
+// applyInbound runs message through the middlewares. A nil result means the
+// message was dropped or rejected; rejections are reported as errors.
+func (wsc *WebSocketClient) applyInbound(message *WebSocketResponse) *WebSocketResponse {
+	middlewares := wsc.middlewares.snapshot()
+	if len(middlewares) == 0 {
+		return message
+	}
+
+	for _, m := range middlewares {
+		messageType := message.messageType()
+		var err error
+		if message, err = m.Inbound(message); err != nil {
+			wsc.handleError(NewVocalsError(fmt.Sprintf("Inbound %s message rejected by middleware: %v", messageType, err), "MIDDLEWARE_REJECTED").
+				AddDetail("type", messageType))
+			return nil
+		}
+		if message == nil {
+			if wsc.config.DebugWebsocket {
+				log.Printf("Inbound %s message dropped by middleware", messageType)
+			}
+			return nil
+		}
+	}
+
+	// Data may have been rewritten; decode typed events from it rather than
+	// from the original frame
+	message.rawData = nil
+	return message
+}
+
+// AddMiddleware appends m to the middleware chain and returns a function
+// that removes it
+func (wsc *WebSocketClient) AddMiddleware(m Middleware) func() {
+	return wsc.middlewares.add(m)
+}
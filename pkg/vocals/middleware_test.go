@@ -0,0 +1,188 @@
+package vocals_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rojolang/vocals-sdk-go/pkg/vocals"
+	"github.com/rojolang/vocals-sdk-go/pkg/vocalstest"
+)
+
+// tagMiddleware appends its tag to the string data of "ask" events and
+// to the text of responses, recording the order it ran in
+func tagMiddleware(tag string, mu *sync.Mutex, order *[]string) vocals.Middleware {
+	return vocals.MiddlewareFuncs{
+		OutboundFunc: func(message *vocals.WebSocketMessage) (*vocals.WebSocketMessage, error) {
+			if message.Event == "ask" {
+				message.Data = message.Data.(string) + tag
+				mu.Lock()
+				*order = append(*order, "out "+tag)
+				mu.Unlock()
+			}
+			return message, nil
+		},
+		InboundFunc: func(message *vocals.WebSocketResponse) (*vocals.WebSocketResponse, error) {
+			if data, ok := message.Data.(map[string]interface{}); ok {
+				data["text"] = data["text"].(string) + tag
+				mu.Lock()
+				*order = append(*order, "in "+tag)
+				mu.Unlock()
+			}
+			return message, nil
+		},
+	}
+}
+
+func TestMiddlewareRunsInRegistrationOrder(t *testing.T) {
+	srv := newTestServer(t)
+	srv.On("ask", vocalstest.Response("reply-"))
+	client := connectClient(t, srv.Config())
+
+	var mu sync.Mutex
+	var order []string
+	client.AddMiddleware(tagMiddleware("a", &mu, &order))
+	removeB := client.AddMiddleware(tagMiddleware("b", &mu, &order))
+	events := make(chan vocals.Event, 10)
+	client.AddEventHandler(func(event vocals.Event) { events <- event })
+
+	if err := client.SendMessage(&vocals.WebSocketMessage{Event: "ask", Data: "q-"}); err != nil {
+		t.Fatalf("SendMessage: %v", err)
+	}
+	frames, err := srv.WaitForEvent("ask", 1, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sent string
+	if err := frames[0].DecodeData(&sent); err != nil || sent != "q-ab" {
+		t.Errorf("server received %q (%v), want q-ab", sent, err)
+	}
+
+	// Typed events are decoded from the rewritten data
+	if event := waitFor(t, events, "the response"); event != (vocals.ResponseEvent{Text: "reply-ab"}) {
+		t.Errorf("handler got %#v, want the rewritten response", event)
+	}
+	mu.Lock()
+	if want := []string{"out a", "out b", "in a", "in b"}; !equalOrder(order, want) {
+		t.Errorf("middlewares ran in order %v, want %v", order, want)
+	}
+	order = nil
+	mu.Unlock()
+
+	removeB()
+	if err := client.SendMessage(&vocals.WebSocketMessage{Event: "ask", Data: "q-"}); err != nil {
+		t.Fatalf("SendMessage: %v", err)
+	}
+	if event := waitFor(t, events, "the second response"); event != (vocals.ResponseEvent{Text: "reply-a"}) {
+		t.Errorf("handler got %#v after removing a middleware, want reply-a", event)
+	}
+}
+
+func TestMiddlewareRejectsAndDrops(t *testing.T) {
+	srv := newTestServer(t)
+	client := connectClient(t, srv.Config())
+	errorsSeen := make(chan *vocals.VocalsError, 10)
+	client.AddErrorHandler(func(err *vocals.VocalsError) { errorsSeen <- err })
+	events := make(chan vocals.Event, 10)
+	client.AddEventHandler(func(event vocals.Event) { events <- event })
+
+	client.AddMiddleware(vocals.MiddlewareFuncs{
+		OutboundFunc: func(message *vocals.WebSocketMessage) (*vocals.WebSocketMessage, error) {
+			switch message.Event {
+			case "veto":
+				return nil, errors.New("not allowed")
+			case "drop":
+				return nil, nil
+			}
+			return message, nil
+		},
+		InboundFunc: func(message *vocals.WebSocketResponse) (*vocals.WebSocketResponse, error) {
+			event, _ := message.Decode()
+			switch event {
+			case vocals.ResponseEvent{Text: "veto"}:
+				return nil, errors.New("not allowed")
+			case vocals.ResponseEvent{Text: "drop"}:
+				return nil, nil
+			}
+			return message, nil
+		},
+	})
+
+	var vErr *vocals.VocalsError
+	if err := client.SendMessage(&vocals.WebSocketMessage{Event: "veto"}); !errors.As(err, &vErr) || vErr.Code != "MIDDLEWARE_REJECTED" {
+		t.Errorf("vetoed SendMessage = %v, want MIDDLEWARE_REJECTED", err)
+	}
+	if err := client.SendMessage(&vocals.WebSocketMessage{Event: "drop"}); err != nil {
+		t.Errorf("dropped SendMessage = %v, want nil", err)
+	}
+	if err := client.SendMessage(&vocals.WebSocketMessage{Event: "marker"}); err != nil {
+		t.Fatalf("SendMessage: %v", err)
+	}
+	if _, err := srv.WaitForEvent("marker", 1, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	for _, frame := range srv.Received() {
+		if frame.Event == "veto" || frame.Event == "drop" {
+			t.Errorf("server received the %s event", frame.Event)
+		}
+	}
+
+	for _, text := range []string{"veto", "drop", "kept"} {
+		if err := srv.Send(vocalstest.Response(text)); err != nil {
+			t.Fatalf("Send: %v", err)
+		}
+	}
+	if err := waitFor(t, errorsSeen, "the inbound rejection"); err.Code != "MIDDLEWARE_REJECTED" {
+		t.Errorf("inbound rejection reported as %s", err.Code)
+	}
+	if event := waitFor(t, events, "the kept response"); event != (vocals.ResponseEvent{Text: "kept"}) {
+		t.Errorf("handler got %#v, want only the kept response", event)
+	}
+}
+
+func TestRequestWaitsForMiddlewareID(t *testing.T) {
+	srv := newTestServer(t)
+	srv.On("ask", vocalstest.Response("ok"))
+	client := connectClient(t, srv.Config())
+	remove := client.AddMiddleware(vocals.MiddlewareFuncs{
+		OutboundFunc: func(message *vocals.WebSocketMessage) (*vocals.WebSocketMessage, error) {
+			message.ID = "tenant-" + message.ID
+			return message, nil
+		},
+	})
+
+	response, err := client.Request(context.Background(), &vocals.WebSocketMessage{Event: "ask"})
+	if err != nil {
+		t.Fatalf("Request: %v", err)
+	}
+	if frames := srv.ReceivedEvents("ask"); len(frames) != 1 || frames[0].ID != response.ID {
+		t.Errorf("sent %v, replied to %s", frames, response.ID)
+	}
+	remove()
+
+	client.AddMiddleware(vocals.MiddlewareFuncs{
+		OutboundFunc: func(message *vocals.WebSocketMessage) (*vocals.WebSocketMessage, error) {
+			message.ID = ""
+			return message, nil
+		},
+	})
+	_, err = client.Request(context.Background(), &vocals.WebSocketMessage{Event: "ask"})
+	var vErr *vocals.VocalsError
+	if !errors.As(err, &vErr) || vErr.Code != "MIDDLEWARE_REJECTED" {
+		t.Errorf("Request without an ID = %v, want MIDDLEWARE_REJECTED", err)
+	}
+}
+
+func equalOrder(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,232 @@
+package vocals
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Overflow policies for the outbound audio queue
+const (
+	OverflowBlock      = "block"       // Wait for room in the queue
+	OverflowDropOldest = "drop_oldest" // Discard the oldest queued audio frame
+	OverflowDropNewest = "drop_newest" // Discard the frame being sent
+)
+
+// OutboundStats reports the state of the outbound message queue
+type OutboundStats struct {
+	ControlQueueDepth int   // Control messages waiting to be written
+	AudioQueueDepth   int   // Audio frames waiting to be written
+	Sent              int64 // Frames written to the connection
+	DroppedOldest     int64 // Audio frames discarded to make room for newer ones
+	DroppedNewest     int64 // Audio frames discarded because the queue was full
+	WriteErrors       int64 // Frames that failed to write
+}
+
+// QueueDepth returns the total number of queued frames
+func (s OutboundStats) QueueDepth() int {
+	return s.ControlQueueDepth + s.AudioQueueDepth
+}
+
+// Dropped returns the total number of discarded audio frames
+func (s OutboundStats) Dropped() int64 {
+	return s.DroppedOldest + s.DroppedNewest
+}
+
+// outboundCounters are shared by the writers of successive connections
+type outboundCounters struct {
+	sent          atomic.Int64
+	droppedOldest atomic.Int64
+	droppedNewest atomic.Int64
+	writeErrors   atomic.Int64
+}
+
+type outboundFrame struct {
+	frameType FrameType
+	data      []byte
+	result    chan error // nil for fire-and-forget audio frames
+	marker    bool       // Written nothing; reports once the frames before it are written
+}
+
+// outboundWriter is the only goroutine writing to a connection. Control
+// messages are written before any queued audio. A failed write closes the
+// connection so the read loop starts the normal reconnect.
+type outboundWriter struct {
+	conn         TransportConn
+	control      chan *outboundFrame
+	audio        chan *outboundFrame
+	policy       string
+	writeTimeout time.Duration
+	counters     *outboundCounters
+	debug        bool
+	done         chan struct{}
+	once         sync.Once
+}
+
+func newOutboundWriter(conn TransportConn, queueSize int, policy string, writeTimeout time.Duration, counters *outboundCounters, debug bool) *outboundWriter {
+	if queueSize <= 0 {
+		queueSize = 1
+	}
+	w := &outboundWriter{
+		conn:         conn,
+		control:      make(chan *outboundFrame, queueSize),
+		audio:        make(chan *outboundFrame, queueSize),
+		policy:       policy,
+		writeTimeout: writeTimeout,
+		counters:     counters,
+		debug:        debug,
+		done:         make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+func (w *outboundWriter) run() {
+	for {
+		// Drain control messages first so audio never delays them
+		select {
+		case frame := <-w.control:
+			w.write(frame)
+			continue
+		default:
+		}
+
+		select {
+		case frame := <-w.control:
+			w.write(frame)
+		case frame := <-w.audio:
+			w.write(frame)
+		case <-w.done:
+			return
+		}
+	}
+}
+
+func (w *outboundWriter) write(frame *outboundFrame) {
+	if frame.marker {
+		frame.result <- nil
+		return
+	}
+
+	if deadliner, ok := w.conn.(DeadlineConn); ok && w.writeTimeout > 0 {
+		deadliner.SetWriteDeadline(time.Now().Add(w.writeTimeout))
+	}
+
+	err := w.conn.WriteFrame(frame.frameType, frame.data)
+	if err != nil {
+		w.counters.writeErrors.Add(1)
+		if w.debug {
+			log.Printf("Failed to write frame, closing connection: %v", err)
+		}
+		w.conn.Close()
+	} else {
+		w.counters.sent.Add(1)
+	}
+	if frame.result != nil {
+		frame.result <- err
+	}
+}
+
+// sendControl queues a control frame and waits until it has been written
+func (w *outboundWriter) sendControl(frameType FrameType, data []byte) error {
+	frame := &outboundFrame{frameType: frameType, data: data, result: make(chan error, 1)}
+
+	select {
+	case w.control <- frame:
+	case <-w.done:
+		return fmt.Errorf("connection closed")
+	}
+
+	select {
+	case err := <-frame.result:
+		return err
+	case <-w.done:
+		return fmt.Errorf("connection closed")
+	}
+}
+
+// sendAudio queues an audio frame without waiting for it to be written,
+// applying the overflow policy when the queue is full
+func (w *outboundWriter) sendAudio(frameType FrameType, data []byte) error {
+	frame := &outboundFrame{frameType: frameType, data: data}
+
+	select {
+	case w.audio <- frame:
+		return nil
+	case <-w.done:
+		return fmt.Errorf("connection closed")
+	default:
+	}
+
+	switch w.policy {
+	case OverflowBlock:
+		select {
+		case w.audio <- frame:
+			return nil
+		case <-w.done:
+			return fmt.Errorf("connection closed")
+		}
+	case OverflowDropNewest:
+		w.counters.droppedNewest.Add(1)
+		return nil
+	default:
+		for {
+			select {
+			case w.audio <- frame:
+				return nil
+			case <-w.done:
+				return fmt.Errorf("connection closed")
+			default:
+			}
+			select {
+			case <-w.audio:
+				w.counters.droppedOldest.Add(1)
+			default:
+			}
+		}
+	}
+}
+
+// drain waits up to timeout for the queued frames to be written, then
+// stops the writer
+func (w *outboundWriter) drain(timeout time.Duration) {
+	defer w.stop()
+
+	deadline := time.Now().Add(timeout)
+	for len(w.audio) > 0 {
+		if time.Now().After(deadline) {
+			return
+		}
+		select {
+		case <-w.done:
+			return
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+
+	// Control frames are written first, so once the marker is through
+	// everything queued before it has been written as well
+	marker := &outboundFrame{marker: true, result: make(chan error, 1)}
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+	select {
+	case w.control <- marker:
+	case <-w.done:
+		return
+	case <-timer.C:
+		return
+	}
+	select {
+	case <-marker.result:
+	case <-w.done:
+	case <-timer.C:
+	}
+}
+
+func (w *outboundWriter) stop() {
+	w.once.Do(func() {
+		close(w.done)
+	})
+}
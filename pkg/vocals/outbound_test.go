@@ -0,0 +1,304 @@
+package vocals
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// gatedConn is a TransportConn whose writes wait for the test to open a gate
+type gatedConn struct {
+	gate   chan struct{}
+	mu     sync.Mutex
+	frames [][]byte
+	fail   bool
+	closed bool
+}
+
+func newGatedConn() *gatedConn {
+	return &gatedConn{gate: make(chan struct{})}
+}
+
+func (c *gatedConn) ReadFrame() (FrameType, []byte, error) {
+	select {}
+}
+
+func (c *gatedConn) WriteFrame(frameType FrameType, data []byte) error {
+	<-c.gate
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.fail {
+		return errors.New("write failed")
+	}
+	c.frames = append(c.frames, data)
+	return nil
+}
+
+func (c *gatedConn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	return nil
+}
+
+func (c *gatedConn) written() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	frames := make([]string, len(c.frames))
+	for i, f := range c.frames {
+		frames[i] = string(f)
+	}
+	return frames
+}
+
+// stalledWriter returns a writer whose connection is blocked writing
+// "first", so later audio frames stay queued
+func stalledWriter(t *testing.T, conn *gatedConn, queueSize int, policy string) (*outboundWriter, *outboundCounters) {
+	t.Helper()
+	counters := &outboundCounters{}
+	w := newOutboundWriter(conn, queueSize, policy, 0, counters, false)
+	t.Cleanup(w.stop)
+
+	if err := w.sendAudio(BinaryFrame, []byte("first")); err != nil {
+		t.Fatalf("sendAudio: %v", err)
+	}
+	// Wait until the writer has taken the frame and is blocked writing it
+	for deadline := time.Now().Add(time.Second); len(w.audio) > 0; {
+		if time.Now().After(deadline) {
+			t.Fatal("writer did not pick up the first frame")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return w, counters
+}
+
+func waitForFrames(t *testing.T, conn *gatedConn, count int) []string {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); ; {
+		if frames := conn.written(); len(frames) >= count {
+			return frames
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("wrote %v, want %d frames", conn.written(), count)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestOutboundDropOldest(t *testing.T) {
+	conn := newGatedConn()
+	w, counters := stalledWriter(t, conn, 2, OverflowDropOldest)
+
+	for _, frame := range []string{"a", "b", "c", "d"} {
+		if err := w.sendAudio(BinaryFrame, []byte(frame)); err != nil {
+			t.Fatalf("sendAudio: %v", err)
+		}
+	}
+	close(conn.gate)
+
+	frames := waitForFrames(t, conn, 3)
+	if want := []string{"first", "c", "d"}; !equalStrings(frames, want) {
+		t.Errorf("wrote %v, want %v", frames, want)
+	}
+	if got := counters.droppedOldest.Load(); got != 2 {
+		t.Errorf("dropped %d oldest frames, want 2", got)
+	}
+}
+
+func TestOutboundDropNewest(t *testing.T) {
+	conn := newGatedConn()
+	w, counters := stalledWriter(t, conn, 2, OverflowDropNewest)
+
+	for _, frame := range []string{"a", "b", "c", "d"} {
+		if err := w.sendAudio(BinaryFrame, []byte(frame)); err != nil {
+			t.Fatalf("sendAudio: %v", err)
+		}
+	}
+	close(conn.gate)
+
+	frames := waitForFrames(t, conn, 3)
+	if want := []string{"first", "a", "b"}; !equalStrings(frames, want) {
+		t.Errorf("wrote %v, want %v", frames, want)
+	}
+	if got := counters.droppedNewest.Load(); got != 2 {
+		t.Errorf("dropped %d newest frames, want 2", got)
+	}
+}
+
+func TestOutboundBlockWaitsForRoom(t *testing.T) {
+	conn := newGatedConn()
+	w, counters := stalledWriter(t, conn, 1, OverflowBlock)
+
+	if err := w.sendAudio(BinaryFrame, []byte("a")); err != nil {
+		t.Fatalf("sendAudio: %v", err)
+	}
+	sent := make(chan error, 1)
+	go func() { sent <- w.sendAudio(BinaryFrame, []byte("b")) }()
+
+	select {
+	case <-sent:
+		t.Fatal("sendAudio returned while the queue was full")
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(conn.gate)
+	if err := <-sent; err != nil {
+		t.Fatalf("sendAudio: %v", err)
+	}
+
+	frames := waitForFrames(t, conn, 3)
+	if want := []string{"first", "a", "b"}; !equalStrings(frames, want) {
+		t.Errorf("wrote %v, want %v", frames, want)
+	}
+	if counters.droppedOldest.Load()+counters.droppedNewest.Load() != 0 {
+		t.Error("block policy dropped frames")
+	}
+}
+
+func TestOutboundControlJumpsAudioQueue(t *testing.T) {
+	conn := newGatedConn()
+	w, _ := stalledWriter(t, conn, 4, OverflowBlock)
+
+	for _, frame := range []string{"a", "b"} {
+		if err := w.sendAudio(BinaryFrame, []byte(frame)); err != nil {
+			t.Fatalf("sendAudio: %v", err)
+		}
+	}
+	sent := make(chan error, 1)
+	go func() { sent <- w.sendControl(TextFrame, []byte("control")) }()
+	for deadline := time.Now().Add(time.Second); len(w.control) == 0; {
+		if time.Now().After(deadline) {
+			t.Fatal("control frame was not queued")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(conn.gate)
+	if err := <-sent; err != nil {
+		t.Fatalf("sendControl: %v", err)
+	}
+
+	frames := waitForFrames(t, conn, 4)
+	if want := []string{"first", "control", "a", "b"}; !equalStrings(frames, want) {
+		t.Errorf("wrote %v, want %v", frames, want)
+	}
+}
+
+func TestOutboundWriteErrorClosesConnection(t *testing.T) {
+	conn := newGatedConn()
+	conn.fail = true
+	close(conn.gate)
+	counters := &outboundCounters{}
+	w := newOutboundWriter(conn, 1, OverflowBlock, 0, counters, false)
+	defer w.stop()
+
+	if err := w.sendControl(TextFrame, []byte("x")); err == nil {
+		t.Fatal("sendControl succeeded on a failing connection")
+	}
+	conn.mu.Lock()
+	closed := conn.closed
+	conn.mu.Unlock()
+	if !closed {
+		t.Error("connection left open after a failed write")
+	}
+	if counters.writeErrors.Load() != 1 {
+		t.Errorf("counted %d write errors, want 1", counters.writeErrors.Load())
+	}
+}
+
+// returnsPromptly fails the test when call blocks, as it would behind a
+// lock held across a stalled write
+func returnsPromptly(t *testing.T, what string, call func()) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		call()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("%s blocked behind a stalled write", what)
+	}
+}
+
+func TestStalledStartDoesNotBlockClient(t *testing.T) {
+	client, transport := newPipeClientWithTransport(t, func(config *VocalsConfig) { config.WriteTimeout = 0 })
+	transport.gate = make(chan struct{}) // The peer never reads
+	connected := make(chan error, 1)
+	go func() { connected <- client.Connect() }()
+	<-transport.conns
+
+	returnsPromptly(t, "GetState", func() {
+		if state := client.GetState(); state != Connecting {
+			t.Errorf("state %s while the start event is stalled, want connecting", state)
+		}
+	})
+	returnsPromptly(t, "SendMessage", func() {
+		if err := client.SendMessage(&WebSocketMessage{Event: "ping"}); err == nil {
+			t.Error("SendMessage succeeded before the session started")
+		}
+	})
+	returnsPromptly(t, "Disconnect", client.Disconnect)
+
+	select {
+	case err := <-connected:
+		if err == nil {
+			t.Error("Connect succeeded although the start event was never written")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Connect still waiting on the start event after Disconnect")
+	}
+}
+
+func TestStalledStopDoesNotBlockClient(t *testing.T) {
+	client, conn := newPipeClient(t, func(config *VocalsConfig) { config.WriteTimeout = 0 })
+	conn.next(t, "start")
+	release := conn.stall()
+	defer release()
+
+	disconnected := make(chan struct{})
+	go func() {
+		client.Disconnect()
+		close(disconnected)
+	}()
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(time.Millisecond) {
+		var state ConnectionState
+		returnsPromptly(t, "GetState", func() { state = client.GetState() })
+		if state == Disconnected {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("state %s, want disconnected", state)
+		}
+	}
+	returnsPromptly(t, "IsConnected", func() {
+		if client.IsConnected() {
+			t.Error("IsConnected during Disconnect")
+		}
+	})
+	select {
+	case <-disconnected:
+		t.Fatal("Disconnect returned before the stop event was written")
+	default:
+	}
+
+	release()
+	conn.next(t, "stop")
+	select {
+	case <-disconnected:
+	case <-time.After(time.Second):
+		t.Fatal("Disconnect did not return after the stop event was written")
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
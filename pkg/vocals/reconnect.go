@@ -0,0 +1,123 @@
+package vocals
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// ReconnectPolicy decides how long to wait before each reconnect attempt
+type ReconnectPolicy interface {
+	// NextDelay returns the delay before the given attempt (1-based).
+	// prev is the delay returned for the previous attempt, zero for the first.
+	NextDelay(attempt int, prev time.Duration) time.Duration
+}
+
+// ReconnectPolicyFunc adapts a function to ReconnectPolicy
+type ReconnectPolicyFunc func(attempt int, prev time.Duration) time.Duration
+
+func (f ReconnectPolicyFunc) NextDelay(attempt int, prev time.Duration) time.Duration {
+	return f(attempt, prev)
+}
+
+// ConstantBackoff waits the same delay before every attempt
+type ConstantBackoff struct {
+	Delay time.Duration
+}
+
+func NewConstantBackoff(delay time.Duration) *ConstantBackoff {
+	return &ConstantBackoff{Delay: delay}
+}
+
+func (b *ConstantBackoff) NextDelay(attempt int, prev time.Duration) time.Duration {
+	return b.Delay
+}
+
+// ExponentialBackoff multiplies the delay after every attempt, capped at Max.
+// Jitter (0-1) randomizes each delay by up to that fraction in either direction.
+type ExponentialBackoff struct {
+	Initial    time.Duration
+	Max        time.Duration
+	Multiplier float64
+	Jitter     float64
+}
+
+func NewExponentialBackoff(initial, max time.Duration) *ExponentialBackoff {
+	return &ExponentialBackoff{
+		Initial:    initial,
+		Max:        max,
+		Multiplier: 2.0,
+		Jitter:     0.2,
+	}
+}
+
+func (b *ExponentialBackoff) NextDelay(attempt int, prev time.Duration) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	multiplier := b.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+
+	delay := float64(b.Initial) * math.Pow(multiplier, float64(attempt-1))
+	if b.Max > 0 && delay > float64(b.Max) {
+		delay = float64(b.Max)
+	}
+	if b.Jitter > 0 {
+		delay += delay * b.Jitter * (2*rand.Float64() - 1)
+	}
+	if delay < 0 {
+		delay = 0
+	}
+	return time.Duration(delay)
+}
+
+// DecorrelatedJitterBackoff picks each delay uniformly between Base and three
+// times the previous delay, capped at Max. It spreads out clients that lost
+// their connection at the same moment better than plain exponential backoff.
+type DecorrelatedJitterBackoff struct {
+	Base time.Duration
+	Max  time.Duration
+}
+
+func NewDecorrelatedJitterBackoff(base, max time.Duration) *DecorrelatedJitterBackoff {
+	return &DecorrelatedJitterBackoff{Base: base, Max: max}
+}
+
+func (b *DecorrelatedJitterBackoff) NextDelay(attempt int, prev time.Duration) time.Duration {
+	if prev < b.Base {
+		prev = b.Base
+	}
+
+	upper := prev * 3
+	delay := b.Base
+	if upper > b.Base {
+		delay += time.Duration(rand.Int63n(int64(upper - b.Base)))
+	}
+	if b.Max > 0 && delay > b.Max {
+		delay = b.Max
+	}
+	return delay
+}
+
+// ReconnectEvent describes a failed connection attempt and the next retry
+type ReconnectEvent struct {
+	Attempt     int           // Number of the attempt that just failed (1-based)
+	MaxAttempts int           // Configured attempt limit
+	NextDelay   time.Duration // Delay before the next attempt
+	Elapsed     time.Duration // Time spent reconnecting so far
+	Err         error         // Error from the failed attempt
+	GivingUp    bool          // True when no further attempt will be made
+}
+
+func (e ReconnectEvent) String() string {
+	if e.GivingUp {
+		return fmt.Sprintf("giving up after %d attempts: %v", e.Attempt, e.Err)
+	}
+	return fmt.Sprintf("reconnecting in %s (attempt %d/%d)", e.NextDelay.Round(time.Millisecond), e.Attempt+1, e.MaxAttempts)
+}
+
+// ReconnectHandler is called after every failed connection attempt
+type ReconnectHandler func(ReconnectEvent)
@@ -0,0 +1,82 @@
+package vocals_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rojolang/vocals-sdk-go/pkg/vocals"
+)
+
+func TestReconnectRetriesRejectedConnections(t *testing.T) {
+	srv := newTestServer(t)
+	client := connectClient(t, srv.Config())
+	attempts := make(chan vocals.ReconnectEvent, 10)
+	client.AddReconnectHandler(func(event vocals.ReconnectEvent) { attempts <- event })
+	resumed := make(chan vocals.ResumeEvent, 1)
+	client.AddResumeHandler(func(event vocals.ResumeEvent) { resumed <- event })
+
+	srv.RejectConnections(2)
+	srv.DisconnectAll()
+
+	if event := waitFor(t, resumed, "resume"); !event.Success {
+		t.Fatalf("resume failed: %v", event.Err)
+	}
+	// Reconnect and resume handlers run independently, so the attempts may
+	// still be on their way
+	for i := 1; i <= 2; i++ {
+		if event := waitFor(t, attempts, "a failed attempt"); event.Attempt != i || event.GivingUp {
+			t.Errorf("failed attempt %d reported as %+v", i, event)
+		}
+	}
+}
+
+func TestReconnectGivesUp(t *testing.T) {
+	srv := newTestServer(t)
+	config := srv.Config()
+	config.MaxReconnectAttempts = 2
+	client := connectClient(t, config)
+	attempts := make(chan vocals.ReconnectEvent, 10)
+	client.AddReconnectHandler(func(event vocals.ReconnectEvent) { attempts <- event })
+	resumed := make(chan vocals.ResumeEvent, 1)
+	client.AddResumeHandler(func(event vocals.ResumeEvent) { resumed <- event })
+
+	srv.RejectConnections(100)
+	srv.DisconnectAll()
+
+	if event := waitFor(t, resumed, "resume"); event.Success {
+		t.Fatal("resumed while every connection was rejected")
+	}
+	waitFor(t, attempts, "the first failed attempt")
+	if last := waitFor(t, attempts, "the last failed attempt"); !last.GivingUp || last.Attempt != 2 {
+		t.Errorf("last reconnect event = %+v, want giving up after 2 attempts", last)
+	}
+	if state := client.GetState(); state != vocals.ErrorState {
+		t.Errorf("state = %s, want error", state)
+	}
+}
+
+func TestDisconnectDuringBackoff(t *testing.T) {
+	srv := newTestServer(t)
+	config := srv.Config()
+	config.ReconnectDelay = 30
+	client := connectClient(t, config)
+	attempts := make(chan vocals.ReconnectEvent, 10)
+	client.AddReconnectHandler(func(event vocals.ReconnectEvent) { attempts <- event })
+
+	srv.RejectConnections(100)
+	srv.DisconnectAll()
+	waitFor(t, attempts, "a failed attempt")
+
+	// The client is waiting out the backoff and must stay responsive
+	start := time.Now()
+	if client.IsConnected() {
+		t.Error("connected during an outage")
+	}
+	client.Disconnect()
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Disconnect during backoff took %s", elapsed)
+	}
+	if state := client.GetState(); state != vocals.Disconnected {
+		t.Errorf("state = %s, want disconnected", state)
+	}
+}
@@ -0,0 +1,245 @@
+package vocals
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Capture record kinds
+const (
+	CaptureOpen     = "open"  // A connection was established
+	CaptureInbound  = "in"    // A frame received from the server
+	CaptureOutbound = "out"   // A frame written to the server
+	CaptureClose    = "close" // The connection was closed or failed
+)
+
+// CapturedFrame is one line of a session capture. Offset is measured on the
+// monotonic clock from the start of the recording, so it is unaffected by
+// wall clock changes; Time is the wall clock time for reference.
+type CapturedFrame struct {
+	Offset    time.Duration `json:"offset_ns"`
+	Time      time.Time     `json:"time"`
+	Conn      int           `json:"conn"` // Connection number, starting at 0
+	Direction string        `json:"dir"`  // CaptureOpen, CaptureInbound, CaptureOutbound or CaptureClose
+	Text      string        `json:"text,omitempty"`
+	Binary    []byte        `json:"binary,omitempty"`
+	Error     string        `json:"error,omitempty"` // Why the connection closed
+}
+
+// Recorder writes captured frames as JSON lines. Every line is flushed as it
+// is written so a capture survives a crash.
+type Recorder struct {
+	writer *bufio.Writer
+	gz     *gzip.Writer
+	closer io.Closer
+	start  time.Time
+	conns  int
+	closed bool
+	mu     sync.Mutex
+}
+
+// NewRecorder creates a recorder writing JSONL to w
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{writer: bufio.NewWriter(w), start: time.Now()}
+}
+
+// CreateRecorder creates a recorder writing to path. Paths ending in .gz are
+// written as a gzip archive.
+func CreateRecorder(path string) (*Recorder, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, NewVocalsError(fmt.Sprintf("Failed to create capture file: %v", err), "RECORDER_ERROR")
+	}
+
+	if strings.HasSuffix(path, ".gz") {
+		gz := gzip.NewWriter(file)
+		r := NewRecorder(gz)
+		r.gz = gz
+		r.closer = file
+		return r, nil
+	}
+
+	r := NewRecorder(file)
+	r.closer = file
+	return r, nil
+}
+
+// nextConn returns the number of the next recorded connection
+func (r *Recorder) nextConn() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	conn := r.conns
+	r.conns++
+	return conn
+}
+
+func (r *Recorder) record(frame CapturedFrame) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return
+	}
+
+	now := time.Now()
+	frame.Offset = now.Sub(r.start)
+	frame.Time = now
+
+	data, err := json.Marshal(frame)
+	if err == nil {
+		r.writer.Write(data)
+		r.writer.WriteByte('\n')
+		err = r.writer.Flush()
+	}
+	if err == nil && r.gz != nil {
+		err = r.gz.Flush()
+	}
+	if err != nil {
+		log.Printf("Failed to record frame: %v", err)
+	}
+}
+
+// Close flushes the capture and closes the underlying file, if the recorder
+// opened one
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+
+	err := r.writer.Flush()
+	if r.gz != nil {
+		if gzErr := r.gz.Close(); err == nil {
+			err = gzErr
+		}
+	}
+	if r.closer != nil {
+		if closeErr := r.closer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// RecordingTransport wraps a Transport and records every frame sent and
+// received on its connections. Pings and pongs are not recorded.
+type RecordingTransport struct {
+	transport Transport
+	recorder  *Recorder
+}
+
+// NewRecordingTransport records the connections of transport, or of the
+// default gorilla/websocket transport when transport is nil
+func NewRecordingTransport(transport Transport, recorder *Recorder) *RecordingTransport {
+	if transport == nil {
+		transport = NewGorillaTransport()
+	}
+	return &RecordingTransport{transport: transport, recorder: recorder}
+}
+
+func (t *RecordingTransport) Dial(ctx context.Context, endpoint string, header http.Header) (TransportConn, error) {
+	conn, err := t.transport.Dial(ctx, endpoint, header)
+	if err != nil {
+		return nil, err
+	}
+
+	rc := &recordingConn{conn: conn, recorder: t.recorder, id: t.recorder.nextConn()}
+	rc.recorder.record(CapturedFrame{Conn: rc.id, Direction: CaptureOpen})
+
+	// Keep keepalive and write deadlines working when the wrapped
+	// connection supports them
+	_, pings := conn.(PingConn)
+	_, deadlines := conn.(DeadlineConn)
+	if pings && deadlines {
+		return &recordingKeepaliveConn{rc}, nil
+	}
+	return rc, nil
+}
+
+type recordingConn struct {
+	conn      TransportConn
+	recorder  *Recorder
+	id        int
+	closeOnce sync.Once
+}
+
+func (c *recordingConn) ReadFrame() (FrameType, []byte, error) {
+	frameType, data, err := c.conn.ReadFrame()
+	if err != nil {
+		c.recordClose(err)
+		return frameType, data, err
+	}
+	c.recorder.record(capturedData(c.id, CaptureInbound, frameType, data))
+	return frameType, data, nil
+}
+
+func (c *recordingConn) WriteFrame(frameType FrameType, data []byte) error {
+	if err := c.conn.WriteFrame(frameType, data); err != nil {
+		return err
+	}
+	c.recorder.record(capturedData(c.id, CaptureOutbound, frameType, data))
+	return nil
+}
+
+func (c *recordingConn) Close() error {
+	c.recordClose(nil)
+	return c.conn.Close()
+}
+
+func (c *recordingConn) recordClose(err error) {
+	c.closeOnce.Do(func() {
+		frame := CapturedFrame{Conn: c.id, Direction: CaptureClose}
+		if err != nil {
+			frame.Error = err.Error()
+		}
+		c.recorder.record(frame)
+	})
+}
+
+func capturedData(conn int, direction string, frameType FrameType, data []byte) CapturedFrame {
+	frame := CapturedFrame{Conn: conn, Direction: direction}
+	if frameType == BinaryFrame {
+		frame.Binary = append([]byte(nil), data...)
+	} else {
+		frame.Text = string(data)
+	}
+	return frame
+}
+
+// recordingKeepaliveConn also forwards pings and write deadlines
+type recordingKeepaliveConn struct {
+	*recordingConn
+}
+
+func (c *recordingKeepaliveConn) WritePing(data []byte, deadline time.Time) error {
+	return c.conn.(PingConn).WritePing(data, deadline)
+}
+
+func (c *recordingKeepaliveConn) SetPongHandler(handler func(data []byte)) {
+	c.conn.(PingConn).SetPongHandler(handler)
+}
+
+func (c *recordingKeepaliveConn) SetWriteDeadline(t time.Time) error {
+	return c.conn.(DeadlineConn).SetWriteDeadline(t)
+}
+
+// closeRecorder finishes the capture configured with RecordPath
+func (wsc *WebSocketClient) closeRecorder() {
+	if wsc.recorder == nil {
+		return
+	}
+	if err := wsc.recorder.Close(); err != nil {
+		log.Printf("Failed to close session capture: %v", err)
+	}
+}
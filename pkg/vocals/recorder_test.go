@@ -0,0 +1,130 @@
+package vocals_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rojolang/vocals-sdk-go/pkg/vocals"
+	"github.com/rojolang/vocals-sdk-go/pkg/vocalstest"
+)
+
+// recordSession records a session with two connections and a 300ms pause
+// between the two responses
+func recordSession(t *testing.T, path string) {
+	t.Helper()
+	srv := newTestServer(t)
+	config := srv.Config()
+	config.RecordPath = path
+	config.ReconnectDelay = 0.01
+	client := vocals.NewVocalsClient(config, vocals.NewAudioConfig(), nil, []string{"transcription"})
+	defer client.Cleanup()
+	responses := make(chan vocals.ResponseEvent, 10)
+	vocals.On(client, func(e vocals.ResponseEvent) { responses <- e })
+	resumed := make(chan vocals.ResumeEvent, 1)
+	client.AddResumeHandler(func(event vocals.ResumeEvent) { resumed <- event })
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+
+	if err := srv.Send(vocalstest.Response("first")); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, responses, "the first response")
+	srv.DisconnectAll()
+	if event := waitFor(t, resumed, "resume"); !event.Success {
+		t.Fatalf("resume failed: %v", event.Err)
+	}
+	time.Sleep(300 * time.Millisecond)
+	if err := srv.Send(vocalstest.Response("second")); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, responses, "the second response")
+}
+
+func TestRecorderCapturesBothDirections(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+	recordSession(t, path)
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	counts := map[string]map[int]int{}
+	var last time.Duration
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var frame vocals.CapturedFrame
+		if err := json.Unmarshal(scanner.Bytes(), &frame); err != nil {
+			t.Fatalf("invalid capture line %q: %v", scanner.Text(), err)
+		}
+		if frame.Offset < last {
+			t.Errorf("offset %s follows %s", frame.Offset, last)
+		}
+		last = frame.Offset
+		if counts[frame.Direction] == nil {
+			counts[frame.Direction] = map[int]int{}
+		}
+		counts[frame.Direction][frame.Conn]++
+	}
+
+	for _, conn := range []int{0, 1} {
+		for _, direction := range []string{vocals.CaptureOpen, vocals.CaptureInbound, vocals.CaptureOutbound} {
+			if counts[direction][conn] == 0 {
+				t.Errorf("no %q records for connection %d", direction, conn)
+			}
+		}
+	}
+	if counts[vocals.CaptureClose][0] != 1 {
+		t.Errorf("%d close records for the dropped connection, want 1", counts[vocals.CaptureClose][0])
+	}
+}
+
+func TestReplayDeliversRecordedSession(t *testing.T) {
+	for _, name := range []string{"session.jsonl", "session.jsonl.gz"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			recordSession(t, path)
+
+			for _, tt := range []struct {
+				speed          float64
+				minGap, maxGap time.Duration
+			}{
+				{speed: 1, minGap: 250 * time.Millisecond, maxGap: 2 * time.Second},
+				{speed: 0, maxGap: 150 * time.Millisecond},
+			} {
+				replay, err := vocals.LoadReplay(path, tt.speed)
+				if err != nil {
+					t.Fatalf("LoadReplay: %v", err)
+				}
+				config := replay.Config()
+				config.ReconnectDelay = 0.01
+				client := vocals.NewVocalsClient(config, vocals.NewAudioConfig(), nil, []string{"transcription"})
+				responses := make(chan vocals.ResponseEvent, 10)
+				vocals.On(client, func(e vocals.ResponseEvent) { responses <- e })
+				if err := client.Connect(); err != nil {
+					t.Fatalf("Connect: %v", err)
+				}
+
+				// The recorded disconnect is replayed, so the second response
+				// arrives on a new connection
+				if e := waitFor(t, responses, "the first response"); e.Text != "first" {
+					t.Errorf("first response = %q", e.Text)
+				}
+				start := time.Now()
+				if e := waitFor(t, responses, "the second response"); e.Text != "second" {
+					t.Errorf("second response = %q", e.Text)
+				}
+				if gap := time.Since(start); gap < tt.minGap || gap > tt.maxGap {
+					t.Errorf("at speed %v the responses were %s apart, want %s to %s", tt.speed, gap, tt.minGap, tt.maxGap)
+				}
+				waitFor(t, replay.Done(), "the end of the replay")
+				client.Cleanup()
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package vocals
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// Replay is a Transport that plays the server side of a session capture
+// back to a client without a network. Each Dial replays the next recorded
+// connection: inbound frames arrive with their recorded spacing divided by
+// the speed, outbound frames are discarded, and a recorded close ends the
+// connection so the client reconnects as it did originally. The last
+// connection stays open once its frames are delivered and Done is closed.
+type Replay struct {
+	conns    [][]CapturedFrame // Open, inbound and close records per connection
+	speed    float64
+	next     int
+	done     chan struct{}
+	doneOnce sync.Once
+	mu       sync.Mutex
+}
+
+// NewReplay reads a capture written by a Recorder, plain or gzipped. A speed
+// of 1 keeps the original timing, 4 plays four times faster and 0 delivers
+// frames as fast as the client reads them.
+func NewReplay(r io.Reader, speed float64) (*Replay, error) {
+	reader := bufio.NewReader(r)
+	if magic, err := reader.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(reader)
+		if err != nil {
+			return nil, NewVocalsError(fmt.Sprintf("Failed to open capture archive: %v", err), "REPLAY_ERROR")
+		}
+		defer gz.Close()
+		reader = bufio.NewReader(gz)
+	}
+
+	replay := &Replay{speed: speed, done: make(chan struct{})}
+	decoder := json.NewDecoder(reader)
+	for {
+		var frame CapturedFrame
+		if err := decoder.Decode(&frame); err != nil {
+			if err == io.EOF || errors.Is(err, io.ErrUnexpectedEOF) {
+				// A capture that was not closed cleanly ends mid-line
+				break
+			}
+			return nil, NewVocalsError(fmt.Sprintf("Invalid capture: %v", err), "REPLAY_ERROR")
+		}
+		if frame.Direction == CaptureOutbound || frame.Conn < 0 {
+			continue
+		}
+		for len(replay.conns) <= frame.Conn {
+			replay.conns = append(replay.conns, nil)
+		}
+		replay.conns[frame.Conn] = append(replay.conns[frame.Conn], frame)
+	}
+
+	if len(replay.conns) == 0 {
+		return nil, NewVocalsError("Capture contains no connections", "REPLAY_ERROR")
+	}
+	return replay, nil
+}
+
+// LoadReplay reads the capture at path
+func LoadReplay(path string, speed float64) (*Replay, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, NewVocalsError(fmt.Sprintf("Failed to open capture: %v", err), "REPLAY_ERROR")
+	}
+	defer file.Close()
+	return NewReplay(file, speed)
+}
+
+// Config returns a VocalsConfig that connects to the replay with token auth
+// disabled
+func (r *Replay) Config() *VocalsConfig {
+	config := NewVocalsConfig()
+	endpoint := "replay://capture"
+	config.WsEndpoint = &endpoint
+	config.TokenEndpoint = nil
+	config.UseTokenAuth = false
+	config.Transport = r
+	config.RecordPath = ""
+	return config
+}
+
+// Done is closed once every recorded inbound frame has been delivered
+func (r *Replay) Done() <-chan struct{} {
+	return r.done
+}
+
+func (r *Replay) Dial(ctx context.Context, endpoint string, header http.Header) (TransportConn, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.next >= len(r.conns) {
+		return nil, NewVocalsError("No recorded connections left to replay", "REPLAY_ERROR")
+	}
+	frames := r.conns[r.next]
+	r.next++
+
+	conn := &replayConn{
+		replay: r,
+		frames: frames,
+		last:   r.next == len(r.conns),
+		start:  time.Now(),
+		closed: make(chan struct{}),
+	}
+	if len(frames) > 0 {
+		conn.origin = frames[0].Offset
+		if frames[0].Direction == CaptureOpen {
+			conn.frames = frames[1:]
+		}
+	}
+	return conn, nil
+}
+
+type replayConn struct {
+	replay    *Replay
+	frames    []CapturedFrame
+	last      bool          // The final recorded connection
+	origin    time.Duration // Recorded offset of the connection's start
+	start     time.Time
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+var errReplayConnClosed = errors.New("replay connection closed")
+
+func (c *replayConn) ReadFrame() (FrameType, []byte, error) {
+	for len(c.frames) > 0 {
+		frame := c.frames[0]
+		c.frames = c.frames[1:]
+		if frame.Direction == CaptureClose && c.last {
+			continue
+		}
+
+		if c.replay.speed > 0 {
+			due := c.start.Add(time.Duration(float64(frame.Offset-c.origin) / c.replay.speed))
+			timer := time.NewTimer(time.Until(due))
+			select {
+			case <-timer.C:
+			case <-c.closed:
+				timer.Stop()
+				return 0, nil, errReplayConnClosed
+			}
+		}
+
+		switch frame.Direction {
+		case CaptureInbound:
+			if frame.Binary != nil {
+				return BinaryFrame, frame.Binary, nil
+			}
+			return TextFrame, []byte(frame.Text), nil
+		case CaptureClose:
+			if frame.Error != "" {
+				return 0, nil, fmt.Errorf("replayed connection failed: %s", frame.Error)
+			}
+			return 0, nil, io.EOF
+		}
+	}
+
+	// Everything was delivered; stay open like an idle server
+	if c.last {
+		c.replay.doneOnce.Do(func() { close(c.replay.done) })
+	}
+	<-c.closed
+	return 0, nil, errReplayConnClosed
+}
+
+func (c *replayConn) WriteFrame(frameType FrameType, data []byte) error {
+	select {
+	case <-c.closed:
+		return errReplayConnClosed
+	default:
+		return nil
+	}
+}
+
+func (c *replayConn) Close() error {
+	c.closeOnce.Do(func() { close(c.closed) })
+	return nil
+}
@@ -0,0 +1,40 @@
+package vocals
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAudioRingBufferKeepsCaptureTimes(t *testing.T) {
+	// Ten samples per second, so each sample lasts 100ms
+	rb := newAudioRingBuffer(5, 10)
+	start := time.Unix(1000, 0)
+	rb.Write([]float32{1, 2, 3}, start)
+	rb.Write([]float32{4, 5, 6}, start.Add(300*time.Millisecond))
+
+	blocks := rb.Snapshot()
+	if len(blocks) != 2 {
+		t.Fatalf("got %d blocks, want 2", len(blocks))
+	}
+	// The oldest sample was dropped, so the first block starts one sample later
+	if got := blocks[0].samples; len(got) != 2 || got[0] != 2 {
+		t.Errorf("first block = %v, want [2 3]", got)
+	}
+	if want := start.Add(100 * time.Millisecond); !blocks[0].capturedAt.Equal(want) {
+		t.Errorf("first block captured at %v, want %v", blocks[0].capturedAt, want)
+	}
+	if want := start.Add(500 * time.Millisecond); !blocks[1].after(2, 10).Equal(want) {
+		t.Errorf("last sample captured at %v, want %v", blocks[1].after(2, 10), want)
+	}
+
+	rb.Write([]float32{7, 8, 9, 10, 11, 12}, start.Add(time.Second))
+	blocks = rb.Snapshot()
+	if len(blocks) != 1 || len(blocks[0].samples) != 5 || !blocks[0].capturedAt.Equal(start.Add(1100*time.Millisecond)) {
+		t.Errorf("after an oversized write got %+v, want its newest 5 samples", blocks)
+	}
+
+	rb.Reset()
+	if blocks := rb.Snapshot(); len(blocks) != 0 {
+		t.Errorf("got %d blocks after Reset, want none", len(blocks))
+	}
+}
@@ -0,0 +1,124 @@
+package vocals
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+)
+
+// NewMessageID returns a random identifier for an outbound message
+func NewMessageID() string {
+	var b [12]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// assignMessageID gives message an ID unless it already has one. Media
+// events are left alone since nothing replies to them.
+func assignMessageID(message *WebSocketMessage) {
+	if message.ID == "" && message.Event != "media" {
+		message.ID = NewMessageID()
+	}
+}
+
+// pendingRequests routes replies to the Request calls waiting for them
+type pendingRequests struct {
+	waiters map[string]chan *WebSocketResponse
+	mu      sync.Mutex
+}
+
+func (p *pendingRequests) register(id string) chan *WebSocketResponse {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.waiters == nil {
+		p.waiters = make(map[string]chan *WebSocketResponse)
+	}
+	reply := make(chan *WebSocketResponse, 1)
+	p.waiters[id] = reply
+	return reply
+}
+
+func (p *pendingRequests) remove(id string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.waiters, id)
+}
+
+// resolve hands message to the request it replies to. The first reply
+// completes the request; later replies with the same ID only reach handlers.
+func (p *pendingRequests) resolve(message *WebSocketResponse) {
+	if message.ID == "" {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if reply, ok := p.waiters[message.ID]; ok {
+		reply <- message
+		delete(p.waiters, message.ID)
+	}
+}
+
+// abort fails every waiting request
+func (p *pendingRequests) abort() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for id, reply := range p.waiters {
+		close(reply)
+		delete(p.waiters, id)
+	}
+}
+
+// Request sends message and waits for the server reply carrying its ID.
+// The message is given an ID if it has none, and the wait uses the ID the
+// outbound middlewares leave on it. Unless ctx already has a
+// deadline the wait is bounded by RequestTimeout; on timeout or
+// cancellation ctx.Err() is returned. A server error reply is returned
+// together with the error it describes. Replies are still delivered to the
+// message and event handlers.
+func (wsc *WebSocketClient) Request(ctx context.Context, message *WebSocketMessage) (*WebSocketResponse, error) {
+	assignMessageID(message)
+	event := message.Event
+	message, err := wsc.applyOutbound(message)
+	if err != nil {
+		return nil, err
+	}
+	if message == nil {
+		return nil, NewVocalsError(fmt.Sprintf("Outbound %s request dropped by middleware", event), "MIDDLEWARE_REJECTED").
+			AddDetail("event", event)
+	}
+	id := message.ID
+	if id == "" {
+		return nil, NewVocalsError(fmt.Sprintf("Outbound %s request lost its ID in middleware", event), "MIDDLEWARE_REJECTED").
+			AddDetail("event", event)
+	}
+
+	if _, ok := ctx.Deadline(); !ok && wsc.config.RequestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, secondsToDuration(wsc.config.RequestTimeout))
+		defer cancel()
+	}
+
+	reply := wsc.requests.register(id)
+	defer wsc.requests.remove(id)
+
+	if err := wsc.sendProcessed(message); err != nil {
+		return nil, err
+	}
+
+	select {
+	case response, ok := <-reply:
+		if !ok {
+			return nil, NewVocalsError(fmt.Sprintf("Connection closed before %s request %s was answered", message.Event, id), "REQUEST_ABORTED")
+		}
+		if event, err := response.Decode(); err == nil {
+			if serverErr, ok := event.(ServerErrorEvent); ok {
+				return response, serverErr.VocalsError()
+			}
+		}
+		return response, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
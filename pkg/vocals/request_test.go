@@ -0,0 +1,117 @@
+package vocals_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rojolang/vocals-sdk-go/pkg/vocals"
+	"github.com/rojolang/vocals-sdk-go/pkg/vocalstest"
+)
+
+type requestResult struct {
+	response *vocals.WebSocketResponse
+	err      error
+}
+
+func startRequest(client *vocals.WebSocketClient, message *vocals.WebSocketMessage) <-chan requestResult {
+	done := make(chan requestResult, 1)
+	go func() {
+		response, err := client.Request(context.Background(), message)
+		done <- requestResult{response, err}
+	}()
+	return done
+}
+
+func responseText(t *testing.T, result requestResult) string {
+	t.Helper()
+	if result.err != nil {
+		t.Fatalf("Request: %v", result.err)
+	}
+	event, err := result.response.Decode()
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	response, ok := event.(vocals.ResponseEvent)
+	if !ok {
+		t.Fatalf("reply is %T, want ResponseEvent", event)
+	}
+	return response.Text
+}
+
+func TestRequestMatchesRepliesByID(t *testing.T) {
+	srv := newTestServer(t)
+	client := connectClient(t, srv.Config())
+
+	first := startRequest(client, &vocals.WebSocketMessage{Event: "ask", Data: "first"})
+	second := startRequest(client, &vocals.WebSocketMessage{Event: "ask", Data: "second"})
+	frames, err := srv.WaitForEvent("ask", 2, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := map[string]string{}
+	for _, frame := range frames {
+		var data string
+		if err := frame.DecodeData(&data); err != nil {
+			t.Fatalf("decoding request: %v", err)
+		}
+		ids[data] = frame.ID
+	}
+
+	// Replies arrive out of order, after one that answers neither request
+	for _, reply := range []vocalstest.Reply{
+		vocalstest.Response("unrelated"),
+		vocalstest.Response("to second").To(ids["second"]),
+		vocalstest.Response("to first").To(ids["first"]),
+	} {
+		if err := srv.Send(reply); err != nil {
+			t.Fatalf("Send: %v", err)
+		}
+	}
+
+	if text := responseText(t, waitFor(t, first, "the first reply")); text != "to first" {
+		t.Errorf("first request got %q", text)
+	}
+	if text := responseText(t, waitFor(t, second, "the second reply")); text != "to second" {
+		t.Errorf("second request got %q", text)
+	}
+}
+
+func TestRequestReturnsServerErrors(t *testing.T) {
+	srv := newTestServer(t)
+	srv.On("ask", vocalstest.Error("BAD_PROMPT", "prompt is empty"))
+	client := connectClient(t, srv.Config())
+
+	response, err := client.Request(context.Background(), &vocals.WebSocketMessage{Event: "ask"})
+	var vErr *vocals.VocalsError
+	if !errors.As(err, &vErr) || vErr.Code != "BAD_PROMPT" {
+		t.Fatalf("Request error = %v, want BAD_PROMPT", err)
+	}
+	if response == nil {
+		t.Error("error reply not returned")
+	}
+}
+
+func TestRequestTimesOut(t *testing.T) {
+	srv := newTestServer(t)
+	config := srv.Config()
+	config.RequestTimeout = 0.05
+	client := connectClient(t, config)
+
+	if _, err := client.Request(context.Background(), &vocals.WebSocketMessage{Event: "ask"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Request = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestRequestAbortedWhenConnectionDrops(t *testing.T) {
+	srv := newTestServer(t)
+	srv.DisconnectAfter("ask", 1)
+	client := connectClient(t, srv.Config())
+
+	_, err := client.Request(context.Background(), &vocals.WebSocketMessage{Event: "ask"})
+	var vErr *vocals.VocalsError
+	if !errors.As(err, &vErr) || vErr.Code != "REQUEST_ABORTED" {
+		t.Errorf("Request = %v, want REQUEST_ABORTED", err)
+	}
+}
@@ -0,0 +1,260 @@
+package vocals
+
+import (
+	"fmt"
+	"math"
+)
+
+// Resampling quality levels. Higher levels keep more of the passband and
+// reject aliasing better at the cost of CPU.
+const (
+	ResampleQualityLow    = "low"    // 16 taps, about 60 dB stopband
+	ResampleQualityMedium = "medium" // 32 taps, about 80 dB stopband
+	ResampleQualityHigh   = "high"   // 64 taps, about 100 dB stopband
+)
+
+// resampleProfile holds the filter design of a quality level
+type resampleProfile struct {
+	halfTaps int     // Taps on each side of the output sample
+	beta     float64 // Kaiser window shape
+	rolloff  float64 // Cutoff as a fraction of the lower Nyquist frequency
+}
+
+var resampleProfiles = map[string]resampleProfile{
+	ResampleQualityLow:    {halfTaps: 8, beta: 6, rolloff: 0.85},
+	ResampleQualityMedium: {halfTaps: 16, beta: 8, rolloff: 0.9},
+	ResampleQualityHigh:   {halfTaps: 32, beta: 10, rolloff: 0.94},
+}
+
+// maxResamplePhases bounds the filter table for ratios such as
+// 44100:16001; outputs between two phases interpolate them
+const maxResamplePhases = 1024
+
+// Resampler converts interleaved float32 audio between sample rates with a
+// polyphase windowed-sinc filter. It keeps the filter history between
+// calls, so a stream can be converted frame by frame without artifacts at
+// frame boundaries. A Resampler is not safe for concurrent use.
+type Resampler struct {
+	inputRate  int
+	outputRate int
+	channels   int
+	up, down   int // outputRate:inputRate in lowest terms
+	halfTaps   int
+	phases     int
+	filters    [][]float32 // phases+1 rows of 2*halfTaps coefficients
+	history    [][]float32 // Per channel input the filter still needs
+	pos        int         // Index in history of the input the next output is aligned to
+	phase      int         // Offset of the next output past pos, in 1/up input samples
+	consumed   int64       // Input frames accepted since the last reset
+	produced   int64       // Output frames returned since the last reset
+}
+
+// NewResampler converts interleaved audio with channels channels from
+// inputRate to outputRate. quality is one of the ResampleQuality levels;
+// empty selects ResampleQualityMedium.
+func NewResampler(inputRate, outputRate, channels int, quality string) (*Resampler, error) {
+	if inputRate <= 0 || outputRate <= 0 {
+		return nil, NewVocalsError(fmt.Sprintf("Invalid sample rates %d -> %d", inputRate, outputRate), "INVALID_SAMPLE_RATE")
+	}
+	if channels <= 0 {
+		return nil, NewVocalsError("Invalid channel count", "INVALID_CHANNELS")
+	}
+	if quality == "" {
+		quality = ResampleQualityMedium
+	}
+	profile, ok := resampleProfiles[quality]
+	if !ok {
+		return nil, NewVocalsError(fmt.Sprintf("Unknown resample quality %q", quality), "INVALID_RESAMPLE_QUALITY")
+	}
+
+	divisor := gcd(inputRate, outputRate)
+	r := &Resampler{
+		inputRate:  inputRate,
+		outputRate: outputRate,
+		channels:   channels,
+		up:         outputRate / divisor,
+		down:       inputRate / divisor,
+	}
+
+	// Downsampling lowers the cutoff below the input Nyquist frequency, so
+	// the filter widens to keep the same transition band
+	scale := math.Min(1, float64(r.up)/float64(r.down))
+	r.halfTaps = int(math.Ceil(float64(profile.halfTaps) / scale))
+	r.phases = min(r.up, maxResamplePhases)
+	r.filters = designResampleFilters(r.phases, r.halfTaps, profile.rolloff*scale, profile.beta)
+	r.history = make([][]float32, channels)
+	r.Reset()
+	return r, nil
+}
+
+// designResampleFilters returns phases+1 Kaiser-windowed sinc filters, row
+// p delaying the input by p/phases of a sample. Each row has unity gain at
+// DC.
+func designResampleFilters(phases, halfTaps int, cutoff, beta float64) [][]float32 {
+	filters := make([][]float32, phases+1)
+	norm := besselI0(beta)
+	for p := range filters {
+		offset := float64(p) / float64(phases)
+		coeffs := make([]float64, 2*halfTaps)
+		sum := 0.0
+		for k := range coeffs {
+			d := float64(k-halfTaps+1) - offset
+			x := d / float64(halfTaps)
+			window := besselI0(beta*math.Sqrt(math.Max(0, 1-x*x))) / norm
+			coeffs[k] = cutoff * sinc(cutoff*d) * window
+			sum += coeffs[k]
+		}
+
+		row := make([]float32, len(coeffs))
+		for k, c := range coeffs {
+			row[k] = float32(c / sum)
+		}
+		filters[p] = row
+	}
+	return filters
+}
+
+// Process converts a block of interleaved samples and returns the output
+// that is ready. The filter looks ahead halfTaps input samples, so that much
+// output is held back until later input or Flush arrives. A trailing
+// partial frame is ignored.
+func (r *Resampler) Process(samples []float32) []float32 {
+	frames := len(samples) / r.channels
+	if r.up == r.down {
+		r.consumed += int64(frames)
+		r.produced += int64(frames)
+		return append([]float32(nil), samples[:frames*r.channels]...)
+	}
+
+	for f := 0; f < frames; f++ {
+		for c := range r.history {
+			r.history[c] = append(r.history[c], samples[f*r.channels+c])
+		}
+	}
+	r.consumed += int64(frames)
+	return r.drain(-1)
+}
+
+// Flush returns the output held back for look-ahead, so the total output
+// matches the input duration, and resets the resampler for a new stream
+func (r *Resampler) Flush() []float32 {
+	if r.up == r.down {
+		r.Reset()
+		return nil
+	}
+
+	for c := range r.history {
+		r.history[c] = append(r.history[c], make([]float32, r.halfTaps)...)
+	}
+	total := (r.consumed*int64(r.up) + int64(r.down) - 1) / int64(r.down)
+	out := r.drain(total)
+	r.Reset()
+	return out
+}
+
+// Reset discards the filter history, as at the start of a new stream
+func (r *Resampler) Reset() {
+	for c := range r.history {
+		// Silence before the first sample keeps the output aligned with
+		// the input
+		r.history[c] = make([]float32, r.halfTaps-1, 4*r.halfTaps)
+	}
+	r.pos = r.halfTaps - 1
+	r.phase = 0
+	r.consumed = 0
+	r.produced = 0
+}
+
+// InputRate returns the sample rate Process expects
+func (r *Resampler) InputRate() int {
+	return r.inputRate
+}
+
+// OutputRate returns the sample rate Process returns
+func (r *Resampler) OutputRate() int {
+	return r.outputRate
+}
+
+// drain computes every output the history covers, stopping once limit
+// output frames were produced when limit is not negative
+func (r *Resampler) drain(limit int64) []float32 {
+	var out []float32
+	taps := 2 * r.halfTaps
+	for r.pos+r.halfTaps < len(r.history[0]) {
+		if limit >= 0 && r.produced >= limit {
+			break
+		}
+
+		position := r.phase * r.phases
+		row, rem := position/r.up, position%r.up
+		start := r.pos - r.halfTaps + 1
+		for c := range r.history {
+			window := r.history[c][start : start+taps]
+			v := dot32(window, r.filters[row])
+			if rem != 0 {
+				w := float64(rem) / float64(r.up)
+				v = v*(1-w) + dot32(window, r.filters[row+1])*w
+			}
+			out = append(out, float32(v))
+		}
+
+		r.produced++
+		r.phase += r.down
+		r.pos += r.phase / r.up
+		r.phase %= r.up
+	}
+
+	// Drop the input no later output reaches back to
+	if drop := min(r.pos-r.halfTaps+1, len(r.history[0])); drop > 0 {
+		for c := range r.history {
+			r.history[c] = append(r.history[c][:0], r.history[c][drop:]...)
+		}
+		r.pos -= drop
+	}
+	return out
+}
+
+// Resample converts a complete buffer of interleaved samples
+func Resample(samples []float32, inputRate, outputRate, channels int, quality string) ([]float32, error) {
+	r, err := NewResampler(inputRate, outputRate, channels, quality)
+	if err != nil {
+		return nil, err
+	}
+	return append(r.Process(samples), r.Flush()...), nil
+}
+
+func dot32(x, h []float32) float64 {
+	sum := 0.0
+	for i, v := range x {
+		sum += float64(v) * float64(h[i])
+	}
+	return sum
+}
+
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
+
+// besselI0 is the zeroth-order modified Bessel function of the first kind
+func besselI0(x float64) float64 {
+	sum, term := 1.0, 1.0
+	half := x / 2
+	for k := 1; k < 50; k++ {
+		term *= half / float64(k)
+		sum += term * term
+		if term*term < sum*1e-12 {
+			break
+		}
+	}
+	return sum
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
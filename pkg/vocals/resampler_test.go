@@ -0,0 +1,249 @@
+package vocals
+
+import (
+	"math"
+	"testing"
+)
+
+func sineWave(frequency float64, sampleRate, frames int) []float32 {
+	samples := make([]float32, frames)
+	for i := range samples {
+		samples[i] = float32(0.5 * math.Sin(2*math.Pi*frequency*float64(i)/float64(sampleRate)))
+	}
+	return samples
+}
+
+// toneLevel returns the amplitude of frequency in samples
+func toneLevel(samples []float32, frequency float64, sampleRate int) float64 {
+	var re, im float64
+	for i, s := range samples {
+		phase := 2 * math.Pi * frequency * float64(i) / float64(sampleRate)
+		re += float64(s) * math.Cos(phase)
+		im += float64(s) * math.Sin(phase)
+	}
+	return 2 * math.Hypot(re, im) / float64(len(samples))
+}
+
+func TestResampleLength(t *testing.T) {
+	tests := []struct {
+		in, out, channels, frames int
+	}{
+		{48000, 16000, 1, 48000},
+		{16000, 48000, 1, 16000},
+		{44100, 16000, 2, 44100},
+		{8000, 24000, 1, 1001},
+		{44100, 16001, 1, 4410},
+	}
+	for _, tc := range tests {
+		out, err := Resample(make([]float32, tc.frames*tc.channels), tc.in, tc.out, tc.channels, "")
+		if err != nil {
+			t.Fatalf("Resample: %v", err)
+		}
+		want := (tc.frames*tc.out + tc.in - 1) / tc.in * tc.channels
+		if len(out) != want {
+			t.Errorf("%d frames %d->%d Hz: got %d samples, want %d", tc.frames, tc.in, tc.out, len(out), want)
+		}
+	}
+}
+
+func TestResampleKeepsFrequency(t *testing.T) {
+	tests := []struct {
+		in, out   int
+		frequency float64
+	}{
+		{48000, 16000, 1000},
+		{16000, 48000, 1000},
+		{44100, 16000, 3000},
+		{8000, 24000, 440},
+	}
+	for _, quality := range []string{ResampleQualityLow, ResampleQualityMedium, ResampleQualityHigh} {
+		for _, tc := range tests {
+			out, err := Resample(sineWave(tc.frequency, tc.in, tc.in), tc.in, tc.out, 1, quality)
+			if err != nil {
+				t.Fatalf("Resample: %v", err)
+			}
+
+			// Skip the filter's edges, where the input starts and stops
+			body := out[len(out)/10 : len(out)*9/10]
+			if level := toneLevel(body, tc.frequency, tc.out); math.Abs(level-0.5) > 0.01 {
+				t.Errorf("%s %d->%d Hz: %g Hz tone has amplitude %g, want 0.5", quality, tc.in, tc.out, tc.frequency, level)
+			}
+			want := sineWave(tc.frequency, tc.out, len(out))[len(out)/10 : len(out)*9/10]
+			var signal, noise float64
+			for i := range body {
+				signal += float64(want[i]) * float64(want[i])
+				noise += float64(body[i]-want[i]) * float64(body[i]-want[i])
+			}
+			if snr := 10 * math.Log10(signal/noise); snr < 50 {
+				t.Errorf("%s %d->%d Hz: SNR %.1f dB, want at least 50", quality, tc.in, tc.out, snr)
+			}
+		}
+	}
+}
+
+func TestResampleRejectsAliases(t *testing.T) {
+	// 10 kHz lies above the 8 kHz Nyquist frequency of 16 kHz audio and
+	// would fold down to 6 kHz
+	out, err := Resample(sineWave(10000, 48000, 48000), 48000, 16000, 1, ResampleQualityMedium)
+	if err != nil {
+		t.Fatalf("Resample: %v", err)
+	}
+	body := out[len(out)/10 : len(out)*9/10]
+	if level := 20 * math.Log10(toneLevel(body, 6000, 16000)/0.5); level > -60 {
+		t.Errorf("alias at 6 kHz is %.1f dB, want below -60", level)
+	}
+}
+
+func TestResamplerStreamingMatchesOneShot(t *testing.T) {
+	input := sineWave(700, 44100, 44100)
+	want, err := Resample(input, 44100, 16000, 1, "")
+	if err != nil {
+		t.Fatalf("Resample: %v", err)
+	}
+
+	r, err := NewResampler(44100, 16000, 1, "")
+	if err != nil {
+		t.Fatalf("NewResampler: %v", err)
+	}
+	var got []float32
+	for start, size := 0, 1; start < len(input); start, size = start+size, size%509+1 {
+		end := min(start+size, len(input))
+		got = append(got, r.Process(input[start:end])...)
+	}
+	got = append(got, r.Flush()...)
+
+	if len(got) != len(want) {
+		t.Fatalf("streamed %d samples, one-shot %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("sample %d: streamed %v, one-shot %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestResamplerKeepsChannelsApart(t *testing.T) {
+	left := sineWave(500, 48000, 4800)
+	input := make([]float32, 2*len(left))
+	for i, s := range left {
+		input[2*i] = s
+	}
+
+	out, err := Resample(input, 48000, 16000, 2, "")
+	if err != nil {
+		t.Fatalf("Resample: %v", err)
+	}
+	for i := 1; i < len(out); i += 2 {
+		if math.Abs(float64(out[i])) > 1e-6 {
+			t.Fatalf("silent right channel has sample %v at frame %d", out[i], i/2)
+		}
+	}
+}
+
+func TestResamplerSameRatePassesThrough(t *testing.T) {
+	input := []float32{0.1, -0.2, 0.3}
+	out, err := Resample(input, 16000, 16000, 1, "")
+	if err != nil {
+		t.Fatalf("Resample: %v", err)
+	}
+	if len(out) != len(input) || out[0] != input[0] || out[2] != input[2] {
+		t.Errorf("got %v, want %v", out, input)
+	}
+}
+
+func TestNewResamplerRejectsInvalidSettings(t *testing.T) {
+	tests := []struct {
+		in, out, channels int
+		quality           string
+	}{
+		{0, 16000, 1, ""},
+		{16000, -1, 1, ""},
+		{16000, 8000, 0, ""},
+		{16000, 8000, 1, "ultra"},
+	}
+	for _, tc := range tests {
+		if _, err := NewResampler(tc.in, tc.out, tc.channels, tc.quality); err == nil {
+			t.Errorf("NewResampler(%d, %d, %d, %q) succeeded", tc.in, tc.out, tc.channels, tc.quality)
+		}
+	}
+}
+
+// channelSourceOf returns a source streaming samples in blocks of frameSize
+// sample frames
+func channelSourceOf(t *testing.T, samples []float32, sampleRate, channels, frameSize int) AudioSource {
+	t.Helper()
+	ch := make(chan []float32, len(samples)/(frameSize*channels)+1)
+	for i := 0; i < len(samples); i += frameSize * channels {
+		ch <- samples[i:min(i+frameSize*channels, len(samples))]
+	}
+	close(ch)
+	source, err := NewChannelSource(ch, sampleRate, channels)
+	if err != nil {
+		t.Fatalf("NewChannelSource: %v", err)
+	}
+	return source
+}
+
+func TestResampledSourceConvertsRateAndChannels(t *testing.T) {
+	// A 1 kHz tone on the left channel only
+	left := sineWave(1000, 48000, 9600)
+	stereo := make([]float32, 2*len(left))
+	for i, s := range left {
+		stereo[2*i] = s
+	}
+	source, err := NewResampledSource(channelSourceOf(t, stereo, 48000, 2, 480), 16000, 1, "")
+	if err != nil {
+		t.Fatalf("NewResampledSource: %v", err)
+	}
+
+	var out []float32
+	for _, frame := range readAll(t, source) {
+		if frame.SampleRate != 16000 || frame.Channels != 1 {
+			t.Fatalf("frame is %d Hz with %d channels, want 16000 Hz mono", frame.SampleRate, frame.Channels)
+		}
+		out = append(out, frame.Samples...)
+	}
+	if len(out) != 3200 {
+		t.Errorf("got %d samples, want 3200", len(out))
+	}
+	// Averaging the silent right channel halves the tone
+	if level := toneLevel(out, 1000, 16000); math.Abs(level-0.25) > 0.02 {
+		t.Errorf("tone level %.3f, want 0.25", level)
+	}
+}
+
+func TestResampledSourceRemixesAtTheSameRate(t *testing.T) {
+	source, err := NewResampledSource(channelSourceOf(t, []float32{1, 0.5, -0.5, 0.25}, 16000, 2, 2), 16000, 1, "")
+	if err != nil {
+		t.Fatalf("NewResampledSource: %v", err)
+	}
+	frames := readAll(t, source)
+	if len(frames) != 1 || frames[0].Channels != 1 || !equalSamples(frames[0].Samples, []float32{0.75, -0.125}) {
+		t.Errorf("frames = %+v, want the channel averages", frames)
+	}
+
+	source, err = NewResampledSource(channelSourceOf(t, []float32{0.1, 0.2}, 16000, 1, 2), 16000, 2, "")
+	if err != nil {
+		t.Fatalf("NewResampledSource: %v", err)
+	}
+	frames = readAll(t, source)
+	if len(frames) != 1 || frames[0].Channels != 2 || !equalSamples(frames[0].Samples, []float32{0.1, 0.1, 0.2, 0.2}) {
+		t.Errorf("frames = %+v, want mono repeated on both channels", frames)
+	}
+
+	if _, err := NewResampledSource(source, 16000, 0, ""); err == nil {
+		t.Error("NewResampledSource accepted 0 channels")
+	}
+}
+
+func equalSamples(a, b []float32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,89 @@
+package vocals
+
+import (
+	"sync"
+	"time"
+)
+
+// ResumeEvent reports the outcome of restoring a session after the
+// connection dropped
+type ResumeEvent struct {
+	Success       bool
+	Err           error
+	Attempts      int           // Connection attempts made while resuming
+	Downtime      time.Duration // Time between losing and restoring the connection
+	SessionID     string        // Server session ID, if the server provided one
+	ReplayedAudio time.Duration // Buffered microphone audio re-sent after resuming
+}
+
+// ResumeHandler is called after every automatic reconnect
+type ResumeHandler func(ResumeEvent)
+
+// SessionSetupFunc returns the messages sent after the start event on every
+// connection. resumed is true when the connection replaces a dropped one.
+type SessionSetupFunc func(resumed bool) []*WebSocketMessage
+
+// audioRingBuffer keeps the most recent samples of captured audio along
+// with the time they were captured
+type audioRingBuffer struct {
+	blocks   []audioBlock
+	size     int // Samples held across blocks
+	capacity int
+	rate     int // Interleaved samples per second
+	mu       sync.Mutex
+}
+
+// audioBlock is a run of samples captured together
+type audioBlock struct {
+	samples    []float32
+	capturedAt time.Time
+}
+
+// after returns the capture time of the sample at offset in the block
+func (b audioBlock) after(offset, rate int) time.Time {
+	return b.capturedAt.Add(time.Duration(offset) * time.Second / time.Duration(rate))
+}
+
+func newAudioRingBuffer(capacity, rate int) *audioRingBuffer {
+	return &audioRingBuffer{capacity: capacity, rate: rate}
+}
+
+// Write stores data captured at capturedAt, dropping the oldest samples
+// beyond capacity
+func (rb *audioRingBuffer) Write(data []float32, capturedAt time.Time) {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+
+	if rb.capacity <= 0 || len(data) == 0 {
+		return
+	}
+	block := audioBlock{samples: append([]float32(nil), data...), capturedAt: capturedAt}
+	rb.blocks = append(rb.blocks, block)
+	rb.size += len(data)
+
+	for rb.size > rb.capacity {
+		oldest := rb.blocks[0]
+		excess := rb.size - rb.capacity
+		if excess >= len(oldest.samples) {
+			rb.blocks = rb.blocks[1:]
+			rb.size -= len(oldest.samples)
+			continue
+		}
+		rb.blocks[0] = audioBlock{samples: oldest.samples[excess:], capturedAt: oldest.after(excess, rb.rate)}
+		rb.size -= excess
+	}
+}
+
+// Snapshot returns the buffered blocks, oldest first
+func (rb *audioRingBuffer) Snapshot() []audioBlock {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	return append([]audioBlock(nil), rb.blocks...)
+}
+
+func (rb *audioRingBuffer) Reset() {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	rb.blocks = nil
+	rb.size = 0
+}
@@ -0,0 +1,116 @@
+package vocals_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rojolang/vocals-sdk-go/pkg/vocals"
+)
+
+func TestReconnectResumesSession(t *testing.T) {
+	srv := newTestServer(t)
+	client := connectClient(t, srv.Config())
+	resumed := make(chan vocals.ResumeEvent, 1)
+	client.AddResumeHandler(func(event vocals.ResumeEvent) { resumed <- event })
+	if _, err := srv.WaitForEvent("start", 1, time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	srv.DisconnectAll()
+
+	if event := waitFor(t, resumed, "resume"); !event.Success {
+		t.Fatalf("resume failed: %v", event.Err)
+	}
+	frames, err := srv.WaitForEvent("start", 2, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var start map[string]interface{}
+	if err := frames[1].DecodeData(&start); err != nil {
+		t.Fatalf("decoding start: %v", err)
+	}
+	if start["resume"] != true {
+		t.Errorf("start after reconnect = %v, want resume", start)
+	}
+	if !client.IsConnected() || srv.ConnectionCount() != 2 {
+		t.Errorf("connected %v with %d connections, want a second connection", client.IsConnected(), srv.ConnectionCount())
+	}
+}
+
+func TestResumeReplaysBufferedAudio(t *testing.T) {
+	srv := newTestServer(t)
+	config := srv.Config()
+	config.ResumeAudioReplayMs = 500
+	audioConfig := vocals.NewAudioConfig()
+	audioConfig.SampleRate = 16000
+
+	client := vocals.NewVocalsClient(config, audioConfig, nil, []string{"transcription"})
+	defer client.Cleanup()
+	resumed := make(chan vocals.ResumeEvent, 1)
+	client.AddResumeHandler(func(event vocals.ResumeEvent) { resumed <- event })
+	attempts := make(chan vocals.ReconnectEvent, 10)
+	client.AddReconnectHandler(func(event vocals.ReconnectEvent) { attempts <- event })
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+
+	audio := make(chan []float32)
+	source, err := vocals.NewChannelSource(audio, 16000, 1)
+	if err != nil {
+		t.Fatalf("NewChannelSource: %v", err)
+	}
+	if err := client.StartRecordingFrom(source); err != nil {
+		t.Fatalf("StartRecordingFrom: %v", err)
+	}
+	block := func(value float32) []float32 {
+		samples := make([]float32, 160)
+		for i := range samples {
+			samples[i] = value
+		}
+		return samples
+	}
+	audio <- block(0.25)
+	if _, err := srv.WaitForEvent("media", 1, time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	// Speech captured during the outage cannot be sent until it ends
+	srv.RejectConnections(1000)
+	srv.DisconnectAll()
+	waitFor(t, attempts, "a failed attempt")
+	audio <- block(0.75)
+	audio <- block(0.75) // Returns once the first outage block was captured
+	srv.RejectConnections(0)
+
+	event := waitFor(t, resumed, "resume")
+	if !event.Success {
+		t.Fatalf("resume failed: %v", event.Err)
+	}
+	if event.ReplayedAudio <= 0 {
+		t.Errorf("replayed %s of audio, want some", event.ReplayedAudio)
+	}
+
+	starts, err := srv.WaitForEvent("start", 2, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resumedConn := starts[1].ConnID
+	var replayed int
+	for _, frame := range srv.ReceivedEvents("media") {
+		if frame.ConnID != resumedConn {
+			continue
+		}
+		samples, err := frame.Samples()
+		if err != nil {
+			t.Fatalf("decoding media: %v", err)
+		}
+		for _, s := range samples {
+			if s == 0.75 {
+				replayed++
+			}
+		}
+	}
+	if replayed < 160 {
+		t.Errorf("resumed connection received %d samples captured during the outage, want at least 160", replayed)
+	}
+}
@@ -0,0 +1,122 @@
+package vocals
+
+import (
+	"context"
+	"sync"
+)
+
+// Subscription is a registered handler. Unsubscribe removes it and is safe
+// to call more than once.
+type Subscription struct {
+	once   sync.Once
+	remove func()
+}
+
+func newSubscription(remove func()) *Subscription {
+	return &Subscription{remove: remove}
+}
+
+// Unsubscribe stops further deliveries to the handler
+func (s *Subscription) Unsubscribe() {
+	s.once.Do(s.remove)
+}
+
+// EventSource is implemented by VocalsClient and WebSocketClient
+type EventSource interface {
+	AddEventHandler(handler EventHandler) func()
+}
+
+// On subscribes handler to events of type T only, for example
+//
+//	vocals.On(client, func(e vocals.TranscriptionEvent) {
+//		fmt.Println(e.Text)
+//	})
+func On[T Event](source EventSource, handler func(T)) *Subscription {
+	return newSubscription(source.AddEventHandler(func(event Event) {
+		if e, ok := event.(T); ok {
+			handler(e)
+		}
+	}))
+}
+
+// eventChannel delivers events from source on a channel that is closed once
+// ctx is done
+func eventChannel(ctx context.Context, source EventSource) <-chan Event {
+	events := make(chan Event, 64)
+	var mu sync.RWMutex
+	closed := false
+
+	remove := source.AddEventHandler(func(event Event) {
+		mu.RLock()
+		defer mu.RUnlock()
+		if closed {
+			return
+		}
+		select {
+		case events <- event:
+		case <-ctx.Done():
+		}
+	})
+
+	go func() {
+		<-ctx.Done()
+		remove()
+		// Wait for in-flight deliveries, which give up once ctx is done
+		mu.Lock()
+		closed = true
+		close(events)
+		mu.Unlock()
+	}()
+
+	return events
+}
+
+type handlerEntry[H any] struct {
+	id      uint64
+	handler H
+}
+
+// handlerList holds registered handlers under a registration ID so that
+// they can be removed again; func values cannot be compared.
+type handlerList[H any] struct {
+	entries []handlerEntry[H]
+	nextID  uint64
+	mu      sync.Mutex
+}
+
+// add registers a handler and returns a function that removes it
+func (l *handlerList[H]) add(handler H) func() {
+	l.mu.Lock()
+	l.nextID++
+	id := l.nextID
+	l.entries = append(l.entries, handlerEntry[H]{id: id, handler: handler})
+	l.mu.Unlock()
+
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		for i, entry := range l.entries {
+			if entry.id == id {
+				l.entries = append(l.entries[:i], l.entries[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// snapshot returns the currently registered handlers in registration order
+func (l *handlerList[H]) snapshot() []H {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	handlers := make([]H, len(l.entries))
+	for i, entry := range l.entries {
+		handlers[i] = entry.handler
+	}
+	return handlers
+}
+
+func (l *handlerList[H]) len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.entries)
+}
@@ -0,0 +1,84 @@
+package vocals_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rojolang/vocals-sdk-go/pkg/vocals"
+	"github.com/rojolang/vocals-sdk-go/pkg/vocalstest"
+)
+
+func TestOnDeliversOnlyItsEventType(t *testing.T) {
+	srv := newTestServer(t)
+	client := connectClient(t, srv.Config())
+
+	finals := make(chan vocals.TranscriptionEvent, 10)
+	sub := vocals.On(client, func(e vocals.TranscriptionEvent) { finals <- e })
+	responses := make(chan vocals.ResponseEvent, 10)
+	vocals.On(client, func(e vocals.ResponseEvent) { responses <- e })
+
+	for _, reply := range []vocalstest.Reply{
+		vocalstest.PartialTranscription("hel"),
+		vocalstest.Transcription("hello", true),
+		vocalstest.Response("hi there"),
+	} {
+		if err := srv.Send(reply); err != nil {
+			t.Fatalf("Send: %v", err)
+		}
+	}
+	if e := waitFor(t, finals, "the transcription"); e != (vocals.TranscriptionEvent{Text: "hello", IsFinal: true}) {
+		t.Errorf("transcription handler got %+v", e)
+	}
+	if e := waitFor(t, responses, "the response"); e.Text != "hi there" {
+		t.Errorf("response handler got %+v", e)
+	}
+
+	// The response handler seeing the marker proves the transcription after
+	// it was dispatched once the first handler was gone
+	sub.Unsubscribe()
+	sub.Unsubscribe()
+	if err := srv.Send(vocalstest.Transcription("ignored", true)); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if err := srv.Send(vocalstest.Response("marker")); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	waitFor(t, responses, "the marker")
+	select {
+	case e := <-finals:
+		t.Errorf("unsubscribed handler got %+v", e)
+	default:
+	}
+}
+
+func TestEventsChannelClosesWithContext(t *testing.T) {
+	srv := newTestServer(t)
+	client := connectClient(t, srv.Config())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := client.Events(ctx)
+	if err := srv.Send(vocalstest.Response("one")); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if event := waitFor(t, events, "the event"); event != (vocals.ResponseEvent{Text: "one"}) {
+		t.Errorf("got %#v, want the response", event)
+	}
+
+	// An undelivered event must not keep the channel open
+	if err := srv.Send(vocalstest.Response("two")); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	cancel()
+	deadline := time.After(5 * time.Second)
+	for {
+		select {
+		case _, ok := <-events:
+			if !ok {
+				return
+			}
+		case <-deadline:
+			t.Fatal("Events channel still open after ctx was cancelled")
+		}
+	}
+}
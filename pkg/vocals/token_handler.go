@@ -0,0 +1,204 @@
+package vocals
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TokenHandlerOptions customizes a TokenHandler. Every hook is optional.
+type TokenHandlerOptions struct {
+	// Authenticate rejects the caller with 401 Unauthorized by returning an
+	// error. Every caller is accepted when nil.
+	Authenticate func(r *http.Request) error
+	// UserID derives the userId claim of the caller's token. An error
+	// rejects the caller with 401 Unauthorized.
+	UserID func(r *http.Request) (string, error)
+	// RateLimit rejects the caller with 429 Too Many Requests by returning
+	// false, telling it to retry after retryAfter when that is positive
+	RateLimit func(r *http.Request, userID string) (allowed bool, retryAfter time.Duration)
+	// Customize adjusts the claims of a single token. An error rejects the
+	// caller with 403 Forbidden.
+	Customize func(r *http.Request, options *TokenOptions) error
+
+	// Token holds the claims every minted token starts from. UserID, ID and
+	// IssuedAt are set per token.
+	Token TokenOptions
+
+	// AllowedOrigins lists the browser origins allowed to call the handler,
+	// or "*" for any. CORS headers are only sent to listed origins.
+	AllowedOrigins []string
+	// AllowCredentials lets browsers send cookies to the handler. It cannot
+	// be combined with a "*" origin, which would let any website mint tokens
+	// with its visitors' cookies; NewTokenHandler rejects that combination.
+	AllowCredentials bool
+	// CORS replaces the AllowedOrigins handling. It sets the CORS headers
+	// and returns false to reject the request with 403 Forbidden.
+	CORS func(w http.ResponseWriter, r *http.Request) bool
+}
+
+// TokenHandler is an http.Handler serving the token endpoint that
+// TokenEndpoint and NewEndpointTokenSource expect. It mints short-lived
+// tokens with the server's API key, so browser and mobile clients can
+// connect without ever holding the key. A POST is answered with
+// {"token": ..., "expiresAt": <unix ms>}; failures are answered with
+// {"error": ..., "code": ...}.
+type TokenHandler struct {
+	apiKey  ValidatedApiKey
+	options TokenHandlerOptions
+}
+
+// NewTokenHandler mints tokens signed with apiKey, or with
+// VOCALS_DEV_API_KEY when apiKey is empty
+func NewTokenHandler(apiKey string, options TokenHandlerOptions) (*TokenHandler, error) {
+	if apiKey == "" {
+		keyResult := GetVocalsApiKey()
+		if !keyResult.Success {
+			return nil, keyResult.Error
+		}
+		apiKey = keyResult.Data
+	}
+
+	validated := ValidateApiKeyFormat(apiKey)
+	if !validated.Success {
+		return nil, validated.Error
+	}
+	if options.AllowCredentials && options.CORS == nil {
+		for _, o := range options.AllowedOrigins {
+			if o == "*" {
+				return nil, NewVocalsError("AllowCredentials cannot be used with a \"*\" origin; list the allowed origins", ErrCodeConfigInvalid)
+			}
+		}
+	}
+	return &TokenHandler{apiKey: validated.Data, options: options}, nil
+}
+
+func (h *TokenHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !h.applyCORS(w, r) {
+		writeTokenError(w, http.StatusForbidden, NewVocalsError("Origin not allowed", "ORIGIN_NOT_ALLOWED"))
+		return
+	}
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST, OPTIONS")
+		writeTokenError(w, http.StatusMethodNotAllowed, NewVocalsError("Method not allowed", "METHOD_NOT_ALLOWED"))
+		return
+	}
+
+	if h.options.Authenticate != nil {
+		if err := h.options.Authenticate(r); err != nil {
+			writeTokenError(w, http.StatusUnauthorized, NewAuthError(err.Error()))
+			return
+		}
+	}
+
+	tokenOptions := h.options.Token
+	tokenOptions.ID = ""
+	tokenOptions.IssuedAt = time.Time{}
+	if h.options.UserID != nil {
+		userID, err := h.options.UserID(r)
+		if err != nil {
+			writeTokenError(w, http.StatusUnauthorized, NewAuthError(err.Error()))
+			return
+		}
+		tokenOptions.UserID = userID
+	}
+
+	if h.options.RateLimit != nil {
+		if allowed, retryAfter := h.options.RateLimit(r, tokenOptions.UserID); !allowed {
+			if retryAfter > 0 {
+				w.Header().Set("Retry-After", strconv.Itoa(int((retryAfter+time.Second-1)/time.Second)))
+			}
+			writeTokenError(w, http.StatusTooManyRequests, NewVocalsError("Too many token requests", "RATE_LIMITED"))
+			return
+		}
+	}
+
+	if h.options.Customize != nil {
+		// Copy the claims so per-token changes never leak into the template
+		claims := make(map[string]interface{}, len(tokenOptions.Claims))
+		for name, value := range tokenOptions.Claims {
+			claims[name] = value
+		}
+		tokenOptions.Claims = claims
+		if err := h.options.Customize(r, &tokenOptions); err != nil {
+			writeTokenError(w, http.StatusForbidden, NewVocalsError(err.Error(), "TOKEN_FORBIDDEN"))
+			return
+		}
+	}
+
+	tokenResult := GenerateWsTokenWithOptions(h.apiKey, tokenOptions)
+	if !tokenResult.Success {
+		log.Printf("Failed to mint token: %v", tokenResult.Error.Message)
+		writeTokenError(w, http.StatusInternalServerError, tokenResult.Error)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"token":     tokenResult.Data.Token,
+		"expiresAt": tokenResult.Data.ExpiresAt,
+	})
+}
+
+// applyCORS sets the CORS headers for the request's origin and reports
+// whether the request may proceed
+func (h *TokenHandler) applyCORS(w http.ResponseWriter, r *http.Request) bool {
+	if h.options.CORS != nil {
+		return h.options.CORS(w, r)
+	}
+
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		// Not a browser cross-origin request
+		return true
+	}
+
+	listed, wildcard := false, false
+	for _, o := range h.options.AllowedOrigins {
+		if strings.EqualFold(o, origin) {
+			listed = true
+			break
+		}
+		wildcard = wildcard || o == "*"
+	}
+	if !listed && !wildcard {
+		return r.Method != http.MethodOptions
+	}
+
+	header := w.Header()
+	if listed {
+		header.Set("Access-Control-Allow-Origin", origin)
+		header.Add("Vary", "Origin")
+		if h.options.AllowCredentials {
+			header.Set("Access-Control-Allow-Credentials", "true")
+		}
+	} else {
+		// Any origin may call, but never with the visitor's cookies
+		header.Set("Access-Control-Allow-Origin", "*")
+	}
+	if r.Method == http.MethodOptions {
+		header.Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+		header.Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+		header.Set("Access-Control-Max-Age", "600")
+	}
+	return true
+}
+
+// writeTokenError answers a token request with err as JSON
+func writeTokenError(w http.ResponseWriter, status int, err *VocalsError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{
+		"error": err.Message,
+		"code":  err.Code,
+	})
+}
@@ -0,0 +1,163 @@
+package vocals
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newTestTokenHandler(t *testing.T, options TokenHandlerOptions) *TokenHandler {
+	t.Helper()
+	handler, err := NewTokenHandler(testAPIKey, options)
+	if err != nil {
+		t.Fatalf("NewTokenHandler: %v", err)
+	}
+	return handler
+}
+
+func serveToken(handler http.Handler, method, origin string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, "/token", nil)
+	if origin != "" {
+		r.Header.Set("Origin", origin)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestTokenHandlerMintsVerifiableTokens(t *testing.T) {
+	handler := newTestTokenHandler(t, TokenHandlerOptions{
+		UserID: func(r *http.Request) (string, error) { return "user-1", nil },
+		Token:  TokenOptions{Scopes: []string{"stream"}},
+	})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	// The handler must answer in the format NewEndpointTokenSource reads
+	token, err := NewEndpointTokenSource(server.URL, nil).Token(context.Background())
+	if err != nil {
+		t.Fatalf("fetching token: %v", err)
+	}
+	if token.ExpiresAt <= time.Now().UnixMilli() {
+		t.Errorf("token expires at %d, in the past", token.ExpiresAt)
+	}
+	claims := DecodeWsTokenWithOptions(token.Token, testAPIKey, VerifyOptions{RequiredScopes: []string{"stream"}})
+	if !claims.Success {
+		t.Fatalf("minted token does not verify: %v", claims.Error)
+	}
+	if claims.Data["userId"] != "user-1" {
+		t.Errorf("userId = %v, want user-1", claims.Data["userId"])
+	}
+}
+
+func TestTokenHandlerRejections(t *testing.T) {
+	tests := []struct {
+		name    string
+		options TokenHandlerOptions
+		method  string
+		status  int
+		code    string
+	}{
+		{
+			name:    "wrong method",
+			options: TokenHandlerOptions{},
+			method:  http.MethodGet,
+			status:  http.StatusMethodNotAllowed,
+			code:    "METHOD_NOT_ALLOWED",
+		},
+		{
+			name: "unauthenticated",
+			options: TokenHandlerOptions{
+				Authenticate: func(r *http.Request) error { return errors.New("no session") },
+			},
+			method: http.MethodPost,
+			status: http.StatusUnauthorized,
+			code:   ErrCodeAuthFailed,
+		},
+		{
+			name: "rate limited",
+			options: TokenHandlerOptions{
+				RateLimit: func(r *http.Request, userID string) (bool, time.Duration) { return false, 1500 * time.Millisecond },
+			},
+			method: http.MethodPost,
+			status: http.StatusTooManyRequests,
+			code:   "RATE_LIMITED",
+		},
+		{
+			name: "customize refused",
+			options: TokenHandlerOptions{
+				Customize: func(r *http.Request, options *TokenOptions) error { return errors.New("plan expired") },
+			},
+			method: http.MethodPost,
+			status: http.StatusForbidden,
+			code:   "TOKEN_FORBIDDEN",
+		},
+	}
+	for _, tc := range tests {
+		w := serveToken(newTestTokenHandler(t, tc.options), tc.method, "")
+		if w.Code != tc.status {
+			t.Errorf("%s: status %d, want %d", tc.name, w.Code, tc.status)
+			continue
+		}
+		var body map[string]string
+		if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+			t.Errorf("%s: decoding body: %v", tc.name, err)
+			continue
+		}
+		if body["code"] != tc.code {
+			t.Errorf("%s: code %q, want %q", tc.name, body["code"], tc.code)
+		}
+		if tc.status == http.StatusTooManyRequests && w.Header().Get("Retry-After") != "2" {
+			t.Errorf("%s: Retry-After %q, want 2", tc.name, w.Header().Get("Retry-After"))
+		}
+	}
+}
+
+func TestTokenHandlerCORS(t *testing.T) {
+	handler := newTestTokenHandler(t, TokenHandlerOptions{
+		AllowedOrigins:   []string{"https://app.example.com"},
+		AllowCredentials: true,
+	})
+
+	w := serveToken(handler, http.MethodOptions, "https://app.example.com")
+	if w.Code != http.StatusNoContent {
+		t.Errorf("preflight from a listed origin: status %d, want 204", w.Code)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+		t.Errorf("Access-Control-Allow-Origin = %q, want the listed origin", got)
+	}
+	if w.Header().Get("Access-Control-Allow-Credentials") != "true" {
+		t.Error("credentials not allowed for a listed origin")
+	}
+
+	w = serveToken(handler, http.MethodOptions, "https://evil.example.com")
+	if w.Code != http.StatusForbidden {
+		t.Errorf("preflight from an unlisted origin: status %d, want 403", w.Code)
+	}
+	w = serveToken(handler, http.MethodPost, "https://evil.example.com")
+	if w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Error("CORS headers sent to an unlisted origin")
+	}
+}
+
+func TestTokenHandlerWildcardOrigin(t *testing.T) {
+	if _, err := NewTokenHandler(testAPIKey, TokenHandlerOptions{AllowedOrigins: []string{"*"}, AllowCredentials: true}); err == nil {
+		t.Error("NewTokenHandler accepted credentials with a \"*\" origin")
+	}
+
+	handler := newTestTokenHandler(t, TokenHandlerOptions{AllowedOrigins: []string{"*"}})
+	w := serveToken(handler, http.MethodPost, "https://any.example.com")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d, want 200", w.Code)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("Access-Control-Allow-Origin = %q, want *", got)
+	}
+	if w.Header().Get("Access-Control-Allow-Credentials") != "" {
+		t.Error("credentials allowed for a wildcard origin")
+	}
+}
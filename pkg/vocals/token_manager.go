@@ -2,92 +2,175 @@ package vocals
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"sync"
 	"time"
 )
 
+// TokenManager caches the token of a token endpoint. It is safe for
+// concurrent use; callers that find the token stale wait for a single
+// refresh.
 type TokenManager struct {
 	endpoint      string
 	headers       map[string]string
 	refreshBuffer float64
+	client        *http.Client
 	token         *string
 	expiresAt     time.Time
+	flight        *tokenFlight // Fetch in progress, if any
+	mu            sync.Mutex
 }
 
 func NewTokenManager(endpoint string, headers map[string]string, refreshBuffer float64) *TokenManager {
+	return NewTokenManagerWithClient(endpoint, headers, refreshBuffer, nil)
+}
+
+// NewTokenManagerWithClient requests tokens through client, or through a
+// shared client with a 30 second timeout when client is nil
+func NewTokenManagerWithClient(endpoint string, headers map[string]string, refreshBuffer float64, client *http.Client) *TokenManager {
 	return &TokenManager{
 		endpoint:      endpoint,
 		headers:       headers,
 		refreshBuffer: refreshBuffer,
+		client:        client,
 	}
 }
 
 func (tm *TokenManager) GetToken() (string, error) {
-	currentTime := time.Now()
-	if tm.token != nil && currentTime.Before(tm.expiresAt.Add(time.Duration(-tm.refreshBuffer)*time.Second)) {
-		return *tm.token, nil
+	token, err := tm.Token(context.Background())
+	if err != nil {
+		return "", err
 	}
+	return token.Token, nil
+}
 
-	return tm.refreshToken()
+// Token returns the cached token, refreshing it when it is within the
+// refresh buffer of expiring. TokenManager is therefore a TokenSource.
+// Concurrent callers share one request, and each stops waiting when its
+// own ctx is done.
+func (tm *TokenManager) Token(ctx context.Context) (*WSToken, error) {
+	tm.mu.Lock()
+	if tm.token != nil && time.Now().Before(tm.expiresAt.Add(-secondsToDuration(tm.refreshBuffer))) {
+		token := &WSToken{Token: *tm.token, ExpiresAt: tm.expiresAt.UnixMilli()}
+		tm.mu.Unlock()
+		return token, nil
+	}
+	flight := tm.startFetchLocked(ctx)
+	tm.mu.Unlock()
+
+	select {
+	case <-flight.done:
+		return flight.token, flight.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
 }
 
-func (tm *TokenManager) refreshToken() (string, error) {
-	reqHeaders := map[string]string{
-		"Content-Type": "application/json",
+// startFetchLocked joins the request in progress or starts one. The request
+// keeps ctx's values but not its cancellation, since other callers may be
+// waiting for it. The caller holds tm.mu.
+func (tm *TokenManager) startFetchLocked(ctx context.Context) *tokenFlight {
+	if tm.flight != nil {
+		return tm.flight
 	}
-	for k, v := range tm.headers {
-		reqHeaders[k] = v
+
+	flight := &tokenFlight{done: make(chan struct{})}
+	tm.flight = flight
+	go func() {
+		token, err := fetchEndpointToken(context.WithoutCancel(ctx), tm.client, tm.endpoint, tm.headers)
+
+		tm.mu.Lock()
+		// A request started before Clear does not refill the cache
+		if tm.flight == flight {
+			if err == nil {
+				tm.token = &token.Token
+				tm.expiresAt = time.UnixMilli(token.ExpiresAt)
+			}
+			tm.flight = nil
+		}
+		tm.mu.Unlock()
+
+		flight.token, flight.err = token, err
+		close(flight.done)
+	}()
+	return flight
+}
+
+// defaultTokenHTTPClient is shared by token requests made without a client
+var defaultTokenHTTPClient = &http.Client{Timeout: 30 * time.Second}
+
+// fetchEndpointToken POSTs to a token endpoint and parses its
+// {"token": ..., "expiresAt": <unix ms>} response. Failures are
+// classified as ErrCodeAuthRejected, ErrCodeTransient or
+// ErrCodeMalformedResponse; a cancelled ctx returns ctx.Err().
+func fetchEndpointToken(ctx context.Context, client *http.Client, endpoint string, headers map[string]string) (*WSToken, error) {
+	if client == nil {
+		client = defaultTokenHTTPClient
 	}
 
-	client := &http.Client{Timeout: 30 * time.Second}
-	req, err := http.NewRequest("POST", tm.endpoint, bytes.NewBuffer([]byte("{}")))
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewBuffer([]byte("{}")))
 	if err != nil {
-		return "", err
+		return nil, NewVocalsError(fmt.Sprintf("Invalid token endpoint: %v", err), ErrCodeConfigInvalid)
 	}
-
-	for k, v := range reqHeaders {
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
 		req.Header.Set(k, v)
 	}
 
 	resp, err := client.Do(req)
 	if err != nil {
-		return "", err
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, NewVocalsError(fmt.Sprintf("Token request failed: %v", err), ErrCodeTransient).
+			AddDetail("endpoint", endpoint)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("failed to refresh token: %s", resp.Status)
+	switch {
+	case resp.StatusCode == http.StatusOK:
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return nil, NewVocalsError(fmt.Sprintf("Token request rejected: %s", resp.Status), ErrCodeAuthRejected).
+			AddDetail("endpoint", endpoint).AddDetail("status", resp.StatusCode)
+	default:
+		// Other statuses, such as a 404 from a misrouted proxy, may clear up
+		return nil, NewVocalsError(fmt.Sprintf("Token endpoint unavailable: %s", resp.Status), ErrCodeTransient).
+			AddDetail("endpoint", endpoint).AddDetail("status", resp.StatusCode)
 	}
 
 	var data map[string]interface{}
 	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		return "", err
+		return nil, NewVocalsError(fmt.Sprintf("Invalid token response: %v", err), ErrCodeMalformedResponse).
+			AddDetail("endpoint", endpoint)
 	}
 
 	token, ok := data["token"].(string)
 	if !ok || token == "" {
-		return "", fmt.Errorf("no token received")
+		return nil, NewVocalsError("No token received", ErrCodeMalformedResponse).AddDetail("endpoint", endpoint)
 	}
 
 	expiresAt, ok := data["expiresAt"].(float64)
 	if !ok {
-		return "", fmt.Errorf("invalid expiresAt")
+		return nil, NewVocalsError("Invalid expiresAt in token response", ErrCodeMalformedResponse).AddDetail("endpoint", endpoint)
 	}
 
-	tm.token = &token
-	tm.expiresAt = time.UnixMilli(int64(expiresAt))
-
-	return token, nil
+	return &WSToken{Token: token, ExpiresAt: int64(expiresAt)}, nil
 }
 
 func (tm *TokenManager) Clear() {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
 	tm.token = nil
 	tm.expiresAt = time.Time{}
+	tm.flight = nil
 }
 
 func (tm *TokenManager) GetTokenInfo() (*string, *float64) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
 	if tm.token == nil {
 		return nil, nil
 	}
@@ -0,0 +1,122 @@
+package vocals_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rojolang/vocals-sdk-go/pkg/vocals"
+)
+
+func TestTokenManagerClassifiesFailures(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   string
+	}{
+		{"unauthorized", http.StatusUnauthorized, "", vocals.ErrCodeAuthRejected},
+		{"forbidden", http.StatusForbidden, "", vocals.ErrCodeAuthRejected},
+		{"server error", http.StatusInternalServerError, "", vocals.ErrCodeTransient},
+		{"not found", http.StatusNotFound, "", vocals.ErrCodeTransient},
+		{"invalid JSON", http.StatusOK, "{", vocals.ErrCodeMalformedResponse},
+		{"missing token", http.StatusOK, `{"expiresAt": 1}`, vocals.ErrCodeMalformedResponse},
+		{"missing expiry", http.StatusOK, `{"token": "t"}`, vocals.ErrCodeMalformedResponse},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.body)
+			}))
+			defer srv.Close()
+
+			_, err := vocals.NewTokenManager(srv.URL, nil, 0).Token(context.Background())
+			var vErr *vocals.VocalsError
+			if !errors.As(err, &vErr) || vErr.Code != tt.want {
+				t.Errorf("Token = %v, want %s", err, tt.want)
+			}
+		})
+	}
+
+	// An unreachable endpoint may come back
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+	_, err := vocals.NewTokenManager(srv.URL, nil, 0).Token(context.Background())
+	var vErr *vocals.VocalsError
+	if !errors.As(err, &vErr) || vErr.Code != vocals.ErrCodeTransient {
+		t.Errorf("Token = %v, want %s", err, vocals.ErrCodeTransient)
+	}
+}
+
+func TestTokenManagerSharesOneRequest(t *testing.T) {
+	var requests atomic.Int32
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := requests.Add(1)
+		if n == 1 {
+			<-release
+		}
+		fmt.Fprintf(w, `{"token": "token-%d", "expiresAt": %d}`, n, time.Now().Add(time.Hour).UnixMilli())
+	}))
+	defer srv.Close()
+	defer close(release)
+	tm := vocals.NewTokenManager(srv.URL, nil, 0)
+
+	type result struct {
+		token *vocals.WSToken
+		err   error
+	}
+	waiting := make(chan result, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			token, err := tm.Token(context.Background())
+			waiting <- result{token, err}
+		}()
+	}
+	for requests.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// A caller gives up when its own context ends, and Clear does not
+	// wait for the request in progress
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	abandoned := make(chan error, 1)
+	go func() {
+		_, err := tm.Token(ctx)
+		abandoned <- err
+	}()
+	if err := waitFor(t, abandoned, "the cancelled caller"); err != context.DeadlineExceeded {
+		t.Errorf("Token with an expired context = %v, want %v", err, context.DeadlineExceeded)
+	}
+	cleared := make(chan struct{})
+	go func() {
+		tm.Clear()
+		close(cleared)
+	}()
+	waitFor(t, cleared, "Clear")
+
+	release <- struct{}{}
+	for i := 0; i < 2; i++ {
+		r := waitFor(t, waiting, "the shared request")
+		if r.err != nil || r.token.Token != "token-1" {
+			t.Errorf("Token = %+v (%v), want token-1", r.token, r.err)
+		}
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("made %d requests, want 1 for concurrent callers", n)
+	}
+
+	// The request started before Clear did not refill the cache
+	token, err := tm.Token(context.Background())
+	if err != nil || token.Token != "token-2" {
+		t.Errorf("Token after Clear = %+v (%v), want token-2", token, err)
+	}
+}
@@ -0,0 +1,211 @@
+package vocals
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Token refresh modes for long-lived connections
+const (
+	TokenRefreshReconnect = "reconnect" // Open a connection with the new token, then close the old one
+	TokenRefreshReauth    = "reauth"    // Send the new token on the live connection, reconnecting if the server refuses
+	TokenRefreshOff       = "off"       // Keep the connection until the server closes it
+)
+
+// TokenRefreshEvent reports the outcome of renewing the token of an open
+// connection
+type TokenRefreshEvent struct {
+	Success   bool
+	Method    string    // TokenRefreshReauth or TokenRefreshReconnect
+	ExpiresAt time.Time // Expiry of the new token, zero on failure
+	Err       error
+}
+
+func (e TokenRefreshEvent) String() string {
+	if !e.Success {
+		return fmt.Sprintf("token refresh failed: %v", e.Err)
+	}
+	return fmt.Sprintf("token refreshed by %s, expires %s", e.Method, e.ExpiresAt.Format(time.RFC3339))
+}
+
+// TokenRefreshHandler is called after every proactive token refresh
+type TokenRefreshHandler func(TokenRefreshEvent)
+
+// AddTokenRefreshHandler registers a handler called after every proactive
+// token refresh
+func (wsc *WebSocketClient) AddTokenRefreshHandler(handler TokenRefreshHandler) func() {
+	return wsc.refreshHandlers.add(handler)
+}
+
+// startTokenRefresh renews the token of the connection served by writer
+// ahead of expiresAt, until lifetime ends. Nothing is scheduled for tokens
+// that do not expire. The caller holds wsc.mu.
+func (wsc *WebSocketClient) startTokenRefresh(lifetime context.Context, writer *outboundWriter, expiresAt time.Time) {
+	if wsc.tokenSource == nil || expiresAt.IsZero() || wsc.config.TokenRefreshMode == TokenRefreshOff {
+		return
+	}
+	go wsc.tokenRefreshLoop(lifetime, writer, expiresAt)
+}
+
+// tokenRefreshLoop runs until the connection served by writer closes. A
+// reconnect hands the refresh over to the loop of the new connection.
+func (wsc *WebSocketClient) tokenRefreshLoop(lifetime context.Context, writer *outboundWriter, expiresAt time.Time) {
+	ctx, cancel := context.WithCancel(lifetime)
+	defer cancel()
+	go func() {
+		select {
+		case <-writer.done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	wait := refreshDelay(expiresAt, secondsToDuration(wsc.config.TokenRefreshBuffer))
+	for {
+		if err := sleepContext(ctx, wait); err != nil {
+			return
+		}
+
+		method, token, err := wsc.refreshConnectionToken(ctx, writer, expiresAt)
+		if err == nil {
+			expiresAt = tokenExpiry(token)
+			wsc.refreshHandlers.dispatch(TokenRefreshEvent{Success: true, Method: method, ExpiresAt: expiresAt})
+			if method == TokenRefreshReconnect || expiresAt.IsZero() {
+				// The new connection refreshes its own token, and a token
+				// without expiry never needs refreshing
+				return
+			}
+			wait = refreshDelay(expiresAt, secondsToDuration(wsc.config.TokenRefreshBuffer))
+			continue
+		}
+		if ctx.Err() != nil {
+			// The connection closed meanwhile; reconnecting fetches a token anyway
+			return
+		}
+
+		if wsc.config.DebugWebsocket {
+			log.Printf("Token refresh failed: %v", err)
+		}
+		wsc.refreshHandlers.dispatch(TokenRefreshEvent{Method: method, Err: err})
+		wait = min(max(time.Until(expiresAt)/4, time.Second), 30*time.Second)
+	}
+}
+
+// refreshConnectionToken fetches a token newer than the one expiring at
+// expiresAt and applies it to the connection served by writer, returning
+// the method that succeeded
+func (wsc *WebSocketClient) refreshConnectionToken(ctx context.Context, writer *outboundWriter, expiresAt time.Time) (string, *WSToken, error) {
+	method := wsc.config.TokenRefreshMode
+	if method == "" {
+		method = TokenRefreshReconnect
+	}
+
+	token, err := wsc.fetchToken(ctx)
+	if err == nil && token.ExpiresAt != 0 && !time.UnixMilli(token.ExpiresAt).After(expiresAt) {
+		// A cache handed back the current token; ask the source itself
+		if caching, ok := wsc.tokenSource.(*CachingTokenSource); ok {
+			caching.Clear()
+			token, err = wsc.fetchToken(ctx)
+		}
+	}
+	if err != nil {
+		return method, nil, err
+	}
+
+	if method == TokenRefreshReauth {
+		if err := wsc.reauthenticate(ctx, token); err == nil {
+			return method, token, nil
+		} else if wsc.config.DebugWebsocket {
+			log.Printf("Re-authentication refused, reconnecting instead: %v", err)
+		}
+		method = TokenRefreshReconnect
+	}
+	return method, token, wsc.replaceConnection(ctx, writer, token)
+}
+
+// reauthenticate sends token on the live connection and waits for the
+// server to accept it
+func (wsc *WebSocketClient) reauthenticate(ctx context.Context, token *WSToken) error {
+	_, err := wsc.Request(ctx, &WebSocketMessage{
+		Event: "reauth",
+		Data: map[string]interface{}{
+			"token":      token.Token,
+			"expires_at": token.ExpiresAt,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	wsc.mu.Lock()
+	wsc.tokenExpiresAt = tokenExpiry(token)
+	wsc.mu.Unlock()
+	return nil
+}
+
+// replaceConnection opens a connection with token and moves the session to
+// it before closing the connection served by writer, so the client never
+// leaves the Connected state. Frames already queued on the old connection
+// are written before it closes.
+func (wsc *WebSocketClient) replaceConnection(ctx context.Context, writer *outboundWriter, token *WSToken) error {
+	wsc.mu.Lock()
+	lifetime := wsc.ctx
+	wsc.mu.Unlock()
+
+	conn, err := wsc.dial(ctx, lifetime, token)
+	if err != nil {
+		return err
+	}
+
+	// Frames sent while the new session starts still go to the old connection
+	newWriter, err := wsc.startSession(ctx, lifetime, conn, true)
+	if err != nil {
+		return err
+	}
+
+	wsc.mu.Lock()
+	if wsc.writer != writer || wsc.state != Connected || wsc.ctx != lifetime {
+		wsc.mu.Unlock()
+		newWriter.stop()
+		conn.Close()
+		return NewVocalsError("Connection changed during token refresh", "TOKEN_REFRESH_ABORTED")
+	}
+
+	oldConn := wsc.conn
+	wsc.installConnection(conn, newWriter)
+	wsc.tokenExpiresAt = tokenExpiry(token)
+	wsc.startKeepalive(wsc.conn, wsc.writer)
+	wsc.startTokenRefresh(lifetime, wsc.writer, wsc.tokenExpiresAt)
+	go wsc.messageLoop(lifetime, wsc.conn, wsc.writer)
+	wsc.mu.Unlock()
+
+	if wsc.config.DebugWebsocket {
+		log.Printf("Moved session to a connection with a refreshed token")
+	}
+
+	// The old read loop sees the close but no longer owns the connection,
+	// so it exits without reconnecting
+	drainTimeout := secondsToDuration(wsc.config.WriteTimeout)
+	if drainTimeout <= 0 {
+		drainTimeout = 5 * time.Second
+	}
+	writer.drain(drainTimeout)
+	oldConn.Close()
+	return nil
+}
+
+// refreshDelay returns how long to wait before renewing a token expiring
+// at expiresAt: buffer ahead of expiry, or halfway there when the token
+// lives shorter than the buffer
+func refreshDelay(expiresAt time.Time, buffer time.Duration) time.Duration {
+	remaining := time.Until(expiresAt)
+	if remaining <= 0 {
+		return 0
+	}
+	if buffer >= remaining {
+		return remaining / 2
+	}
+	return remaining - buffer
+}
@@ -0,0 +1,132 @@
+package vocals_test
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rojolang/vocals-sdk-go/pkg/vocals"
+	"github.com/rojolang/vocals-sdk-go/pkg/vocalstest"
+)
+
+// refreshConfig returns a config whose first token expires shortly after
+// connecting and whose later tokens never expire
+func refreshConfig(srv *vocalstest.Server, mode string) *vocals.VocalsConfig {
+	var fetches atomic.Int32
+	config := srv.Config()
+	config.UseTokenAuth = true
+	config.TokenRefreshMode = mode
+	config.TokenRefreshBuffer = 1
+	config.TokenSource = vocals.TokenSourceFunc(func(ctx context.Context) (*vocals.WSToken, error) {
+		n := fetches.Add(1)
+		token := &vocals.WSToken{Token: fmt.Sprintf("token-%d", n)}
+		if n == 1 {
+			token.ExpiresAt = time.Now().Add(1200 * time.Millisecond).UnixMilli()
+		}
+		return token, nil
+	})
+	return config
+}
+
+func TestTokenRefreshByReconnect(t *testing.T) {
+	srv := newTestServer(t)
+	config := refreshConfig(srv, vocals.TokenRefreshReconnect)
+	client := vocals.NewWebSocketClient(config, nil)
+	defer client.Disconnect()
+	refreshed := make(chan vocals.TokenRefreshEvent, 10)
+	client.AddTokenRefreshHandler(func(event vocals.TokenRefreshEvent) { refreshed <- event })
+	states := make(chan vocals.ConnectionState, 10)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	client.AddConnectionHandler(func(state vocals.ConnectionState) { states <- state })
+
+	event := waitFor(t, refreshed, "the token refresh")
+	if !event.Success || event.Method != vocals.TokenRefreshReconnect || !event.ExpiresAt.IsZero() {
+		t.Fatalf("refresh = %+v, want a reconnect to a token without expiry", event)
+	}
+	starts, err := srv.WaitForEvent("start", 2, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var start map[string]interface{}
+	if err := starts[1].DecodeData(&start); err != nil || start["resume"] != true {
+		t.Errorf("start on the new connection = %v (%v), want resume", start, err)
+	}
+	if auth := srv.ConnectHeaders()[1].Get("Authorization"); auth == "Bearer token-1" {
+		t.Error("new connection reused the expiring token")
+	}
+
+	// A token without expiry is never refreshed again
+	time.Sleep(300 * time.Millisecond)
+	if n := srv.ConnectionCount(); n != 2 {
+		t.Errorf("%d connections opened, want 2", n)
+	}
+	if n := srv.ActiveConnections(); n != 1 {
+		t.Errorf("%d connections open, want only the new one", n)
+	}
+	select {
+	case state := <-states:
+		t.Errorf("client went %s while moving to the new connection", state)
+	default:
+	}
+}
+
+func TestTokenRefreshByReauth(t *testing.T) {
+	srv := newTestServer(t)
+	srv.On("reauth", vocalstest.Response("ok"))
+	config := refreshConfig(srv, vocals.TokenRefreshReauth)
+	client := vocals.NewWebSocketClient(config, nil)
+	defer client.Disconnect()
+	refreshed := make(chan vocals.TokenRefreshEvent, 10)
+	client.AddTokenRefreshHandler(func(event vocals.TokenRefreshEvent) { refreshed <- event })
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+
+	event := waitFor(t, refreshed, "the token refresh")
+	if !event.Success || event.Method != vocals.TokenRefreshReauth {
+		t.Fatalf("refresh = %+v, want a successful reauth", event)
+	}
+	frames, err := srv.WaitForEvent("reauth", 1, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var data map[string]interface{}
+	if err := frames[0].DecodeData(&data); err != nil || data["token"] == "token-1" {
+		t.Errorf("reauth sent %v (%v), want a new token", data, err)
+	}
+
+	time.Sleep(300 * time.Millisecond)
+	if n := len(srv.ReceivedEvents("reauth")); n != 1 {
+		t.Errorf("sent %d reauth events, want 1 for a token without expiry", n)
+	}
+	if n := srv.ConnectionCount(); n != 1 {
+		t.Errorf("%d connections opened, want the original only", n)
+	}
+}
+
+func TestTokenRefreshFallsBackToReconnect(t *testing.T) {
+	srv := newTestServer(t)
+	srv.On("reauth", vocalstest.Error("REAUTH_UNSUPPORTED", "not supported"))
+	config := refreshConfig(srv, vocals.TokenRefreshReauth)
+	client := vocals.NewWebSocketClient(config, nil)
+	defer client.Disconnect()
+	refreshed := make(chan vocals.TokenRefreshEvent, 10)
+	client.AddTokenRefreshHandler(func(event vocals.TokenRefreshEvent) { refreshed <- event })
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+
+	if event := waitFor(t, refreshed, "the token refresh"); !event.Success || event.Method != vocals.TokenRefreshReconnect {
+		t.Fatalf("refresh = %+v, want a reconnect after the refused reauth", event)
+	}
+	if n := srv.ConnectionCount(); n != 2 {
+		t.Errorf("%d connections opened, want 2", n)
+	}
+	if !client.IsConnected() {
+		t.Error("client disconnected by the refresh")
+	}
+}
@@ -0,0 +1,217 @@
+package vocals
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// TokenSource supplies the bearer token used to open connections. A token
+// with ExpiresAt 0 never expires.
+type TokenSource interface {
+	Token(ctx context.Context) (*WSToken, error)
+}
+
+// TokenSourceFunc adapts a function to TokenSource
+type TokenSourceFunc func(ctx context.Context) (*WSToken, error)
+
+// Token calls f
+func (f TokenSourceFunc) Token(ctx context.Context) (*WSToken, error) {
+	return f(ctx)
+}
+
+// NewStaticTokenSource always returns token, which is assumed not to expire
+func NewStaticTokenSource(token string) TokenSource {
+	return TokenSourceFunc(func(ctx context.Context) (*WSToken, error) {
+		return &WSToken{Token: token}, nil
+	})
+}
+
+// NewAPIKeyTokenSource signs tokens locally with apiKey. An empty apiKey
+// reads VOCALS_DEV_API_KEY each time a token is needed.
+func NewAPIKeyTokenSource(apiKey string, userID *string) TokenSource {
+	return TokenSourceFunc(func(ctx context.Context) (*WSToken, error) {
+		tokenResult := generateWsTokenWithKey(apiKey, userID)
+		if !tokenResult.Success {
+			return nil, tokenResult.Error
+		}
+		return tokenResult.Data, nil
+	})
+}
+
+// NewEndpointTokenSource requests tokens from an HTTP endpoint that answers a
+// POST with {"token": ..., "expiresAt": <unix ms>}
+func NewEndpointTokenSource(endpoint string, headers map[string]string) TokenSource {
+	return NewEndpointTokenSourceWithClient(endpoint, headers, nil)
+}
+
+// NewEndpointTokenSourceWithClient requests tokens through client, or
+// through a shared client with a 30 second timeout when client is nil
+func NewEndpointTokenSourceWithClient(endpoint string, headers map[string]string, client *http.Client) TokenSource {
+	return TokenSourceFunc(func(ctx context.Context) (*WSToken, error) {
+		return fetchEndpointToken(ctx, client, endpoint, headers)
+	})
+}
+
+// NewAPIClientTokenSource requests tokens through client
+func NewAPIClientTokenSource(client *APIClient, userID *string) TokenSource {
+	return TokenSourceFunc(func(ctx context.Context) (*WSToken, error) {
+		var tokenResult Result[*WSToken]
+		if userID != nil {
+			tokenResult = client.GenerateWsTokenWithUserId(*userID)
+		} else {
+			tokenResult = client.GenerateWsToken()
+		}
+		if !tokenResult.Success {
+			return nil, tokenResult.Error
+		}
+		return tokenResult.Data, nil
+	})
+}
+
+// CachingTokenSource reuses the token of another source until it is close
+// to expiring. Within twice the refresh buffer of expiry the cached token is
+// still returned while a new one is fetched in the background; within the
+// buffer callers wait for the new token. Concurrent callers share a single
+// fetch.
+type CachingTokenSource struct {
+	source        TokenSource
+	refreshBuffer time.Duration
+	token         *WSToken
+	flight        *tokenFlight // Fetch in progress, if any
+	mu            sync.Mutex
+}
+
+// tokenFlight is a fetch shared by every caller that needs its result
+type tokenFlight struct {
+	done  chan struct{}
+	token *WSToken
+	err   error
+}
+
+// NewCachingTokenSource caches the tokens of source
+func NewCachingTokenSource(source TokenSource, refreshBuffer time.Duration) *CachingTokenSource {
+	return &CachingTokenSource{source: source, refreshBuffer: refreshBuffer}
+}
+
+// Token returns the cached token or waits for a fresh one
+func (s *CachingTokenSource) Token(ctx context.Context) (*WSToken, error) {
+	s.mu.Lock()
+	if token := s.token; token != nil {
+		remaining := tokenRemaining(token)
+		if remaining > 2*s.refreshBuffer {
+			s.mu.Unlock()
+			return token, nil
+		}
+		if remaining > s.refreshBuffer {
+			// Refresh ahead without making this caller wait
+			s.startFetchLocked(ctx)
+			s.mu.Unlock()
+			return token, nil
+		}
+	}
+	flight := s.startFetchLocked(ctx)
+	s.mu.Unlock()
+
+	select {
+	case <-flight.done:
+		return flight.token, flight.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Clear drops the cached token so the next call fetches a new one, for
+// example after the server rejected it
+func (s *CachingTokenSource) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.token = nil
+}
+
+// startFetchLocked joins the fetch in progress or starts one. The fetch
+// keeps ctx's values but not its cancellation, since other callers may be
+// waiting for it. The caller holds s.mu.
+func (s *CachingTokenSource) startFetchLocked(ctx context.Context) *tokenFlight {
+	if s.flight != nil {
+		return s.flight
+	}
+
+	flight := &tokenFlight{done: make(chan struct{})}
+	s.flight = flight
+	go func() {
+		token, err := s.source.Token(context.WithoutCancel(ctx))
+		if err == nil && token == nil {
+			err = NewVocalsError("Token source returned no token", ErrCodeAuthFailed)
+		}
+
+		s.mu.Lock()
+		if err == nil {
+			s.token = token
+		} else if s.token != nil {
+			log.Printf("Token refresh failed: %v", err)
+		}
+		s.flight = nil
+		s.mu.Unlock()
+
+		flight.token, flight.err = token, err
+		close(flight.done)
+	}()
+	return flight
+}
+
+// tokenExpiry returns when token expires, or the zero time for tokens that
+// never expire
+func tokenExpiry(token *WSToken) time.Time {
+	if token == nil || token.ExpiresAt == 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(token.ExpiresAt)
+}
+
+// tokenRemaining returns how long token stays valid
+func tokenRemaining(token *WSToken) time.Duration {
+	if token.ExpiresAt == 0 {
+		return time.Duration(1<<63 - 1)
+	}
+	return time.Until(time.UnixMilli(token.ExpiresAt))
+}
+
+// tokenSource builds the token source described by the configuration, or
+// returns nil when token auth is disabled
+func (c *VocalsConfig) tokenSource(userID *string) TokenSource {
+	if !c.UseTokenAuth {
+		return nil
+	}
+
+	var source TokenSource
+	switch {
+	case c.TokenSource != nil:
+		if caching, ok := c.TokenSource.(*CachingTokenSource); ok {
+			return caching
+		}
+		source = c.TokenSource
+	case c.TokenEndpoint != nil:
+		source = NewEndpointTokenSourceWithClient(*c.TokenEndpoint, c.Headers, c.HTTPClient)
+	default:
+		source = NewAPIKeyTokenSource(c.APIKey, userID)
+	}
+	return NewCachingTokenSource(source, secondsToDuration(c.TokenRefreshBuffer))
+}
+
+// describeTokenSource names the configured token source for PrintConfig
+func (c *VocalsConfig) describeTokenSource() string {
+	switch {
+	case !c.UseTokenAuth:
+		return "disabled"
+	case c.TokenSource != nil:
+		return fmt.Sprintf("custom (%T)", c.TokenSource)
+	case c.TokenEndpoint != nil:
+		return "endpoint " + *c.TokenEndpoint
+	default:
+		return "API key"
+	}
+}
@@ -0,0 +1,58 @@
+package vocals_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rojolang/vocals-sdk-go/pkg/vocals"
+)
+
+func TestConnectRetriesRejectedTokenOnce(t *testing.T) {
+	srv := newTestServer(t)
+	var fetches atomic.Int32
+	config := srv.Config()
+	config.UseTokenAuth = true
+	config.TokenSource = vocals.TokenSourceFunc(func(ctx context.Context) (*vocals.WSToken, error) {
+		n := fetches.Add(1)
+		return &vocals.WSToken{Token: fmt.Sprintf("token-%d", n), ExpiresAt: time.Now().Add(time.Hour).UnixMilli()}, nil
+	})
+
+	srv.RejectConnectionsWithStatus(1, http.StatusUnauthorized)
+	connectClient(t, config)
+
+	if n := fetches.Load(); n != 2 {
+		t.Errorf("fetched %d tokens, want 2", n)
+	}
+	if headers := srv.ConnectHeaders(); len(headers) != 1 || headers[0].Get("Authorization") != "Bearer token-2" {
+		t.Errorf("accepted connection used %v, want the fresh token", headers)
+	}
+}
+
+func TestConnectStopsAfterRejectedFreshToken(t *testing.T) {
+	srv := newTestServer(t)
+	var fetches atomic.Int32
+	config := srv.Config()
+	config.UseTokenAuth = true
+	config.TokenSource = vocals.TokenSourceFunc(func(ctx context.Context) (*vocals.WSToken, error) {
+		fetches.Add(1)
+		return &vocals.WSToken{Token: "revoked", ExpiresAt: time.Now().Add(time.Hour).UnixMilli()}, nil
+	})
+
+	srv.RejectConnectionsWithStatus(100, http.StatusUnauthorized)
+	client := vocals.NewWebSocketClient(config, nil)
+	defer client.Disconnect()
+
+	err := client.Connect()
+	var vErr *vocals.VocalsError
+	if !errors.As(err, &vErr) || vErr.Code != vocals.ErrCodeAuthRejected {
+		t.Fatalf("Connect = %v, want %s", err, vocals.ErrCodeAuthRejected)
+	}
+	if n := fetches.Load(); n != 2 {
+		t.Errorf("fetched %d tokens, want 2", n)
+	}
+}
@@ -0,0 +1,108 @@
+package vocals
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// FrameType identifies the payload type of a transport frame
+type FrameType int
+
+const (
+	TextFrame   FrameType = FrameType(websocket.TextMessage)
+	BinaryFrame FrameType = FrameType(websocket.BinaryMessage)
+)
+
+// Transport dials connections to the Vocals streaming endpoint.
+// The default implementation uses gorilla/websocket; alternative
+// implementations can provide in-memory pipes for tests, recording
+// wrappers or custom network stacks.
+type Transport interface {
+	Dial(ctx context.Context, endpoint string, header http.Header) (TransportConn, error)
+}
+
+// TransportConn is a single established connection returned by a Transport.
+// ReadFrame is only called from one goroutine at a time, and the same holds
+// for WriteFrame.
+type TransportConn interface {
+	ReadFrame() (FrameType, []byte, error)
+	WriteFrame(frameType FrameType, data []byte) error
+	Close() error
+}
+
+// PingConn is implemented by connections that support WebSocket ping/pong.
+// The client uses it for keepalive; WritePing may be called concurrently
+// with WriteFrame.
+type PingConn interface {
+	WritePing(data []byte, deadline time.Time) error
+	SetPongHandler(handler func(data []byte))
+}
+
+// DeadlineConn is implemented by connections that support write deadlines
+type DeadlineConn interface {
+	SetWriteDeadline(t time.Time) error
+}
+
+// GorillaTransport is the default Transport backed by gorilla/websocket
+type GorillaTransport struct {
+	Dialer *websocket.Dialer
+}
+
+// NewGorillaTransport creates a transport using websocket.DefaultDialer
+func NewGorillaTransport() *GorillaTransport {
+	return &GorillaTransport{Dialer: websocket.DefaultDialer}
+}
+
+func (t *GorillaTransport) Dial(ctx context.Context, endpoint string, header http.Header) (TransportConn, error) {
+	dialer := t.Dialer
+	if dialer == nil {
+		dialer = websocket.DefaultDialer
+	}
+
+	conn, resp, err := dialer.DialContext(ctx, endpoint, header)
+	if err != nil {
+		if resp != nil && (resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden) {
+			return nil, NewVocalsError(fmt.Sprintf("Connection rejected: %s", resp.Status), ErrCodeAuthRejected).
+				AddDetail("status", resp.StatusCode)
+		}
+		return nil, err
+	}
+	return &gorillaConn{conn: conn}, nil
+}
+
+// gorillaConn adapts *websocket.Conn to TransportConn
+type gorillaConn struct {
+	conn *websocket.Conn
+}
+
+func (c *gorillaConn) ReadFrame() (FrameType, []byte, error) {
+	messageType, data, err := c.conn.ReadMessage()
+	return FrameType(messageType), data, err
+}
+
+func (c *gorillaConn) WriteFrame(frameType FrameType, data []byte) error {
+	return c.conn.WriteMessage(int(frameType), data)
+}
+
+func (c *gorillaConn) WritePing(data []byte, deadline time.Time) error {
+	return c.conn.WriteControl(websocket.PingMessage, data, deadline)
+}
+
+func (c *gorillaConn) SetPongHandler(handler func(data []byte)) {
+	c.conn.SetPongHandler(func(appData string) error {
+		handler([]byte(appData))
+		return nil
+	})
+}
+
+func (c *gorillaConn) SetWriteDeadline(t time.Time) error {
+	return c.conn.SetWriteDeadline(t)
+}
+
+func (c *gorillaConn) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,153 @@
+package vocals
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// pipeTransport is an in-memory Transport for tests inside the package,
+// which cannot use vocalstest without an import cycle
+type pipeTransport struct {
+	conns chan *pipeConn
+	gate  chan struct{} // Gate of the connections it dials
+}
+
+func newPipeTransport() *pipeTransport {
+	gate := make(chan struct{})
+	close(gate)
+	return &pipeTransport{conns: make(chan *pipeConn, 10), gate: gate}
+}
+
+func (p *pipeTransport) Dial(ctx context.Context, endpoint string, header http.Header) (TransportConn, error) {
+	conn := &pipeConn{
+		sent:     make(chan WebSocketMessage, 100),
+		incoming: make(chan []byte, 100),
+		closed:   make(chan struct{}),
+		gate:     p.gate,
+	}
+	p.conns <- conn
+	return conn, nil
+}
+
+// pipeConn hands written messages to the test and reads the frames the
+// test pushes
+type pipeConn struct {
+	sent      chan WebSocketMessage
+	incoming  chan []byte
+	closed    chan struct{}
+	closeOnce sync.Once
+	gate      chan struct{} // Writes wait until it is closed
+	mu        sync.Mutex
+}
+
+func (c *pipeConn) ReadFrame() (FrameType, []byte, error) {
+	select {
+	case data := <-c.incoming:
+		return TextFrame, data, nil
+	case <-c.closed:
+		return 0, nil, errors.New("pipe closed")
+	}
+}
+
+func (c *pipeConn) WriteFrame(frameType FrameType, data []byte) error {
+	c.mu.Lock()
+	gate := c.gate
+	c.mu.Unlock()
+	select {
+	case <-gate:
+	case <-c.closed:
+		return errors.New("pipe closed")
+	}
+
+	var message WebSocketMessage
+	if frameType == TextFrame {
+		if err := json.Unmarshal(data, &message); err != nil {
+			return err
+		}
+	} else {
+		message.Event = "binary"
+	}
+	select {
+	case c.sent <- message:
+	case <-c.closed:
+		return errors.New("pipe closed")
+	}
+	return nil
+}
+
+func (c *pipeConn) Close() error {
+	c.closeOnce.Do(func() { close(c.closed) })
+	return nil
+}
+
+// stall makes writes block until the returned function is called, like a
+// peer that stopped reading
+func (c *pipeConn) stall() func() {
+	gate := make(chan struct{})
+	c.mu.Lock()
+	c.gate = gate
+	c.mu.Unlock()
+	var once sync.Once
+	return func() { once.Do(func() { close(gate) }) }
+}
+
+// reply pushes a server message to the client
+func (c *pipeConn) reply(t *testing.T, id, messageType string, data interface{}) {
+	t.Helper()
+	frame, err := json.Marshal(map[string]interface{}{"id": id, "type": messageType, "data": data})
+	if err != nil {
+		t.Fatalf("encoding reply: %v", err)
+	}
+	c.incoming <- frame
+}
+
+// next returns the next message the client wrote with the given event
+func (c *pipeConn) next(t *testing.T, event string) WebSocketMessage {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case message := <-c.sent:
+			if message.Event == event {
+				return message
+			}
+		case <-timeout:
+			t.Fatalf("client did not send a %s event", event)
+		}
+	}
+}
+
+// newPipeClient returns a connected client and the server end of its
+// connection
+func newPipeClient(t *testing.T, configure func(*VocalsConfig)) (*WebSocketClient, *pipeConn) {
+	t.Helper()
+	client, transport := newPipeClientWithTransport(t, configure)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	return client, <-transport.conns
+}
+
+// newPipeClientWithTransport returns a client that is not connected yet and
+// the transport it dials
+func newPipeClientWithTransport(t *testing.T, configure func(*VocalsConfig)) (*WebSocketClient, *pipeTransport) {
+	t.Helper()
+	transport := newPipeTransport()
+	config := NewVocalsConfig()
+	endpoint := "ws://pipe"
+	config.WsEndpoint = &endpoint
+	config.UseTokenAuth = false
+	config.Transport = transport
+	if configure != nil {
+		configure(config)
+	}
+
+	client := NewWebSocketClient(config, nil)
+	t.Cleanup(client.Disconnect)
+	return client, transport
+}
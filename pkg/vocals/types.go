@@ -157,17 +157,21 @@ type SpeechInterruptionData struct {
 
 // WebSocketMessage struct
 type WebSocketMessage struct {
+	ID         string      `json:"id,omitempty"` // Echoed by the server in replies, see Request
 	Event      string      `json:"event"`
 	Data       interface{} `json:"data"`
 	Format     *string     `json:"format,omitempty"`
 	SampleRate *int        `json:"sampleRate,omitempty"`
+	CapturedAt time.Time   `json:"-"` // Capture time of media audio, zero for the time it is sent
 }
 
 // WebSocketResponse struct
 type WebSocketResponse struct {
-	Event string
-	Data  any
-	Type  *string
+	ID      string // ID of the message this replies to, if any
+	Event   string
+	Data    any
+	Type    *string
+	rawData []byte // Undecoded data payload, see Decode
 }
 
 // User struct for API operations
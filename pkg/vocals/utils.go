@@ -2,6 +2,7 @@ package vocals
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/binary"
 	"io/ioutil"
@@ -43,27 +44,24 @@ func CreateDefaultMessageHandler(verbose bool) MessageHandler {
 		}
 		
 		// Basic logging for different types
-		if message.Type != nil {
-			switch *message.Type {
-			case "transcription":
-				if data, ok := message.Data.(map[string]interface{}); ok {
-					if text := getString(data, "text"); text != "" {
-						log.Printf("Transcription: %s", text)
-					}
-				}
-			case "response":
-				if data, ok := message.Data.(map[string]interface{}); ok {
-					if text := getString(data, "text"); text != "" {
-						log.Printf("AI Response: %s", text)
-					}
-				}
-			case "tts_audio":
-				if data, ok := message.Data.(map[string]interface{}); ok {
-					segmentID := getString(data, "segment_id")
-					sentenceNumber := getInt(data, "sentence_number")
-					log.Printf("TTS Audio: segment %s-%d", segmentID, sentenceNumber)
-				}
+		if message.Type == nil {
+			return
+		}
+		event, err := message.Decode()
+		if err != nil {
+			return
+		}
+		switch e := event.(type) {
+		case TranscriptionEvent:
+			if e.Text != "" {
+				log.Printf("Transcription: %s", e.Text)
+			}
+		case ResponseEvent:
+			if e.Text != "" {
+				log.Printf("AI Response: %s", e.Text)
 			}
+		case TTSAudioEvent:
+			log.Printf("TTS Audio: segment %s-%d", e.SegmentID, e.SentenceNumber)
 		}
 	}
 }
@@ -186,19 +184,25 @@ func GetTokenTimeLeft(token *WSToken) time.Duration {
 }
 
 // Message creation helpers
+
+// CreateAudioMessage encodes float32 samples in format for a media event.
+// An empty or unsupported format sends pcm_f32le.
 func CreateAudioMessage(audioData []float32, sampleRate int, format string) *WebSocketMessage {
-	// Convert float32 samples to raw bytes
-	buf := new(bytes.Buffer)
-	for _, sample := range audioData {
-		bits := math.Float32bits(sample)
-		binary.Write(buf, binary.LittleEndian, bits)
+	if format == "" {
+		format = FormatPCMF32LE
 	}
-	
+	data, err := EncodePCM(audioData, format)
+	if err != nil {
+		log.Printf("Sending %s audio instead: %v", FormatPCMF32LE, err)
+		format = FormatPCMF32LE
+		data, _ = EncodePCM(audioData, format)
+	}
+
 	sampleRatePtr := &sampleRate
 	formatPtr := &format
 	return &WebSocketMessage{
 		Event:      "media",
-		Data:       buf.Bytes(),  // Raw []byte - JSON marshaler will auto-base64 it
+		Data:       data, // Raw []byte - JSON marshaler will auto-base64 it
 		Format:     formatPtr,
 		SampleRate: sampleRatePtr,
 	}
@@ -216,6 +220,7 @@ func CreateRawAudioBytes(audioData []float32) []byte {
 
 func CreateTextMessage(text string) *WebSocketMessage {
 	return &WebSocketMessage{
+		ID:    NewMessageID(),
 		Event: "text_input",
 		Data: map[string]interface{}{
 			"text": text,
@@ -231,6 +236,7 @@ func CreateControlMessage(command string, params map[string]interface{}) *WebSoc
 		data[k] = v
 	}
 	return &WebSocketMessage{
+		ID:    NewMessageID(),
 		Event: "control",
 		Data:  data,
 	}
@@ -319,22 +325,39 @@ func ValidateAudioConfig(config *AudioConfig) error {
 	if config.BufferSize <= 0 {
 		return NewVocalsError("Invalid buffer size", "INVALID_BUFFER_SIZE")
 	}
+	if config.Format != "" && !IsSupportedAudioFormat(config.Format) {
+		return NewVocalsError("Unsupported audio format: "+config.Format, "UNSUPPORTED_AUDIO_FORMAT")
+	}
+	if config.DeviceSampleRate < 0 || config.PlaybackSampleRate < 0 {
+		return NewVocalsError("Invalid sample rate", "INVALID_SAMPLE_RATE")
+	}
+	if _, ok := resampleProfiles[config.ResampleQuality]; config.ResampleQuality != "" && !ok {
+		return NewVocalsError("Invalid resample quality", "INVALID_RESAMPLE_QUALITY")
+	}
 	return nil
 }
 
 func ValidateVocalsConfig(config *VocalsConfig) error {
-	if config.MaxReconnectAttempts < 0 {
-		return NewVocalsError("Invalid max reconnect attempts", "INVALID_RECONNECT_ATTEMPTS")
-	}
-	if config.ReconnectDelay < 0 {
-		return NewVocalsError("Invalid reconnect delay", "INVALID_RECONNECT_DELAY")
-	}
-	if config.TokenRefreshBuffer < 0 {
-		return NewVocalsError("Invalid token refresh buffer", "INVALID_TOKEN_REFRESH_BUFFER")
+	if issues := config.fieldIssues(); len(issues) > 0 {
+		return NewVocalsError(issues[0].message, issues[0].code)
 	}
 	return nil
 }
 
+// sleepContext waits for d or until ctx is done, returning ctx.Err() in
+// the latter case
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // Type conversion utilities (duplicated here for completeness)
 func getStringUtil(data map[string]interface{}, key string) string {
 	if val, ok := data[key]; ok {
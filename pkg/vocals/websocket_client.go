@@ -2,246 +2,667 @@ package vocals
 
 import (
 	"context"
-
+	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
 	"sync"
 	"time"
-
-	"github.com/gorilla/websocket"
 )
 
 type WebSocketClient struct {
 	config             *VocalsConfig
 	userID             *string
-	tokenManager       *TokenManager
-	conn               *websocket.Conn
+	tokenSource        TokenSource // nil when token auth is disabled
+	tokenExpiresAt     time.Time   // Expiry of the current connection's token, zero if none
+	transport          Transport
+	recorder           *Recorder // Set when RecordPath is configured
+	conn               TransportConn
+	writer             *outboundWriter // Single writer for the current connection
+	outbound           outboundCounters
+	metrics            connectionMetrics
 	state              ConnectionState
-	messageHandlers    []MessageHandler
-	connectionHandlers []ConnectionHandler
-	errorHandlers      []ErrorHandler
+	messageHandlers    *dispatcher[*WebSocketResponse, MessageHandler]
+	eventHandlers      *dispatcher[Event, EventHandler]
+	connectionHandlers *dispatcher[ConnectionState, ConnectionHandler]
+	errorHandlers      *dispatcher[*VocalsError, ErrorHandler]
+	reconnectHandlers  *dispatcher[ReconnectEvent, ReconnectHandler]
+	resumeHandlers     *dispatcher[ResumeEvent, ResumeHandler]
+	refreshHandlers    *dispatcher[TokenRefreshEvent, TokenRefreshHandler]
+	middlewares        handlerList[Middleware]
+	requests           pendingRequests // Request calls waiting for a reply
+	sessionSetup       SessionSetupFunc
+	sessionID          string    // Server session ID, sent back when resuming
+	resuming           bool      // Set while reconnecting after a dropped connection
+	disconnectedAt     time.Time // When the current outage started
 	reconnectAttempts  int
+	connectAttempts    int // Attempts used by the last connectWithRetry
 	shouldReconnect    bool
+	binaryMedia        bool   // Negotiated with the server for the current connection
+	mediaSequence      uint32 // Sequence number of the next binary media frame
 	ctx                context.Context
 	cancel             context.CancelFunc
 	mu                 sync.Mutex
+	outbox             []func()   // Handler deliveries queued while mu was held
+	outboxMu           sync.Mutex // Guards outbox
+	emitMu             sync.Mutex // Held by the goroutine delivering the outbox
 }
 
 func NewWebSocketClient(config *VocalsConfig, userID *string) *WebSocketClient {
 	ctx, cancel := context.WithCancel(context.Background())
 
-	var tokenManager *TokenManager
-	if config.TokenEndpoint != nil {
-		tokenManager = NewTokenManager(*config.TokenEndpoint, config.Headers, config.TokenRefreshBuffer)
+	transport := config.Transport
+	if transport == nil {
+		transport = NewGorillaTransport()
+	}
+
+	var recorder *Recorder
+	if config.RecordPath != "" {
+		var err error
+		if recorder, err = CreateRecorder(config.RecordPath); err != nil {
+			log.Printf("Session recording disabled: %v", err)
+		} else {
+			transport = NewRecordingTransport(transport, recorder)
+		}
 	}
 
-	return &WebSocketClient{
-		config:             config,
-		userID:             userID,
-		tokenManager:       tokenManager,
-		state:              Disconnected,
-		messageHandlers:    []MessageHandler{},
-		connectionHandlers: []ConnectionHandler{},
-		errorHandlers:      []ErrorHandler{},
-		shouldReconnect:    true,
-		ctx:                ctx,
-		cancel:             cancel,
+	wsc := &WebSocketClient{
+		config:          config,
+		userID:          userID,
+		tokenSource:     config.tokenSource(userID),
+		transport:       transport,
+		recorder:        recorder,
+		state:           Disconnected,
+		shouldReconnect: true,
+		ctx:             ctx,
+		cancel:          cancel,
 	}
+
+	// Panics and overflowing queues in handlers are reported to the error
+	// handlers; a panicking or overflowing error handler is only logged
+	mode, queueSize, policy := config.DispatchMode, config.HandlerQueueSize, config.HandlerOverflowPolicy
+	wsc.messageHandlers = newDispatcher[*WebSocketResponse, MessageHandler]("message handler", mode, queueSize, policy, wsc.handleError)
+	wsc.eventHandlers = newDispatcher[Event, EventHandler]("event handler", mode, queueSize, policy, wsc.handleError)
+	wsc.connectionHandlers = newDispatcher[ConnectionState, ConnectionHandler]("connection handler", mode, queueSize, policy, wsc.handleError)
+	wsc.errorHandlers = newDispatcher[*VocalsError, ErrorHandler]("error handler", mode, queueSize, policy, nil)
+	wsc.reconnectHandlers = newDispatcher[ReconnectEvent, ReconnectHandler]("reconnect handler", mode, queueSize, policy, wsc.handleError)
+	wsc.resumeHandlers = newDispatcher[ResumeEvent, ResumeHandler]("resume handler", mode, queueSize, policy, wsc.handleError)
+	wsc.refreshHandlers = newDispatcher[TokenRefreshEvent, TokenRefreshHandler]("token refresh handler", mode, queueSize, policy, wsc.handleError)
+
+	return wsc
 }
 
 func (wsc *WebSocketClient) Connect() error {
-	wsc.mu.Lock()
-	defer wsc.mu.Unlock()
+	return wsc.ConnectContext(context.Background())
+}
 
-	if wsc.state == Connected || wsc.state == Connecting {
+// ConnectContext connects like Connect but gives up when ctx is cancelled or
+// its deadline passes, returning ctx.Err(). ctx only bounds the connection
+// attempt; the established connection lives until Disconnect.
+func (wsc *WebSocketClient) ConnectContext(ctx context.Context) error {
+	wsc.mu.Lock()
+	if wsc.state == Connected || wsc.state == Connecting || wsc.state == Reconnecting {
+		wsc.mu.Unlock()
 		return fmt.Errorf("already connected or connecting")
 	}
 
+	// A previous Disconnect cancels the context; start a fresh one
+	if wsc.ctx.Err() != nil {
+		wsc.ctx, wsc.cancel = context.WithCancel(context.Background())
+		wsc.shouldReconnect = true
+	}
+
 	wsc.setState(Connecting)
 	wsc.reconnectAttempts = 0
+	lifetime := wsc.ctx
+	wsc.unlock()
 
-	return wsc.connectWithRetry()
+	return wsc.connectWithRetry(ctx, lifetime)
 }
 
-func (wsc *WebSocketClient) connectWithRetry() error {
-	for wsc.reconnectAttempts < wsc.config.MaxReconnectAttempts {
-		if err := wsc.performConnection(); err != nil {
-			wsc.reconnectAttempts++
-			if wsc.reconnectAttempts >= wsc.config.MaxReconnectAttempts {
-				wsc.setState(ErrorState)
-				wsc.handleError(NewVocalsError(fmt.Sprintf("Max reconnect attempts reached: %v", err), "CONNECTION_FAILED"))
-				return err
-			}
+// connectWithRetry connects until an attempt succeeds, the attempts or
+// time run out, ctx is done or lifetime ends with Disconnect. wsc.mu is
+// only held to read and update the client's state, never while dialing or
+// backing off, so IsConnected and audio capture keep running during an
+// outage. The caller does not hold wsc.mu.
+func (wsc *WebSocketClient) connectWithRetry(ctx, lifetime context.Context) error {
+	policy := wsc.config.GetReconnectPolicy()
+	maxElapsed := secondsToDuration(wsc.config.MaxReconnectElapsed)
+	start := time.Now()
+	var delay time.Duration
 
-			if wsc.config.DebugWebsocket {
-				log.Printf("Connection attempt %d failed, retrying in %.1fs: %v", wsc.reconnectAttempts, wsc.config.ReconnectDelay, err)
+	for {
+		wsc.mu.Lock()
+		if lifetime.Err() != nil {
+			wsc.stopConnecting(lifetime)
+			wsc.unlock()
+			return lifetime.Err()
+		}
+		if wsc.reconnectAttempts >= wsc.config.MaxReconnectAttempts {
+			wsc.unlock()
+			return fmt.Errorf("failed to connect after %d attempts", wsc.config.MaxReconnectAttempts)
+		}
+		wsc.connectAttempts = wsc.reconnectAttempts + 1
+		wsc.unlock()
+
+		token, conn, err := wsc.openConnection(ctx, lifetime)
+		var writer *outboundWriter
+		if err == nil {
+			writer, err = wsc.startSession(ctx, lifetime, conn, false)
+		}
+
+		wsc.mu.Lock()
+		if lifetime.Err() != nil {
+			// Disconnected while dialing
+			wsc.stopConnecting(lifetime)
+			wsc.unlock()
+			if writer != nil {
+				writer.stop()
 			}
+			if conn != nil {
+				conn.Close()
+			}
+			return lifetime.Err()
+		}
+		if err == nil {
+			wsc.tokenExpiresAt = tokenExpiry(token)
+			wsc.installConnection(conn, writer)
+			wsc.setState(Connected)
+			wsc.reconnectAttempts = 0
+			wsc.startKeepalive(wsc.conn, wsc.writer)
+			wsc.startTokenRefresh(lifetime, wsc.writer, wsc.tokenExpiresAt)
+			go wsc.messageLoop(lifetime, wsc.conn, wsc.writer)
+			wsc.unlock()
+			return nil
+		}
+
+		if ctx.Err() != nil {
+			wsc.setState(Disconnected)
+			wsc.unlock()
+			return ctx.Err()
+		}
 
-			time.Sleep(time.Duration(wsc.config.ReconnectDelay * float64(time.Second)))
-			continue
+		wsc.reconnectAttempts++
+		if vErr, ok := permanentError(err); ok {
+			// A rejected key or a broken token endpoint will not recover by retrying
+			wsc.emitReconnectEvent(ReconnectEvent{
+				Attempt:     wsc.reconnectAttempts,
+				MaxAttempts: wsc.config.MaxReconnectAttempts,
+				Elapsed:     time.Since(start),
+				Err:         err,
+				GivingUp:    true,
+			})
+			wsc.setState(ErrorState)
+			wsc.handleErrorLocked(vErr)
+			wsc.unlock()
+			return err
+		}
+		delay = policy.NextDelay(wsc.reconnectAttempts, delay)
+		elapsed := time.Since(start)
+
+		event := ReconnectEvent{
+			Attempt:     wsc.reconnectAttempts,
+			MaxAttempts: wsc.config.MaxReconnectAttempts,
+			NextDelay:   delay,
+			Elapsed:     elapsed,
+			Err:         err,
+		}
+
+		if wsc.reconnectAttempts >= wsc.config.MaxReconnectAttempts {
+			event.GivingUp = true
+			wsc.emitReconnectEvent(event)
+			wsc.setState(ErrorState)
+			wsc.handleErrorLocked(NewVocalsError(fmt.Sprintf("Max reconnect attempts reached: %v", err), "CONNECTION_FAILED"))
+			wsc.unlock()
+			return err
+		}
+
+		if maxElapsed > 0 && elapsed+delay > maxElapsed {
+			event.GivingUp = true
+			wsc.emitReconnectEvent(event)
+			wsc.setState(ErrorState)
+			wsc.handleErrorLocked(NewVocalsError(fmt.Sprintf("Max reconnect time of %s exceeded: %v", maxElapsed, err), "CONNECTION_FAILED").
+				AddDetail("attempts", wsc.reconnectAttempts))
+			wsc.unlock()
+			return err
+		}
+
+		wsc.emitReconnectEvent(event)
+		wsc.unlock()
+		if wsc.config.DebugWebsocket {
+			log.Printf("Connection attempt %d failed, retrying in %s: %v", event.Attempt, delay, err)
 		}
 
-		wsc.setState(Connected)
-		wsc.reconnectAttempts = 0
-		go wsc.messageLoop()
-		return nil
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			wsc.mu.Lock()
+			wsc.stopConnecting(lifetime)
+			wsc.unlock()
+			return ctx.Err()
+		case <-lifetime.Done():
+			timer.Stop()
+			wsc.mu.Lock()
+			wsc.stopConnecting(lifetime)
+			wsc.unlock()
+			return lifetime.Err()
+		}
 	}
+}
 
-	return fmt.Errorf("failed to connect after %d attempts", wsc.config.MaxReconnectAttempts)
+// stopConnecting leaves the client Disconnected when a connect attempt
+// ends early, unless a newer Connect already owns the client. The caller
+// holds wsc.mu.
+func (wsc *WebSocketClient) stopConnecting(lifetime context.Context) {
+	if wsc.ctx == lifetime {
+		wsc.setState(Disconnected)
+	}
 }
 
-func (wsc *WebSocketClient) performConnection() error {
-	var token string
-	var err error
+// openConnection fetches a token and dials with it. A cached token the
+// server rejects is dropped and the dial retried once with a fresh token;
+// only a rejected fresh token is final. The caller does not hold wsc.mu.
+func (wsc *WebSocketClient) openConnection(ctx, lifetime context.Context) (*WSToken, TransportConn, error) {
+	token, err := wsc.fetchToken(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
 
-	if wsc.config.UseTokenAuth {
-		if wsc.tokenManager != nil {
-			token, err = wsc.tokenManager.GetToken()
-			if err != nil {
-				return fmt.Errorf("failed to get token: %v", err)
-			}
-		} else {
-			// Generate token from API key
-			var tokenResult Result[*WSToken]
-			if wsc.userID != nil {
-				tokenResult = GenerateWsTokenWithUserId(*wsc.userID)
-			} else {
-				tokenResult = GenerateWsToken()
-			}
+	conn, err := wsc.dial(ctx, lifetime, token)
+	if vErr, ok := permanentError(err); ok && vErr.Code == ErrCodeAuthRejected {
+		caching, ok := wsc.tokenSource.(*CachingTokenSource)
+		if !ok {
+			return nil, nil, err
+		}
 
-			if !tokenResult.Success {
-				return fmt.Errorf("failed to generate token: %v", tokenResult.Error.Message)
-			}
-			token = tokenResult.Data.Token
+		// Never offer a rejected token again
+		caching.Clear()
+		if token, err = wsc.fetchToken(ctx); err != nil {
+			return nil, nil, err
+		}
+		conn, err = wsc.dial(ctx, lifetime, token)
+		if vErr, ok := permanentError(err); ok && vErr.Code == ErrCodeAuthRejected {
+			caching.Clear()
 		}
 	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return token, conn, nil
+}
+
+// fetchToken returns the token for the next connection, or nil when token
+// auth is disabled
+func (wsc *WebSocketClient) fetchToken(ctx context.Context) (*WSToken, error) {
+	if wsc.tokenSource == nil {
+		return nil, nil
+	}
+	token, err := wsc.tokenSource.Token(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get token: %w", err)
+	}
+	return token, nil
+}
 
+// dial opens a connection authenticated with token. Dialing stops on ctx,
+// when lifetime ends (Disconnect) or at the handshake timeout.
+func (wsc *WebSocketClient) dial(ctx, lifetime context.Context, token *WSToken) (TransportConn, error) {
 	header := make(http.Header)
-	if token != "" {
-		header.Set("Authorization", "Bearer "+token)
+	if token != nil && token.Token != "" {
+		header.Set("Authorization", "Bearer "+token.Token)
 	}
 	for k, v := range wsc.config.Headers {
 		header.Set(k, v)
 	}
 
-	dialer := websocket.DefaultDialer
-	conn, _, err := dialer.Dial(*wsc.config.WsEndpoint, header)
-	if err != nil {
-		return err
+	dialCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stop := context.AfterFunc(lifetime, cancel)
+	defer stop()
+	if wsc.config.HandshakeTimeout > 0 {
+		var cancelTimeout context.CancelFunc
+		dialCtx, cancelTimeout = context.WithTimeout(dialCtx, secondsToDuration(wsc.config.HandshakeTimeout))
+		defer cancelTimeout()
 	}
 
-	wsc.conn = conn
-	
+	return wsc.transport.Dial(dialCtx, *wsc.config.WsEndpoint, header)
+}
+
+// startSession sends the start and session setup events on a new
+// connection and returns its writer once they are written. handover marks
+// a connection that takes over from one that is still open, which is
+// resumed without replaying audio. The caller does not hold wsc.mu, so a
+// peer that stops reading never blocks the rest of the client; the
+// connection is closed when ctx is done or lifetime ends.
+func (wsc *WebSocketClient) startSession(ctx, lifetime context.Context, conn TransportConn, handover bool) (*outboundWriter, error) {
+	writer := newOutboundWriter(conn, wsc.config.OutboundQueueSize, wsc.config.OutboundOverflowPolicy,
+		secondsToDuration(wsc.config.WriteTimeout), &wsc.outbound, wsc.config.DebugWebsocket)
+	abort := func() {
+		writer.stop()
+		conn.Close()
+	}
+	stopCtx := context.AfterFunc(ctx, abort)
+	defer stopCtx()
+	stopLifetime := context.AfterFunc(lifetime, abort)
+	defer stopLifetime()
+
+	wsc.mu.Lock()
+	resuming, sessionID, setup := wsc.resuming, wsc.sessionID, wsc.sessionSetup
+	wsc.mu.Unlock()
+
 	// Send start event immediately after connection
 	if wsc.config.DebugWebsocket {
 		log.Printf("Sending start event after connection")
 	}
+	startData := map[string]interface{}{}
+	if resuming || handover {
+		startData["resume"] = true
+		if sessionID != "" {
+			startData["session_id"] = sessionID
+		}
+	}
+	if wsc.config.MediaMode == MediaModeBinary {
+		// Offer binary media; the server confirms with a media_mode message
+		startData["media_modes"] = []string{MediaModeBinary, MediaModeJSON}
+	}
 	startMsg := &WebSocketMessage{
 		Event: "start",
-		Data:  map[string]interface{}{},
+		Data:  startData,
 	}
-	if err := wsc.conn.WriteJSON(startMsg); err != nil {
-		wsc.conn.Close()
-		return fmt.Errorf("failed to send start event: %v", err)
+	if err := wsc.writeSessionMessage(writer, startMsg); err != nil {
+		abort()
+		return nil, fmt.Errorf("failed to send start event: %v", err)
 	}
-	
-	return nil
+
+	// Replay session configuration such as audio settings and modes
+	if setup != nil {
+		for _, msg := range setup(resuming) {
+			if err := wsc.writeSessionMessage(writer, msg); err != nil {
+				abort()
+				return nil, fmt.Errorf("failed to send %s event: %v", msg.Event, err)
+			}
+		}
+	}
+
+	return writer, nil
 }
 
-func (wsc *WebSocketClient) messageLoop() {
+// installConnection makes conn, whose session was started by writer, the
+// current connection. The caller holds wsc.mu.
+func (wsc *WebSocketClient) installConnection(conn TransportConn, writer *outboundWriter) {
+	wsc.conn = conn
+	wsc.writer = writer
+	wsc.binaryMedia = false
+	wsc.mediaSequence = 0
+}
+
+// messageLoop reads from conn until it fails or lifetime ends with
+// Disconnect. conn is passed in so that a loop for a dropped connection
+// never closes the connection that replaced it.
+func (wsc *WebSocketClient) messageLoop(lifetime context.Context, conn TransportConn, writer *outboundWriter) {
+	closeConn := true
 	defer func() {
-		if wsc.conn != nil {
-			wsc.conn.Close()
+		if closeConn {
+			writer.stop()
+			conn.Close()
 		}
 	}()
 
 	for {
 		select {
-		case <-wsc.ctx.Done():
+		case <-lifetime.Done():
+			// Disconnect closes conn once the stop event is written
+			closeConn = false
+			wsc.mu.Lock()
+			wsc.stopConnecting(lifetime)
+			wsc.unlock()
 			return
 		default:
-			var message WebSocketResponse
-			if err := wsc.conn.ReadJSON(&message); err != nil {
+			_, data, err := conn.ReadFrame()
+			if err != nil {
 				if wsc.config.DebugWebsocket {
 					log.Printf("WebSocket read error: %v", err)
 				}
 
-				if wsc.shouldReconnect && wsc.state == Connected {
+				// Only the loop of the current connection may start a reconnect
+				wsc.mu.Lock()
+				if wsc.conn == conn {
+					// Replies to requests sent on conn can never arrive
+					wsc.requests.abort()
+				}
+				if wsc.shouldReconnect && wsc.state == Connected && wsc.conn == conn {
+					wsc.disconnectedAt = time.Now()
 					wsc.setState(Reconnecting)
 					go wsc.handleReconnect()
 				}
+				wsc.unlock()
 				return
 			}
 
+			message, err := parseResponse(data)
+			if err != nil {
+				wsc.handleError(NewJSONError(fmt.Sprintf("Failed to decode message: %v", err)))
+				continue
+			}
+
 			if wsc.config.DebugWebsocket {
-				log.Printf("Received message: %+v", message)
+				log.Printf("Received message: type=%s data=%+v", message.messageType(), message.Data)
 			}
 
-			wsc.handleMessage(&message)
+			if message.Type != nil && *message.Type == "media_mode" {
+				wsc.handleMediaMode(message)
+			}
+			wsc.trackSessionID(message)
+
+			if message = wsc.applyInbound(message); message == nil {
+				continue
+			}
+			wsc.requests.resolve(message)
+			wsc.handleMessage(message)
+			wsc.handleEvent(message)
 		}
 	}
 }
 
 func (wsc *WebSocketClient) handleReconnect() {
 	wsc.mu.Lock()
-	defer wsc.mu.Unlock()
-
 	if wsc.state != Reconnecting {
+		wsc.mu.Unlock()
 		return
 	}
+	wsc.resuming = true
+	lifetime := wsc.ctx
+	wsc.unlock()
+
+	err := wsc.connectWithRetry(lifetime, lifetime)
 
-	if err := wsc.connectWithRetry(); err != nil {
+	wsc.mu.Lock()
+	defer wsc.unlock()
+	wsc.resuming = false
+
+	event := ResumeEvent{
+		Success:   err == nil,
+		Err:       err,
+		Attempts:  wsc.connectAttempts,
+		Downtime:  time.Since(wsc.disconnectedAt),
+		SessionID: wsc.sessionID,
+	}
+	if err != nil && lifetime.Err() == nil {
 		wsc.setState(ErrorState)
-		wsc.handleError(NewVocalsError(fmt.Sprintf("Reconnection failed: %v", err), "RECONNECTION_FAILED"))
+		wsc.handleErrorLocked(NewVocalsError(fmt.Sprintf("Reconnection failed: %v", err), "RECONNECTION_FAILED"))
 	}
+	wsc.emitLocked(func() { wsc.resumeHandlers.dispatch(event) })
 }
 
-func (wsc *WebSocketClient) handleMessage(message *WebSocketResponse) {
-	for _, handler := range wsc.messageHandlers {
-		go handler(message)
+// trackSessionID remembers the session ID announced by the server so it can
+// be sent back when resuming
+func (wsc *WebSocketClient) trackSessionID(message *WebSocketResponse) {
+	data, ok := message.Data.(map[string]interface{})
+	if !ok {
+		return
+	}
+	if sessionID, ok := data["session_id"].(string); ok && sessionID != "" {
+		wsc.mu.Lock()
+		wsc.sessionID = sessionID
+		wsc.mu.Unlock()
 	}
 }
 
-func (wsc *WebSocketClient) SendMessage(message *WebSocketMessage) error {
+// handleMediaMode records the media mode confirmed by the server. Binary
+// media is only used when it was requested and the server accepted it;
+// otherwise audio stays in JSON media events.
+func (wsc *WebSocketClient) handleMediaMode(message *WebSocketResponse) {
+	data, ok := message.Data.(map[string]interface{})
+	if !ok {
+		return
+	}
+
 	wsc.mu.Lock()
 	defer wsc.mu.Unlock()
 
+	wsc.binaryMedia = wsc.config.MediaMode == MediaModeBinary && getString(data, "mode") == MediaModeBinary
+	if wsc.config.DebugWebsocket {
+		log.Printf("Server media mode: %s (binary media: %t)", getString(data, "mode"), wsc.binaryMedia)
+	}
+}
+
+func (wsc *WebSocketClient) handleMessage(message *WebSocketResponse) {
+	wsc.messageHandlers.dispatch(message)
+}
+
+// handleEvent decodes the message and passes the typed event to the event
+// handlers. A payload that fails to decode is reported as an error and
+// delivered as a RawEvent.
+func (wsc *WebSocketClient) handleEvent(message *WebSocketResponse) {
+	if wsc.eventHandlers.len() == 0 {
+		return
+	}
+
+	event, err := message.Decode()
+	if err != nil {
+		if vErr, ok := err.(*VocalsError); ok {
+			wsc.handleError(vErr)
+		} else {
+			wsc.handleError(NewJSONError(err.Error()))
+		}
+		event = RawEvent{Type: message.messageType(), Data: message.rawData}
+	}
+
+	wsc.eventHandlers.dispatch(event)
+}
+
+// SendMessage queues a message on the outbound writer. Control messages
+// wait until they have been written; media events return as soon as they
+// are queued, subject to the configured overflow policy. Messages other
+// than media events are given an ID when they have none.
+func (wsc *WebSocketClient) SendMessage(message *WebSocketMessage) error {
+	assignMessageID(message)
+	message, err := wsc.applyOutbound(message)
+	if err != nil || message == nil {
+		return err
+	}
+	return wsc.sendProcessed(message)
+}
+
+// sendProcessed writes a message that already went through the outbound
+// middlewares
+func (wsc *WebSocketClient) sendProcessed(message *WebSocketMessage) error {
+	wsc.mu.Lock()
 	if wsc.state != Connected {
+		wsc.mu.Unlock()
 		return fmt.Errorf("not connected")
 	}
 
+	writer := wsc.writer
+	binaryMedia := false
+	var sequence uint32
+	if wsc.binaryMedia && message.Event == "media" {
+		binaryMedia = true
+		sequence = wsc.mediaSequence
+		wsc.mediaSequence++
+	}
+	wsc.mu.Unlock()
+
+	return wsc.writeMessage(writer, message, binaryMedia, sequence)
+}
+
+// writeMessage encodes a message and hands it to the writer. Media events
+// become binary frames when binaryMedia is set and JSON text frames otherwise.
+func (wsc *WebSocketClient) writeMessage(writer *outboundWriter, message *WebSocketMessage, binaryMedia bool, sequence uint32) error {
+	if message.Event == "media" {
+		if pcm, ok := message.Data.([]byte); ok && binaryMedia {
+			capturedAt := message.CapturedAt
+			if capturedAt.IsZero() {
+				capturedAt = time.Now()
+			}
+			return writer.sendAudio(BinaryFrame, EncodeMediaFrame(sequence, capturedAt, pcm))
+		}
+	}
+
 	if wsc.config.DebugWebsocket {
 		log.Printf("Sending message: %+v", message)
 	}
 
-	return wsc.conn.WriteJSON(message)
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	if message.Event == "media" {
+		return writer.sendAudio(TextFrame, data)
+	}
+	return writer.sendControl(TextFrame, data)
+}
+
+// writeSessionMessage runs the middlewares and writes message on writer
+// rather than the current connection, for the events that open or close a
+// session. The caller does not hold wsc.mu.
+func (wsc *WebSocketClient) writeSessionMessage(writer *outboundWriter, message *WebSocketMessage) error {
+	assignMessageID(message)
+	message, err := wsc.applyOutbound(message)
+	if err != nil || message == nil {
+		return err
+	}
+	return wsc.writeMessage(writer, message, false, 0)
 }
 
 // SendBinaryMessage sends raw binary data over WebSocket
 func (wsc *WebSocketClient) SendBinaryMessage(data []byte) error {
 	wsc.mu.Lock()
-	defer wsc.mu.Unlock()
-
 	if wsc.state != Connected {
+		wsc.mu.Unlock()
 		return fmt.Errorf("not connected")
 	}
+	writer := wsc.writer
+	wsc.mu.Unlock()
 
 	if wsc.config.DebugWebsocket {
 		log.Printf("Sending binary message: %d bytes", len(data))
 	}
 
-	return wsc.conn.WriteMessage(websocket.BinaryMessage, data)
+	return writer.sendAudio(BinaryFrame, data)
 }
 
+// Disconnect ends the session. The client is Disconnected as soon as the
+// call starts; the stop event is written after wsc.mu is released, so a
+// stalled peer delays only Disconnect itself.
 func (wsc *WebSocketClient) Disconnect() {
 	wsc.mu.Lock()
-	defer wsc.mu.Unlock()
-
 	wsc.shouldReconnect = false
-	
+	wsc.cancel()
+
+	conn, writer := wsc.conn, wsc.writer
+	connected := conn != nil && wsc.state == Connected
+	wsc.conn, wsc.writer = nil, nil
+	wsc.sessionID = ""
+	wsc.requests.abort()
+
+	wsc.setState(Disconnected)
+	wsc.unlock()
+
 	// Send stop event before disconnecting
-	if wsc.conn != nil && wsc.state == Connected {
+	if connected {
 		if wsc.config.DebugWebsocket {
 			log.Printf("Sending stop event before disconnect")
 		}
@@ -249,86 +670,138 @@ func (wsc *WebSocketClient) Disconnect() {
 			Event: "stop",
 			Data:  map[string]interface{}{},
 		}
-		if err := wsc.conn.WriteJSON(stopMsg); err != nil && wsc.config.DebugWebsocket {
+		if err := wsc.writeSessionMessage(writer, stopMsg); err != nil && wsc.config.DebugWebsocket {
 			log.Printf("Failed to send stop event: %v", err)
 		}
 	}
-	
-	wsc.cancel()
 
-	if wsc.conn != nil {
-		wsc.conn.Close()
-		wsc.conn = nil
+	if writer != nil {
+		writer.stop()
+	}
+	if conn != nil {
+		conn.Close()
 	}
-
-	wsc.setState(Disconnected)
 }
 
+// setState records state and queues it for the connection handlers. The
+// caller holds wsc.mu and releases it with unlock.
 func (wsc *WebSocketClient) setState(state ConnectionState) {
 	if wsc.state != state {
 		wsc.state = state
-		for _, handler := range wsc.connectionHandlers {
-			go handler(state)
-		}
+		wsc.emitLocked(func() { wsc.connectionHandlers.dispatch(state) })
 	}
 }
 
-func (wsc *WebSocketClient) handleError(err *VocalsError) {
-	log.Printf("WebSocket error: %s (%s)", err.Message, err.Code)
-	for _, handler := range wsc.errorHandlers {
-		go handler(err)
-	}
+// emitReconnectEvent queues event for the reconnect handlers. The caller
+// holds wsc.mu.
+func (wsc *WebSocketClient) emitReconnectEvent(event ReconnectEvent) {
+	wsc.emitLocked(func() { wsc.reconnectHandlers.dispatch(event) })
 }
 
-func (wsc *WebSocketClient) AddMessageHandler(handler MessageHandler) func() {
-	wsc.mu.Lock()
-	wsc.messageHandlers = append(wsc.messageHandlers, handler)
+// emitLocked queues a handler delivery until wsc.mu is released, so a
+// handler that blocks or calls back into the client never runs under the
+// lock. The caller holds wsc.mu and releases it with unlock.
+func (wsc *WebSocketClient) emitLocked(emit func()) {
+	wsc.outboxMu.Lock()
+	wsc.outbox = append(wsc.outbox, emit)
+	wsc.outboxMu.Unlock()
+}
+
+// unlock releases wsc.mu and delivers the queued handler events in order
+func (wsc *WebSocketClient) unlock() {
 	wsc.mu.Unlock()
+	wsc.flushEvents()
+}
 
-	return func() {
-		wsc.mu.Lock()
-		for i, h := range wsc.messageHandlers {
-			if &h == &handler {
-				wsc.messageHandlers = append(wsc.messageHandlers[:i], wsc.messageHandlers[i+1:]...)
+// flushEvents delivers the queued handler events. When another goroutine
+// is already delivering it picks up the new events, so callers never wait
+// behind each other's slow handlers.
+func (wsc *WebSocketClient) flushEvents() {
+	for {
+		if !wsc.emitMu.TryLock() {
+			return
+		}
+		for {
+			wsc.outboxMu.Lock()
+			batch := wsc.outbox
+			wsc.outbox = nil
+			wsc.outboxMu.Unlock()
+			if len(batch) == 0 {
 				break
 			}
+			for _, emit := range batch {
+				emit()
+			}
+		}
+		wsc.emitMu.Unlock()
+
+		// Events queued between the last batch and the unlock above
+		wsc.outboxMu.Lock()
+		empty := len(wsc.outbox) == 0
+		wsc.outboxMu.Unlock()
+		if empty {
+			return
 		}
-		wsc.mu.Unlock()
 	}
 }
 
-func (wsc *WebSocketClient) AddConnectionHandler(handler ConnectionHandler) func() {
-	wsc.mu.Lock()
-	wsc.connectionHandlers = append(wsc.connectionHandlers, handler)
-	wsc.mu.Unlock()
+func (wsc *WebSocketClient) handleError(err *VocalsError) {
+	log.Printf("WebSocket error: %s (%s)", err.Message, err.Code)
+	wsc.emitError(err)
+}
 
-	return func() {
-		wsc.mu.Lock()
-		for i, h := range wsc.connectionHandlers {
-			if &h == &handler {
-				wsc.connectionHandlers = append(wsc.connectionHandlers[:i], wsc.connectionHandlers[i+1:]...)
-				break
-			}
-		}
-		wsc.mu.Unlock()
-	}
+// handleErrorLocked is handleError for callers holding wsc.mu
+func (wsc *WebSocketClient) handleErrorLocked(err *VocalsError) {
+	log.Printf("WebSocket error: %s (%s)", err.Message, err.Code)
+	wsc.emitLocked(func() { wsc.emitError(err) })
+}
+
+// emitError passes err to the error handlers without logging it, for errors
+// that were already logged where they occurred
+func (wsc *WebSocketClient) emitError(err *VocalsError) {
+	wsc.errorHandlers.dispatch(err)
+}
+
+func (wsc *WebSocketClient) AddMessageHandler(handler MessageHandler) func() {
+	return wsc.messageHandlers.add(handler)
+}
+
+// AddEventHandler registers a handler that receives every server message
+// as a typed Event
+func (wsc *WebSocketClient) AddEventHandler(handler EventHandler) func() {
+	return wsc.eventHandlers.add(handler)
+}
+
+// Events returns a channel of typed server events that is closed once ctx
+// is done
+func (wsc *WebSocketClient) Events(ctx context.Context) <-chan Event {
+	return eventChannel(ctx, wsc)
+}
+
+func (wsc *WebSocketClient) AddConnectionHandler(handler ConnectionHandler) func() {
+	return wsc.connectionHandlers.add(handler)
 }
 
 func (wsc *WebSocketClient) AddErrorHandler(handler ErrorHandler) func() {
+	return wsc.errorHandlers.add(handler)
+}
+
+// SetSessionSetup sets the function that supplies the messages sent after
+// the start event on every connection, including automatic reconnects
+func (wsc *WebSocketClient) SetSessionSetup(setup SessionSetupFunc) {
 	wsc.mu.Lock()
-	wsc.errorHandlers = append(wsc.errorHandlers, handler)
-	wsc.mu.Unlock()
+	defer wsc.mu.Unlock()
+	wsc.sessionSetup = setup
+}
 
-	return func() {
-		wsc.mu.Lock()
-		for i, h := range wsc.errorHandlers {
-			if &h == &handler {
-				wsc.errorHandlers = append(wsc.errorHandlers[:i], wsc.errorHandlers[i+1:]...)
-				break
-			}
-		}
-		wsc.mu.Unlock()
-	}
+// AddResumeHandler registers a handler called after every automatic reconnect
+func (wsc *WebSocketClient) AddResumeHandler(handler ResumeHandler) func() {
+	return wsc.resumeHandlers.add(handler)
+}
+
+// AddReconnectHandler registers a handler called after every failed connection attempt
+func (wsc *WebSocketClient) AddReconnectHandler(handler ReconnectHandler) func() {
+	return wsc.reconnectHandlers.add(handler)
 }
 
 func (wsc *WebSocketClient) GetState() ConnectionState {
@@ -337,6 +810,58 @@ func (wsc *WebSocketClient) GetState() ConnectionState {
 	return wsc.state
 }
 
+// MediaMode returns the media mode in use on the current connection
+func (wsc *WebSocketClient) MediaMode() string {
+	wsc.mu.Lock()
+	defer wsc.mu.Unlock()
+	if wsc.binaryMedia {
+		return MediaModeBinary
+	}
+	return MediaModeJSON
+}
+
+// GetOutboundStats returns queue depths and drop counters for outbound frames
+func (wsc *WebSocketClient) GetOutboundStats() OutboundStats {
+	wsc.mu.Lock()
+	writer := wsc.writer
+	wsc.mu.Unlock()
+
+	stats := OutboundStats{
+		Sent:          wsc.outbound.sent.Load(),
+		DroppedOldest: wsc.outbound.droppedOldest.Load(),
+		DroppedNewest: wsc.outbound.droppedNewest.Load(),
+		WriteErrors:   wsc.outbound.writeErrors.Load(),
+	}
+	if writer != nil {
+		stats.ControlQueueDepth = len(writer.control)
+		stats.AudioQueueDepth = len(writer.audio)
+	}
+	return stats
+}
+
+// GetHandlerStats returns the queue depth and drop counter of the handler
+// queues
+func (wsc *WebSocketClient) GetHandlerStats() HandlerStats {
+	var stats HandlerStats
+	add := func(depth int, dropped int64) {
+		stats.QueueDepth += depth
+		stats.Dropped += dropped
+	}
+	add(wsc.messageHandlers.queueDepth(), wsc.messageHandlers.dropped.Load())
+	add(wsc.eventHandlers.queueDepth(), wsc.eventHandlers.dropped.Load())
+	add(wsc.connectionHandlers.queueDepth(), wsc.connectionHandlers.dropped.Load())
+	add(wsc.errorHandlers.queueDepth(), wsc.errorHandlers.dropped.Load())
+	add(wsc.reconnectHandlers.queueDepth(), wsc.reconnectHandlers.dropped.Load())
+	add(wsc.resumeHandlers.queueDepth(), wsc.resumeHandlers.dropped.Load())
+	add(wsc.refreshHandlers.queueDepth(), wsc.refreshHandlers.dropped.Load())
+	return stats
+}
+
+// GetConnectionMetrics returns keepalive ping round trip times and counters
+func (wsc *WebSocketClient) GetConnectionMetrics() ConnectionMetrics {
+	return wsc.metrics.snapshot()
+}
+
 func (wsc *WebSocketClient) IsConnected() bool {
 	wsc.mu.Lock()
 	defer wsc.mu.Unlock()
@@ -0,0 +1,62 @@
+package vocals_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rojolang/vocals-sdk-go/pkg/vocals"
+	"github.com/rojolang/vocals-sdk-go/pkg/vocalstest"
+)
+
+func newTestServer(t *testing.T) *vocalstest.Server {
+	t.Helper()
+	srv := vocalstest.NewServer()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func connectClient(t *testing.T, config *vocals.VocalsConfig) *vocals.WebSocketClient {
+	t.Helper()
+	client := vocals.NewWebSocketClient(config, nil)
+	t.Cleanup(client.Disconnect)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	return client
+}
+
+func waitFor[T any](t *testing.T, ch <-chan T, what string) T {
+	t.Helper()
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for %s", what)
+		panic("unreachable")
+	}
+}
+
+func TestConnectSendsStart(t *testing.T) {
+	srv := newTestServer(t)
+	config := srv.Config()
+	config.Headers = map[string]string{"X-Test": "yes"}
+	client := connectClient(t, config)
+
+	if state := client.GetState(); state != vocals.Connected {
+		t.Errorf("state = %s, want connected", state)
+	}
+	frames, err := srv.WaitForEvent("start", 1, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var start map[string]interface{}
+	if err := frames[0].DecodeData(&start); err != nil {
+		t.Fatalf("decoding start: %v", err)
+	}
+	if start["resume"] != nil {
+		t.Errorf("first start event asks to resume: %v", start)
+	}
+	if headers := srv.ConnectHeaders(); len(headers) != 1 || headers[0].Get("X-Test") != "yes" {
+		t.Errorf("connect headers = %v, want X-Test", headers)
+	}
+}
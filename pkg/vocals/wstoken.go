@@ -1,12 +1,12 @@
 package vocals
 
 import (
+	"fmt"
 	"os"
 	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
-	"github.com/joho/godotenv"
 )
 
 const (
@@ -22,6 +22,8 @@ func ValidateApiKeyFormat(apiKey string) Result[ValidatedApiKey] {
 	return Err[ValidatedApiKey](NewVocalsError("Invalid API key format", "INVALID_API_KEY_FORMAT"))
 }
 
+// GetVocalsApiKey reads VOCALS_DEV_API_KEY from the environment. Clients
+// prefer VocalsConfig.APIKey and only fall back to it.
 func GetVocalsApiKey() Result[string] {
 	apiKey := os.Getenv("VOCALS_DEV_API_KEY")
 	if apiKey != "" {
@@ -31,14 +33,85 @@ func GetVocalsApiKey() Result[string] {
 }
 
 func GenerateWsTokenFromApiKey(apiKey ValidatedApiKey, userId *string) Result[*WSToken] {
-	expiresAt := time.Now().UnixMilli() + TOKEN_EXPIRY_MS
+	var options TokenOptions
+	if userId != nil {
+		options.UserID = *userId
+	}
+	return GenerateWsTokenWithOptions(apiKey, options)
+}
 
-	payload := map[string]interface{}{
-		"apiKey": string(apiKey)[:8] + "...",
-		"exp":    expiresAt / 1000, // JWT expects seconds
+// TokenOptions controls the claims of a locally minted token
+type TokenOptions struct {
+	Expiry    time.Duration          // Token lifetime, TOKEN_EXPIRY_MS when 0
+	IssuedAt  time.Time              // iat, now when zero
+	NotBefore time.Time              // nbf, omitted when zero
+	ID        string                 // jti, random when empty
+	Audience  []string               // aud
+	Issuer    string                 // iss
+	UserID    string                 // userId
+	SessionID string                 // sessionId
+	Modes     []string               // Conversation modes the token may use
+	Scopes    []string               // Permissions granted to the token holder
+	Claims    map[string]interface{} // Additional claims; may not replace the ones above
+}
+
+// reservedTokenClaims are set from TokenOptions fields and cannot be
+// supplied as custom claims
+var reservedTokenClaims = []string{"apiKey", "exp", "iat", "nbf", "jti", "aud", "iss", "userId", "sessionId", "modes", "scopes"}
+
+// GenerateWsTokenWithOptions signs a token with apiKey carrying the claims
+// described by options
+func GenerateWsTokenWithOptions(apiKey ValidatedApiKey, options TokenOptions) Result[*WSToken] {
+	for _, name := range reservedTokenClaims {
+		if _, ok := options.Claims[name]; ok {
+			return Err[*WSToken](NewVocalsError(fmt.Sprintf("Custom claim %q is reserved", name), "INVALID_TOKEN_CLAIMS"))
+		}
 	}
-	if userId != nil {
-		payload["userId"] = *userId
+
+	issuedAt := options.IssuedAt
+	if issuedAt.IsZero() {
+		issuedAt = time.Now()
+	}
+	expiry := options.Expiry
+	if expiry <= 0 {
+		expiry = TOKEN_EXPIRY_MS * time.Millisecond
+	}
+	expiresAt := issuedAt.Add(expiry).UnixMilli()
+	id := options.ID
+	if id == "" {
+		id = NewMessageID()
+	}
+
+	payload := map[string]interface{}{}
+	for name, value := range options.Claims {
+		payload[name] = value
+	}
+	payload["apiKey"] = string(apiKey)[:8] + "..."
+	payload["exp"] = expiresAt / 1000 // JWT expects seconds
+	payload["iat"] = issuedAt.Unix()
+	payload["jti"] = id
+	if !options.NotBefore.IsZero() {
+		payload["nbf"] = options.NotBefore.Unix()
+	}
+	if len(options.Audience) == 1 {
+		payload["aud"] = options.Audience[0]
+	} else if len(options.Audience) > 1 {
+		payload["aud"] = options.Audience
+	}
+	if options.Issuer != "" {
+		payload["iss"] = options.Issuer
+	}
+	if options.UserID != "" {
+		payload["userId"] = options.UserID
+	}
+	if options.SessionID != "" {
+		payload["sessionId"] = options.SessionID
+	}
+	if len(options.Modes) > 0 {
+		payload["modes"] = options.Modes
+	}
+	if len(options.Scopes) > 0 {
+		payload["scopes"] = options.Scopes
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims(payload))
@@ -51,31 +124,42 @@ func GenerateWsTokenFromApiKey(apiKey ValidatedApiKey, userId *string) Result[*W
 }
 
 func GenerateWsToken() Result[*WSToken] {
-	apiKeyResult := GetVocalsApiKey()
-	if !apiKeyResult.Success {
-		return Err[*WSToken](apiKeyResult.Error)
-	}
+	return generateWsTokenWithKey("", nil)
+}
 
-	validatedResult := ValidateApiKeyFormat(apiKeyResult.Data)
-	if !validatedResult.Success {
-		return Err[*WSToken](validatedResult.Error)
-	}
+func GenerateWsTokenWithUserId(userId string) Result[*WSToken] {
+	return generateWsTokenWithKey("", &userId)
+}
 
-	return GenerateWsTokenFromApiKey(validatedResult.Data, nil)
+// GenerateWsTokenForConfig signs a token with config's APIKey, or with
+// VOCALS_DEV_API_KEY when it is empty
+func GenerateWsTokenForConfig(config *VocalsConfig) Result[*WSToken] {
+	return generateWsTokenWithKey(config.APIKey, nil)
 }
 
-func GenerateWsTokenWithUserId(userId string) Result[*WSToken] {
-	apiKeyResult := GetVocalsApiKey()
-	if !apiKeyResult.Success {
-		return Err[*WSToken](apiKeyResult.Error)
+// GenerateWsTokenForConfigWithUserId is GenerateWsTokenForConfig for a
+// token carrying userId
+func GenerateWsTokenForConfigWithUserId(config *VocalsConfig, userId string) Result[*WSToken] {
+	return generateWsTokenWithKey(config.APIKey, &userId)
+}
+
+// generateWsTokenWithKey signs a token with apiKey, or with
+// VOCALS_DEV_API_KEY when apiKey is empty
+func generateWsTokenWithKey(apiKey string, userId *string) Result[*WSToken] {
+	if apiKey == "" {
+		apiKeyResult := GetVocalsApiKey()
+		if !apiKeyResult.Success {
+			return Err[*WSToken](apiKeyResult.Error)
+		}
+		apiKey = apiKeyResult.Data
 	}
 
-	validatedResult := ValidateApiKeyFormat(apiKeyResult.Data)
+	validatedResult := ValidateApiKeyFormat(apiKey)
 	if !validatedResult.Success {
 		return Err[*WSToken](validatedResult.Error)
 	}
 
-	return GenerateWsTokenFromApiKey(validatedResult.Data, &userId)
+	return GenerateWsTokenFromApiKey(validatedResult.Data, userId)
 }
 
 func IsTokenExpired(token *WSToken) bool {
@@ -105,6 +189,78 @@ func DecodeWsToken(token string, apiKey string) Result[map[string]interface{}] {
 	return Err[map[string]interface{}](NewVocalsError("Invalid token", "TOKEN_DECODE_FAILED"))
 }
 
+// VerifyOptions lists the checks DecodeWsTokenWithOptions applies on top
+// of the signature
+type VerifyOptions struct {
+	Audience       string        // Required aud, unchecked when empty
+	Issuer         string        // Required iss, unchecked when empty
+	ClockSkew      time.Duration // Leeway for exp, nbf and iat
+	RequiredScopes []string      // Scopes the token must grant
+}
+
+// DecodeWsTokenWithOptions verifies token against apiKey and options and
+// returns its claims. Only HS256 signatures are accepted.
+func DecodeWsTokenWithOptions(token string, apiKey string, options VerifyOptions) Result[map[string]interface{}] {
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithoutClaimsValidation())
+	claims := jwt.MapClaims{}
+	if _, err := parser.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		return []byte(apiKey), nil
+	}); err != nil {
+		return Err[map[string]interface{}](NewVocalsError(err.Error(), "TOKEN_DECODE_FAILED"))
+	}
+
+	if err := VerifyTokenClaims(claims, options); err != nil {
+		return Err[map[string]interface{}](err)
+	}
+	return Ok(map[string]interface{}(claims))
+}
+
+// VerifyTokenClaims checks decoded claims against options
+func VerifyTokenClaims(claims map[string]interface{}, options VerifyOptions) *VocalsError {
+	mapClaims := jwt.MapClaims(claims)
+	now := time.Now()
+	if !mapClaims.VerifyExpiresAt(now.Add(-options.ClockSkew).Unix(), true) {
+		return NewVocalsError("Token has expired", ErrCodeTokenExpired)
+	}
+	if !mapClaims.VerifyNotBefore(now.Add(options.ClockSkew).Unix(), false) {
+		return NewVocalsError("Token is not valid yet", "TOKEN_NOT_YET_VALID")
+	}
+	if !mapClaims.VerifyIssuedAt(now.Add(options.ClockSkew).Unix(), false) {
+		return NewVocalsError("Token was issued in the future", "TOKEN_NOT_YET_VALID")
+	}
+	if options.Audience != "" && !mapClaims.VerifyAudience(options.Audience, true) {
+		return NewVocalsError(fmt.Sprintf("Token is not issued for audience %s", options.Audience), "TOKEN_AUDIENCE_MISMATCH")
+	}
+	if options.Issuer != "" && !mapClaims.VerifyIssuer(options.Issuer, true) {
+		return NewVocalsError(fmt.Sprintf("Token is not issued by %s", options.Issuer), "TOKEN_ISSUER_MISMATCH")
+	}
+	for _, scope := range options.RequiredScopes {
+		if !TokenHasScope(claims, scope) {
+			return NewVocalsError(fmt.Sprintf("Token does not grant scope %s", scope), "TOKEN_SCOPE_MISSING")
+		}
+	}
+	return nil
+}
+
+// TokenHasScope reports whether decoded claims grant scope
+func TokenHasScope(claims map[string]interface{}, scope string) bool {
+	switch scopes := claims["scopes"].(type) {
+	case []interface{}:
+		for _, s := range scopes {
+			if s == scope {
+				return true
+			}
+		}
+	case []string:
+		for _, s := range scopes {
+			if s == scope {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func GetWsEndpoint() string {
 	return VOCALS_WS_ENDPOINT
 }
@@ -112,7 +268,3 @@ func GetWsEndpoint() string {
 func GetTokenExpiryMs() int {
 	return TOKEN_EXPIRY_MS
 }
-
-func init() {
-	_ = godotenv.Load()
-}
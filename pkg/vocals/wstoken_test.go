@@ -0,0 +1,130 @@
+package vocals
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func mintToken(t *testing.T, options TokenOptions) string {
+	t.Helper()
+	result := GenerateWsTokenWithOptions(ValidatedApiKey(testAPIKey), options)
+	if !result.Success {
+		t.Fatalf("GenerateWsTokenWithOptions: %v", result.Error)
+	}
+	return result.Data.Token
+}
+
+func TestTokenRoundTrip(t *testing.T) {
+	token := mintToken(t, TokenOptions{
+		Audience: []string{"vocals"},
+		Issuer:   "backend",
+		UserID:   "user-1",
+		Scopes:   []string{"stream"},
+		Claims:   map[string]interface{}{"tier": "pro"},
+	})
+
+	result := DecodeWsTokenWithOptions(token, testAPIKey, VerifyOptions{
+		Audience:       "vocals",
+		Issuer:         "backend",
+		RequiredScopes: []string{"stream"},
+	})
+	if !result.Success {
+		t.Fatalf("DecodeWsTokenWithOptions: %v", result.Error)
+	}
+	claims := result.Data
+	if claims["userId"] != "user-1" || claims["tier"] != "pro" {
+		t.Errorf("claims = %v, want userId user-1 and tier pro", claims)
+	}
+	if apiKey, _ := claims["apiKey"].(string); strings.Contains(apiKey, testAPIKey) {
+		t.Error("token carries the full API key")
+	}
+}
+
+func TestTokenVerificationFailures(t *testing.T) {
+	valid := TokenOptions{Audience: []string{"vocals"}, Issuer: "backend", Scopes: []string{"stream"}}
+	past := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name    string
+		options TokenOptions
+		verify  VerifyOptions
+		code    string
+	}{
+		{"expired", TokenOptions{IssuedAt: past, Expiry: time.Minute}, VerifyOptions{}, ErrCodeTokenExpired},
+		{"not yet valid", TokenOptions{NotBefore: time.Now().Add(time.Hour)}, VerifyOptions{}, "TOKEN_NOT_YET_VALID"},
+		{"wrong audience", valid, VerifyOptions{Audience: "other"}, "TOKEN_AUDIENCE_MISMATCH"},
+		{"wrong issuer", valid, VerifyOptions{Issuer: "other"}, "TOKEN_ISSUER_MISMATCH"},
+		{"missing scope", valid, VerifyOptions{RequiredScopes: []string{"admin"}}, "TOKEN_SCOPE_MISSING"},
+	}
+	for _, tc := range tests {
+		result := DecodeWsTokenWithOptions(mintToken(t, tc.options), testAPIKey, tc.verify)
+		if result.Success {
+			t.Errorf("%s: token verified", tc.name)
+			continue
+		}
+		if result.Error.Code != tc.code {
+			t.Errorf("%s: code %s, want %s", tc.name, result.Error.Code, tc.code)
+		}
+	}
+}
+
+func TestTokenClockSkew(t *testing.T) {
+	token := mintToken(t, TokenOptions{IssuedAt: time.Now().Add(-time.Minute - 30*time.Second), Expiry: time.Minute})
+	if result := DecodeWsTokenWithOptions(token, testAPIKey, VerifyOptions{ClockSkew: time.Minute}); !result.Success {
+		t.Errorf("token expired 30s ago rejected with a minute of skew: %v", result.Error)
+	}
+}
+
+func TestTokenRejectsWrongKey(t *testing.T) {
+	token := mintToken(t, TokenOptions{})
+	result := DecodeWsTokenWithOptions(token, "vdev_ffffffffffffffffffffffffffffffff", VerifyOptions{})
+	if result.Success || result.Error.Code != "TOKEN_DECODE_FAILED" {
+		t.Errorf("token signed with another key: got %+v, want TOKEN_DECODE_FAILED", result.Error)
+	}
+}
+
+func TestTokenRejectsOtherAlgorithms(t *testing.T) {
+	claims := jwt.MapClaims{"exp": time.Now().Add(time.Hour).Unix()}
+
+	unsigned, err := jwt.NewWithClaims(jwt.SigningMethodNone, claims).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatalf("signing with none: %v", err)
+	}
+	hs512, err := jwt.NewWithClaims(jwt.SigningMethodHS512, claims).SignedString([]byte(testAPIKey))
+	if err != nil {
+		t.Fatalf("signing with HS512: %v", err)
+	}
+
+	for name, token := range map[string]string{"none": unsigned, "HS512": hs512} {
+		if result := DecodeWsTokenWithOptions(token, testAPIKey, VerifyOptions{}); result.Success {
+			t.Errorf("accepted a token signed with %s", name)
+		}
+	}
+}
+
+func TestTokenRejectsReservedClaims(t *testing.T) {
+	result := GenerateWsTokenWithOptions(ValidatedApiKey(testAPIKey), TokenOptions{
+		Claims: map[string]interface{}{"exp": 0},
+	})
+	if result.Success || result.Error.Code != "INVALID_TOKEN_CLAIMS" {
+		t.Errorf("overriding exp: got %+v, want INVALID_TOKEN_CLAIMS", result.Error)
+	}
+}
+
+func TestGenerateWsTokenForConfigUsesConfigKey(t *testing.T) {
+	t.Setenv("VOCALS_DEV_API_KEY", "")
+	config := NewVocalsConfig()
+	config.APIKey = testAPIKey
+
+	result := GenerateWsTokenForConfigWithUserId(config, "user-2")
+	if !result.Success {
+		t.Fatalf("GenerateWsTokenForConfigWithUserId: %v", result.Error)
+	}
+	claims := DecodeWsToken(result.Data.Token, testAPIKey)
+	if !claims.Success || claims.Data["userId"] != "user-2" {
+		t.Errorf("token did not verify with the config key: %+v", claims)
+	}
+}
@@ -0,0 +1,96 @@
+package vocalstest
+
+import (
+	"encoding/base64"
+	"time"
+
+	"github.com/rojolang/vocals-sdk-go/pkg/vocals"
+)
+
+// Transcription returns a transcription reply
+func Transcription(text string, isFinal bool) Reply {
+	return Reply{
+		Type: "transcription",
+		Data: map[string]interface{}{
+			"text":     text,
+			"is_final": isFinal,
+		},
+	}
+}
+
+// PartialTranscription returns a partial_transcription reply
+func PartialTranscription(text string) Reply {
+	return Reply{
+		Type: "partial_transcription",
+		Data: map[string]interface{}{
+			"text": text,
+		},
+	}
+}
+
+// Response returns an AI response reply
+func Response(text string) Reply {
+	return Reply{
+		Type: "response",
+		Data: map[string]interface{}{
+			"text": text,
+		},
+	}
+}
+
+// TTSAudio returns a tts_audio reply carrying pcm_f32le samples
+func TTSAudio(segmentID string, sentenceNumber int, text string, samples []float32, sampleRate int) Reply {
+	return TTSAudioFormat(segmentID, sentenceNumber, text, samples, sampleRate, vocals.FormatPCMF32LE)
+}
+
+// TTSAudioFormat returns a tts_audio reply carrying samples encoded in
+// format, such as vocals.FormatMuLaw. It panics on an unsupported format.
+func TTSAudioFormat(segmentID string, sentenceNumber int, text string, samples []float32, sampleRate int, format string) Reply {
+	data, err := vocals.EncodePCM(samples, format)
+	if err != nil {
+		panic(err)
+	}
+	return Reply{
+		Type: "tts_audio",
+		Data: map[string]interface{}{
+			"segment_id":       segmentID,
+			"sentence_number":  sentenceNumber,
+			"text":             text,
+			"audio_data":       base64.StdEncoding.EncodeToString(data),
+			"sample_rate":      sampleRate,
+			"format":           format,
+			"duration_seconds": float64(len(samples)) / float64(sampleRate),
+		},
+	}
+}
+
+// Interruption returns an interruption reply
+func Interruption() Reply {
+	return Reply{
+		Type: "interruption",
+		Data: map[string]interface{}{},
+	}
+}
+
+// Error returns a server error reply
+func Error(code, message string) Reply {
+	return Reply{
+		Type: "error",
+		Data: map[string]interface{}{
+			"code":    code,
+			"message": message,
+		},
+	}
+}
+
+// To returns a copy of the reply addressed to the message with the given ID
+func (r Reply) To(id string) Reply {
+	r.ID = id
+	return r
+}
+
+// After returns a copy of the reply delayed by d
+func (r Reply) After(d time.Duration) Reply {
+	r.Delay = d
+	return r
+}
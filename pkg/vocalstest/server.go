@@ -0,0 +1,503 @@
+// Package vocalstest provides an in-process fake Vocals server for tests.
+//
+// The server speaks the same WebSocket protocol as vocals.WebSocketClient,
+// so a VocalsClient can be exercised end to end without network access:
+//
+//	srv := vocalstest.NewServer()
+//	defer srv.Close()
+//
+//	srv.On("media", vocalstest.Transcription("hello", true))
+//
+//	client := vocals.NewVocalsClient(srv.Config(), nil, nil, []string{})
+//	// ... stream audio ...
+//
+//	frames, err := srv.WaitForEvent("media", 1, time.Second)
+package vocalstest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/rojolang/vocals-sdk-go/pkg/vocals"
+)
+
+// Frame is a single frame received by the server from a client
+type Frame struct {
+	ConnID     int
+	ID         string // Message ID, echoed in the canned replies to this frame
+	Event      string
+	Data       json.RawMessage
+	Format     *string
+	SampleRate *int
+	Binary     []byte // PCM payload of binary media frames, nil for JSON frames
+	Sequence   uint32 // Binary media frame sequence number
+	Timestamp  time.Time
+	ReceivedAt time.Time
+}
+
+// DecodeData unmarshals the frame's data payload into v
+func (f Frame) DecodeData(v interface{}) error {
+	return json.Unmarshal(f.Data, v)
+}
+
+// Samples decodes the audio of a media frame in the frame's format, or as
+// pcm_f32le when the frame names none
+func (f Frame) Samples() ([]float32, error) {
+	pcm := f.Binary
+	if pcm == nil {
+		if err := f.DecodeData(&pcm); err != nil {
+			return nil, err
+		}
+	}
+	format := vocals.FormatPCMF32LE
+	if f.Format != nil && *f.Format != "" {
+		format = *f.Format
+	}
+	return vocals.DecodePCM(pcm, format)
+}
+
+// Reply is a server-to-client message
+type Reply struct {
+	ID    string // ID of the message being replied to, if any
+	Type  string
+	Data  interface{}
+	Delay time.Duration // Delay before the reply is written
+}
+
+type serverConn struct {
+	id     int
+	format *string // Audio format of the last settings event, used for binary media
+	ws     *websocket.Conn
+	out    chan Reply
+	done   chan struct{}
+	once   sync.Once
+}
+
+func (sc *serverConn) close() {
+	sc.once.Do(func() {
+		close(sc.done)
+		sc.ws.Close()
+	})
+}
+
+// Server is a scriptable fake Vocals WebSocket server backed by httptest
+type Server struct {
+	httpServer      *httptest.Server
+	upgrader        websocket.Upgrader
+	frames          []Frame
+	headers         []http.Header
+	replies         map[string][]Reply
+	conns           map[int]*serverConn
+	nextConnID      int
+	totalConns      int
+	replyDelay      time.Duration
+	binaryMedia     bool
+	ignorePings     bool
+	omitReplyIDs    bool
+	rejectNext      int
+	rejectStatus    int
+	disconnectAfter map[string]int
+	notify          chan struct{}
+	mu              sync.Mutex
+}
+
+// NewServer starts a fake server listening on a local loopback address
+func NewServer() *Server {
+	s := &Server{
+		upgrader: websocket.Upgrader{
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+		frames:          []Frame{},
+		headers:         []http.Header{},
+		replies:         make(map[string][]Reply),
+		conns:           make(map[int]*serverConn),
+		disconnectAfter: make(map[string]int),
+		notify:          make(chan struct{}),
+	}
+	s.httpServer = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// URL returns the ws:// endpoint of the server
+func (s *Server) URL() string {
+	return "ws" + strings.TrimPrefix(s.httpServer.URL, "http")
+}
+
+// Config returns a VocalsConfig pointed at the server with token auth
+// disabled and short reconnect delays suitable for tests
+func (s *Server) Config() *vocals.VocalsConfig {
+	config := vocals.NewVocalsConfig()
+	endpoint := s.URL()
+	config.WsEndpoint = &endpoint
+	config.TokenEndpoint = nil
+	config.UseTokenAuth = false
+	config.AutoConnect = false
+	config.ReconnectDelay = 0.05
+	return config
+}
+
+// Close disconnects all clients and shuts the server down
+func (s *Server) Close() {
+	s.DisconnectAll()
+	s.httpServer.Close()
+}
+
+// On registers canned replies sent every time the given event is received.
+// Replies carry the ID of the received message unless they set their own
+// or OmitReplyIDs is on.
+func (s *Server) On(event string, replies ...Reply) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.replies[event] = append(s.replies[event], replies...)
+}
+
+// SetReplyDelay adds a delay before every canned reply
+func (s *Server) SetReplyDelay(delay time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.replyDelay = delay
+}
+
+// EnableBinaryMedia makes the server accept binary media frames when a
+// client offers them in its start event
+func (s *Server) EnableBinaryMedia(enable bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.binaryMedia = enable
+}
+
+// OmitReplyIDs stops canned replies from echoing the ID of the received
+// message, like a server that predates message IDs
+func (s *Server) OmitReplyIDs(omit bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.omitReplyIDs = omit
+}
+
+// IgnorePings stops the server from answering pings on new connections,
+// simulating a peer that has silently gone away
+func (s *Server) IgnorePings(ignore bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ignorePings = ignore
+}
+
+// RejectConnections makes the next n connection attempts fail with 503
+func (s *Server) RejectConnections(n int) {
+	s.RejectConnectionsWithStatus(n, http.StatusServiceUnavailable)
+}
+
+// RejectConnectionsWithStatus makes the next n connection attempts fail
+// with the given HTTP status, such as 401 for a rejected token
+func (s *Server) RejectConnectionsWithStatus(n, status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rejectNext = n
+	s.rejectStatus = status
+}
+
+// DisconnectAfter drops the connection once the given event has been
+// received n more times
+func (s *Server) DisconnectAfter(event string, n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.disconnectAfter[event] = n
+}
+
+// DisconnectAll abruptly closes every active connection
+func (s *Server) DisconnectAll() {
+	s.mu.Lock()
+	conns := make([]*serverConn, 0, len(s.conns))
+	for _, sc := range s.conns {
+		conns = append(conns, sc)
+	}
+	s.mu.Unlock()
+
+	for _, sc := range conns {
+		sc.close()
+	}
+}
+
+// Send writes a reply to every active connection
+func (s *Server) Send(reply Reply) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.conns) == 0 {
+		return fmt.Errorf("no active connections")
+	}
+	for _, sc := range s.conns {
+		s.enqueue(sc, reply)
+	}
+	return nil
+}
+
+// Received returns a copy of every frame received so far
+func (s *Server) Received() []Frame {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	frames := make([]Frame, len(s.frames))
+	copy(frames, s.frames)
+	return frames
+}
+
+// ReceivedEvents returns the received frames with the given event
+func (s *Server) ReceivedEvents(event string) []Frame {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.filterLocked(event)
+}
+
+// WaitForEvent blocks until at least count frames with the given event have
+// been received or the timeout elapses
+func (s *Server) WaitForEvent(event string, count int, timeout time.Duration) ([]Frame, error) {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	for {
+		s.mu.Lock()
+		frames := s.filterLocked(event)
+		notify := s.notify
+		s.mu.Unlock()
+
+		if len(frames) >= count {
+			return frames, nil
+		}
+
+		select {
+		case <-notify:
+		case <-deadline.C:
+			return frames, fmt.Errorf("timed out waiting for %d %q frames (got %d)", count, event, len(frames))
+		}
+	}
+}
+
+// ConnectHeaders returns the HTTP headers of every accepted connection
+func (s *Server) ConnectHeaders() []http.Header {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	headers := make([]http.Header, len(s.headers))
+	copy(headers, s.headers)
+	return headers
+}
+
+// ConnectionCount returns the total number of accepted connections
+func (s *Server) ConnectionCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.totalConns
+}
+
+// ActiveConnections returns the number of currently open connections
+func (s *Server) ActiveConnections() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.conns)
+}
+
+// Reset forgets received frames and canned replies
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.frames = []Frame{}
+	s.replies = make(map[string][]Reply)
+	s.disconnectAfter = make(map[string]int)
+}
+
+func (s *Server) filterLocked(event string) []Frame {
+	frames := []Frame{}
+	for _, f := range s.frames {
+		if f.Event == event {
+			frames = append(frames, f)
+		}
+	}
+	return frames
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	if s.rejectNext > 0 {
+		s.rejectNext--
+		status := s.rejectStatus
+		s.mu.Unlock()
+		http.Error(w, "connection rejected", status)
+		return
+	}
+	s.mu.Unlock()
+
+	ws, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	s.nextConnID++
+	s.totalConns++
+	sc := &serverConn{
+		id:   s.nextConnID,
+		ws:   ws,
+		out:  make(chan Reply, 256),
+		done: make(chan struct{}),
+	}
+	s.conns[sc.id] = sc
+	s.headers = append(s.headers, r.Header.Clone())
+	if s.ignorePings {
+		ws.SetPingHandler(func(string) error { return nil })
+	}
+	s.mu.Unlock()
+
+	go s.writeLoop(sc)
+	s.readLoop(sc)
+}
+
+func (s *Server) readLoop(sc *serverConn) {
+	defer func() {
+		sc.close()
+		s.mu.Lock()
+		delete(s.conns, sc.id)
+		s.mu.Unlock()
+	}()
+
+	for {
+		messageType, data, err := sc.ws.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		frame := Frame{ConnID: sc.id, ReceivedAt: time.Now()}
+		if messageType == websocket.BinaryMessage {
+			header, pcm, err := vocals.DecodeMediaFrame(data)
+			if err != nil {
+				frame.Event = "binary"
+				frame.Binary = data
+			} else {
+				frame.Event = "media"
+				frame.Format = sc.format
+				frame.Binary = pcm
+				frame.Sequence = header.Sequence
+				frame.Timestamp = header.Timestamp
+			}
+		} else {
+			var msg struct {
+				ID         string          `json:"id"`
+				Event      string          `json:"event"`
+				Data       json.RawMessage `json:"data"`
+				Format     *string         `json:"format"`
+				SampleRate *int            `json:"sampleRate"`
+			}
+			if err := json.Unmarshal(data, &msg); err != nil {
+				continue
+			}
+			frame.ID = msg.ID
+			frame.Event = msg.Event
+			frame.Data = msg.Data
+			frame.Format = msg.Format
+			frame.SampleRate = msg.SampleRate
+
+			var settings struct {
+				Format *string `json:"format"`
+			}
+			if frame.Event == "settings" && json.Unmarshal(msg.Data, &settings) == nil && settings.Format != nil {
+				sc.format = settings.Format
+			}
+		}
+
+		if s.record(sc, frame) {
+			return
+		}
+	}
+}
+
+// record stores a frame, queues canned replies and reports whether the
+// connection should be dropped
+func (s *Server) record(sc *serverConn, frame Frame) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.frames = append(s.frames, frame)
+	close(s.notify)
+	s.notify = make(chan struct{})
+
+	if frame.Event == "start" && s.binaryMedia && offersBinaryMedia(frame) {
+		s.enqueue(sc, Reply{
+			Type: "media_mode",
+			Data: map[string]interface{}{"mode": vocals.MediaModeBinary},
+		})
+	}
+
+	for _, reply := range s.replies[frame.Event] {
+		if s.replyDelay > 0 {
+			reply.Delay += s.replyDelay
+		}
+		if reply.ID == "" && !s.omitReplyIDs {
+			reply.ID = frame.ID
+		}
+		s.enqueue(sc, reply)
+	}
+
+	if remaining, ok := s.disconnectAfter[frame.Event]; ok {
+		remaining--
+		if remaining <= 0 {
+			delete(s.disconnectAfter, frame.Event)
+			return true
+		}
+		s.disconnectAfter[frame.Event] = remaining
+	}
+	return false
+}
+
+func (s *Server) enqueue(sc *serverConn, reply Reply) {
+	select {
+	case sc.out <- reply:
+	case <-sc.done:
+	}
+}
+
+func (s *Server) writeLoop(sc *serverConn) {
+	for {
+		select {
+		case <-sc.done:
+			return
+		case reply := <-sc.out:
+			if reply.Delay > 0 {
+				select {
+				case <-time.After(reply.Delay):
+				case <-sc.done:
+					return
+				}
+			}
+			msg := map[string]interface{}{
+				"type": reply.Type,
+				"data": reply.Data,
+			}
+			if reply.ID != "" {
+				msg["id"] = reply.ID
+			}
+			if err := sc.ws.WriteJSON(msg); err != nil {
+				sc.close()
+				return
+			}
+		}
+	}
+}
+
+func offersBinaryMedia(frame Frame) bool {
+	var data struct {
+		MediaModes []string `json:"media_modes"`
+	}
+	if err := frame.DecodeData(&data); err != nil {
+		return false
+	}
+	for _, mode := range data.MediaModes {
+		if mode == vocals.MediaModeBinary {
+			return true
+		}
+	}
+	return false
+}